@@ -5,6 +5,7 @@ package etxt
 
 import (
 	"embed"
+	"encoding/binary"
 	"fmt"
 	"os"
 	"sort"
@@ -81,3 +82,69 @@ func ensureTestAssetsLoaded() {
 		testFontB = fonts[1].font
 	}
 }
+
+// Returns the given values as big endian uint16s.
+func testBE16(values ...int) []byte {
+	data := make([]byte, 0, len(values)*2)
+	for _, value := range values {
+		data = append(data, byte(uint16(value)>>8), byte(uint16(value)))
+	}
+	return data
+}
+
+type testTable struct {
+	tag  string
+	data []byte
+}
+
+// Returns font data with the given tables, which must be sorted by tag
+// if the data is going to be parsed with sfnt.Parse(). Tables are padded
+// to four byte boundaries.
+func testFontData(tables []testTable) []byte {
+	data := make([]byte, 12+16*len(tables))
+	binary.BigEndian.PutUint32(data[0:], 0x00010000) // TrueType outlines
+	binary.BigEndian.PutUint16(data[4:], uint16(len(tables)))
+	for i, table := range tables {
+		record := data[12+16*i:]
+		copy(record, table.tag)
+		binary.BigEndian.PutUint32(record[8:], uint32(len(data)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table.data)))
+		data = append(data, table.data...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+	return data
+}
+
+// Returns the data of a minimal TrueType font with 1000 units per em,
+// an ascent of 800 and a descent of 200, and a single empty glyph with
+// the given advance, mapped to the given code point.
+func testMinimalFontData(codePoint rune, advance int) []byte {
+	cp := int(codePoint)
+	cmap := testBE16(
+		0, 1, 3, 1, 0, 12, // version, num tables, encoding record
+		4, 32, 0, 4, 4, 1, 0, // format, length, language, seg count x2, search params
+		cp, 0xFFFF, 0, cp, 0xFFFF, // end codes, pad, start codes
+		1-cp, 1, 0, 0, // id deltas, id range offsets
+	)
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint16(head[18:], 1000)
+	hhea := make([]byte, 36)
+	copy(hhea[4:], testBE16(800, -200))
+	binary.BigEndian.PutUint16(hhea[34:], 2)
+	maxp := make([]byte, 32)
+	copy(maxp, testBE16(1, 0, 2))
+	post := make([]byte, 32)
+	copy(post, testBE16(3, 0))
+	return testFontData([]testTable{
+		{"cmap", cmap},
+		{"glyf", nil},
+		{"head", head},
+		{"hhea", hhea},
+		{"hmtx", testBE16(advance, 0, advance, 0)},
+		{"loca", testBE16(0, 0, 0)},
+		{"maxp", maxp},
+		{"post", post},
+	})
+}
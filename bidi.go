@@ -0,0 +1,663 @@
+package etxt
+
+import (
+	"sort"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// This file contains an implementation of the Unicode Bidirectional
+// Algorithm (UAX #9), used by the layout process when the renderer's
+// direction is [BidiLeftToRight], [BidiRightToLeft] or [BidiAuto].
+//
+// Character properties come from golang.org/x/text/unicode/bidi, but
+// level resolution is done here, as x/text doesn't expose resolved
+// embedding levels, only directional runs.
+//
+// The implementation follows the rules in the standard quite literally:
+//   - P2, P3: paragraph level.
+//   - X1-X10: explicit levels and isolating run sequences.
+//   - W1-W7, N0-N2, I1-I2: resolving weak types, neutrals and
+//     implicit levels.
+//   - L1, L2: line level adjustments and visual reordering. These
+//     are applied per line after line breaking, see bidiReorderLine().
+//   - L4: mirroring, see bidiMirror().
+
+const bidiMaxDepth = 125
+const bidiMaxBracketPairs = 63
+
+type bidiStatus struct {
+	level    uint8
+	override bidi.Class // ON if none
+	isolate  bool
+}
+
+type bidiBracketPair struct {
+	opener int
+	closer int
+}
+
+// Reusable buffers for bidi level resolution.
+type bidiResolver struct {
+	runes       []rune
+	initial     []bidi.Class
+	classes     []bidi.Class
+	levels      []uint8
+	matchingPDI []int // index of the matching PDI for isolate initiators
+	matchedPDI  []bool
+	stack       []bidiStatus
+	runStarts   []int // level run starts in runIndices, plus the final end
+	runIndices  []int
+	runOf       []int // level run index for each character, or -1 if removed
+	sequence    []int
+	types       []bidi.Class
+	pairs       []bidiBracketPair
+	openers     []int
+}
+
+// Resolves the embedding levels for the given paragraph runes. The
+// paragraph level can be 0 (LTR), 1 (RTL) or -1 (auto, rules P2 and P3).
+// The paragraph runes shouldn't contain paragraph separators. Results
+// are stored in self.levels, and the resolved paragraph level is returned.
+func (self *bidiResolver) resolve(runes []rune, paraLevel int8) uint8 {
+	n := len(runes)
+	self.runes = runes
+	self.initial = resizeBidiSlice(self.initial, n)
+	self.classes = resizeBidiSlice(self.classes, n)
+	self.levels = resizeBidiSlice(self.levels, n)
+	self.matchingPDI = resizeBidiSlice(self.matchingPDI, n)
+	self.matchedPDI = resizeBidiSlice(self.matchedPDI, n)
+	for i, codePoint := range runes {
+		props, _ := bidi.LookupRune(codePoint)
+		self.initial[i] = props.Class()
+		self.classes[i] = self.initial[i]
+	}
+
+	self.computeMatchingIsolates()
+	var level uint8
+	if paraLevel < 0 {
+		level = self.firstStrongLevel(0, n, 0)
+	} else {
+		level = uint8(paraLevel)
+	}
+	self.resolveExplicit(level)
+	self.computeLevelRuns()
+	self.resolveSequences(level)
+	self.assignRemovedLevels(level)
+	return level
+}
+
+// BD9. Matching PDIs for isolate initiators.
+func (self *bidiResolver) computeMatchingIsolates() {
+	n := len(self.runes)
+	self.openers = self.openers[:0]
+	for i := 0; i < n; i++ {
+		self.matchingPDI[i] = n
+		self.matchedPDI[i] = false
+		switch self.initial[i] {
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			self.openers = append(self.openers, i)
+		case bidi.PDI:
+			if len(self.openers) > 0 {
+				last := len(self.openers) - 1
+				self.matchingPDI[self.openers[last]] = i
+				self.matchedPDI[i] = true
+				self.openers = self.openers[:last]
+			}
+		}
+	}
+}
+
+// P2 and P3. Returns the level of the first strong character in
+// [start, end), skipping isolates, or the default level if none.
+func (self *bidiResolver) firstStrongLevel(start, end int, defaultLevel uint8) uint8 {
+	for i := start; i < end; i++ {
+		switch self.initial[i] {
+		case bidi.L:
+			return 0
+		case bidi.R, bidi.AL:
+			return 1
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			i = self.matchingPDI[i]
+		}
+	}
+	return defaultLevel
+}
+
+// X1-X9. Explicit levels and directions. Removed characters (X9)
+// are marked as BN.
+func (self *bidiResolver) resolveExplicit(paraLevel uint8) {
+	self.stack = append(self.stack[:0], bidiStatus{level: paraLevel, override: bidi.ON})
+	var overflowIsolates, overflowEmbeddings, validIsolates int
+	for i, class := range self.initial {
+		top := self.stack[len(self.stack)-1]
+		switch class {
+		case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO: // X2-X5
+			self.levels[i] = top.level
+			self.classes[i] = bidi.BN
+			newLevel := bidiNextLevel(top.level, class == bidi.RLE || class == bidi.RLO)
+			if newLevel <= bidiMaxDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				override := bidi.ON
+				if class == bidi.RLO {
+					override = bidi.R
+				} else if class == bidi.LRO {
+					override = bidi.L
+				}
+				self.stack = append(self.stack, bidiStatus{level: newLevel, override: override})
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings += 1
+			}
+		case bidi.RLI, bidi.LRI, bidi.FSI: // X5a-X5c
+			self.levels[i] = top.level
+			if top.override != bidi.ON {
+				self.classes[i] = top.override
+			}
+			rtl := (class == bidi.RLI)
+			if class == bidi.FSI {
+				rtl = (self.firstStrongLevel(i+1, self.matchingPDI[i], 0) == 1)
+			}
+			newLevel := bidiNextLevel(top.level, rtl)
+			if newLevel <= bidiMaxDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				validIsolates += 1
+				self.stack = append(self.stack, bidiStatus{level: newLevel, override: bidi.ON, isolate: true})
+			} else {
+				overflowIsolates += 1
+			}
+		case bidi.PDI: // X6a
+			if overflowIsolates > 0 {
+				overflowIsolates -= 1
+			} else if validIsolates > 0 {
+				overflowEmbeddings = 0
+				for !self.stack[len(self.stack)-1].isolate {
+					self.stack = self.stack[:len(self.stack)-1]
+				}
+				self.stack = self.stack[:len(self.stack)-1]
+				validIsolates -= 1
+			}
+			top = self.stack[len(self.stack)-1]
+			self.levels[i] = top.level
+			if top.override != bidi.ON {
+				self.classes[i] = top.override
+			}
+		case bidi.PDF: // X7
+			self.levels[i] = top.level
+			self.classes[i] = bidi.BN
+			if overflowIsolates > 0 {
+				// nothing to do
+			} else if overflowEmbeddings > 0 {
+				overflowEmbeddings -= 1
+			} else if !top.isolate && len(self.stack) >= 2 {
+				self.stack = self.stack[:len(self.stack)-1]
+			}
+		case bidi.BN: // X9
+			self.levels[i] = top.level
+		case bidi.B: // X8
+			self.levels[i] = paraLevel
+		default: // X6
+			self.levels[i] = top.level
+			if top.override != bidi.ON {
+				self.classes[i] = top.override
+			}
+		}
+	}
+}
+
+// X10 and BD7. Computes level runs, ignoring removed characters.
+func (self *bidiResolver) computeLevelRuns() {
+	self.runStarts = self.runStarts[:0]
+	self.runIndices = self.runIndices[:0]
+	self.runOf = resizeBidiSlice(self.runOf, len(self.runes))
+	var runLevel int = -1
+	for i := range self.runes {
+		if self.classes[i] == bidi.BN {
+			self.runOf[i] = -1
+			continue
+		}
+		if int(self.levels[i]) != runLevel {
+			self.runStarts = append(self.runStarts, len(self.runIndices))
+			runLevel = int(self.levels[i])
+		}
+		self.runOf[i] = len(self.runStarts) - 1
+		self.runIndices = append(self.runIndices, i)
+	}
+	self.runStarts = append(self.runStarts, len(self.runIndices))
+}
+
+func (self *bidiResolver) levelRun(run int) []int {
+	return self.runIndices[self.runStarts[run]:self.runStarts[run+1]]
+}
+
+// BD13. Builds each isolating run sequence and resolves it.
+func (self *bidiResolver) resolveSequences(paraLevel uint8) {
+	numRuns := len(self.runStarts) - 1
+	for run := 0; run < numRuns; run++ {
+		indices := self.levelRun(run)
+		first := indices[0]
+		if self.initial[first] == bidi.PDI && self.matchedPDI[first] {
+			continue // already part of a previous sequence
+		}
+
+		self.sequence = append(self.sequence[:0], indices...)
+		for {
+			last := self.sequence[len(self.sequence)-1]
+			class := self.initial[last]
+			if class != bidi.LRI && class != bidi.RLI && class != bidi.FSI {
+				break
+			}
+			pdi := self.matchingPDI[last]
+			if pdi >= len(self.runes) {
+				break
+			}
+			self.sequence = append(self.sequence, self.levelRun(self.runOf[pdi])...)
+		}
+		self.resolveSequence(paraLevel)
+	}
+}
+
+// Resolves weak types, neutrals and implicit levels for self.sequence.
+func (self *bidiResolver) resolveSequence(paraLevel uint8) {
+	seq := self.sequence
+	level := self.levels[seq[0]]
+
+	// determine sos and eos
+	prevLevel := paraLevel
+	for i := seq[0] - 1; i >= 0; i-- {
+		if self.classes[i] != bidi.BN {
+			prevLevel = self.levels[i]
+			break
+		}
+	}
+	nextLevel := paraLevel
+	last := seq[len(seq)-1]
+	switch self.initial[last] {
+	case bidi.LRI, bidi.RLI, bidi.FSI:
+		// isolate initiator without matching PDI, use paragraph level
+	default:
+		for i := last + 1; i < len(self.runes); i++ {
+			if self.classes[i] != bidi.BN {
+				nextLevel = self.levels[i]
+				break
+			}
+		}
+	}
+	sos := bidiLevelClass(maxUint8(level, prevLevel))
+	eos := bidiLevelClass(maxUint8(level, nextLevel))
+
+	// copy types for the sequence
+	self.types = self.types[:0]
+	for _, index := range seq {
+		self.types = append(self.types, self.classes[index])
+	}
+	types := self.types
+
+	// W1: non-spacing marks
+	for i, class := range types {
+		if class != bidi.NSM {
+			continue
+		}
+		if i == 0 {
+			types[i] = sos
+		} else {
+			switch types[i-1] {
+			case bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+				types[i] = bidi.ON
+			default:
+				types[i] = types[i-1]
+			}
+		}
+	}
+
+	// W2 and W3: european numbers after arabic letters, arabic letters
+	lastStrong := sos
+	for i, class := range types {
+		switch class {
+		case bidi.L, bidi.R:
+			lastStrong = class
+		case bidi.AL:
+			lastStrong = class
+			types[i] = bidi.R
+		case bidi.EN:
+			if lastStrong == bidi.AL {
+				types[i] = bidi.AN
+			}
+		}
+	}
+
+	// W4: single separators between numbers
+	for i := 1; i < len(types)-1; i++ {
+		prev, next := types[i-1], types[i+1]
+		switch types[i] {
+		case bidi.ES:
+			if prev == bidi.EN && next == bidi.EN {
+				types[i] = bidi.EN
+			}
+		case bidi.CS:
+			if prev == next && (prev == bidi.EN || prev == bidi.AN) {
+				types[i] = prev
+			}
+		}
+	}
+
+	// W5: european terminators adjacent to european numbers
+	for i := 0; i < len(types); i++ {
+		if types[i] != bidi.ET {
+			continue
+		}
+		end := i
+		for end < len(types) && types[end] == bidi.ET {
+			end += 1
+		}
+		if (i > 0 && types[i-1] == bidi.EN) || (end < len(types) && types[end] == bidi.EN) {
+			for j := i; j < end; j++ {
+				types[j] = bidi.EN
+			}
+		}
+		i = end - 1
+	}
+
+	// W6: remaining separators and terminators
+	for i, class := range types {
+		if class == bidi.ES || class == bidi.ET || class == bidi.CS {
+			types[i] = bidi.ON
+		}
+	}
+
+	// W7: european numbers after L
+	lastStrong = sos
+	for i, class := range types {
+		switch class {
+		case bidi.L, bidi.R:
+			lastStrong = class
+		case bidi.EN:
+			if lastStrong == bidi.L {
+				types[i] = bidi.L
+			}
+		}
+	}
+
+	// N0: paired brackets
+	self.resolveBrackets(level, sos)
+
+	// N1 and N2: remaining neutrals
+	for i := 0; i < len(types); i++ {
+		if !bidiIsNeutral(types[i]) {
+			continue
+		}
+		end := i
+		for end < len(types) && bidiIsNeutral(types[end]) {
+			end += 1
+		}
+		before, after := sos, eos
+		if i > 0 {
+			before = bidiStrongForNeutrals(types[i-1])
+		}
+		if end < len(types) {
+			after = bidiStrongForNeutrals(types[end])
+		}
+		resolved := bidiLevelClass(level)
+		if before == after {
+			resolved = before
+		}
+		for j := i; j < end; j++ {
+			types[j] = resolved
+		}
+		i = end - 1
+	}
+
+	// I1 and I2: implicit levels
+	for i, index := range seq {
+		self.classes[index] = types[i]
+		if level&1 == 0 {
+			switch types[i] {
+			case bidi.R:
+				self.levels[index] = level + 1
+			case bidi.AN, bidi.EN:
+				self.levels[index] = level + 2
+			}
+		} else {
+			switch types[i] {
+			case bidi.L, bidi.EN, bidi.AN:
+				self.levels[index] = level + 1
+			}
+		}
+	}
+}
+
+// N0. Resolves paired brackets within self.sequence.
+func (self *bidiResolver) resolveBrackets(level uint8, sos bidi.Class) {
+	seq, types := self.sequence, self.types
+
+	// BD16: identify bracket pairs
+	self.pairs = self.pairs[:0]
+	self.openers = self.openers[:0]
+	for i, index := range seq {
+		if types[i] != bidi.ON {
+			continue
+		}
+		props, _ := bidi.LookupRune(self.runes[index])
+		if !props.IsBracket() {
+			continue
+		}
+		if props.IsOpeningBracket() {
+			if len(self.openers) == bidiMaxBracketPairs {
+				break
+			}
+			self.openers = append(self.openers, i)
+		} else {
+			closing := self.runes[index]
+			for j := len(self.openers) - 1; j >= 0; j-- {
+				if bidiBracketsMatch(self.runes[seq[self.openers[j]]], closing) {
+					self.pairs = append(self.pairs, bidiBracketPair{opener: self.openers[j], closer: i})
+					self.openers = self.openers[:j]
+					break
+				}
+			}
+		}
+	}
+	if len(self.pairs) == 0 {
+		return
+	}
+	sort.Slice(self.pairs, func(i, j int) bool { return self.pairs[i].opener < self.pairs[j].opener })
+
+	// resolve each pair
+	embedding := bidiLevelClass(level)
+	for _, pair := range self.pairs {
+		var foundEmbedding, foundOpposite bool
+		for i := pair.opener + 1; i < pair.closer; i++ {
+			strong := bidiStrongForNeutrals(types[i])
+			if strong == embedding {
+				foundEmbedding = true
+				break
+			} else if strong == bidi.L || strong == bidi.R {
+				foundOpposite = true
+			}
+		}
+
+		var resolved bidi.Class
+		if foundEmbedding {
+			resolved = embedding
+		} else if foundOpposite {
+			context := sos
+			for i := pair.opener - 1; i >= 0; i-- {
+				strong := bidiStrongForNeutrals(types[i])
+				if strong == bidi.L || strong == bidi.R {
+					context = strong
+					break
+				}
+			}
+			if context != embedding {
+				resolved = context
+			} else {
+				resolved = embedding
+			}
+		} else {
+			continue
+		}
+
+		// set bracket types, including any following non-spacing marks
+		for _, i := range [2]int{pair.opener, pair.closer} {
+			types[i] = resolved
+			for j := i + 1; j < len(types) && self.initial[seq[j]] == bidi.NSM; j++ {
+				types[j] = resolved
+			}
+		}
+	}
+}
+
+// X9. Removed characters take the level of the previous character,
+// which doesn't affect reordering and keeps them next to their
+// neighbors.
+func (self *bidiResolver) assignRemovedLevels(paraLevel uint8) {
+	level := paraLevel
+	for i := range self.runes {
+		if self.classes[i] == bidi.BN {
+			self.levels[i] = level
+		} else {
+			level = self.levels[i]
+		}
+	}
+}
+
+// ---- line level operations ----
+
+// L1. Resets the levels of trailing whitespace and separators in the
+// given line levels. The code points must be given for the same range.
+func bidiResetWhitespaceLevels(levels []uint8, codePoints []rune, paraLevel uint8) {
+	trailing := true
+	for i := len(levels) - 1; i >= 0; i-- {
+		props, _ := bidi.LookupRune(codePoints[i])
+		switch props.Class() {
+		case bidi.S, bidi.B:
+			levels[i] = paraLevel
+			trailing = true
+		case bidi.WS, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI, bidi.BN,
+			bidi.LRE, bidi.RLE, bidi.LRO, bidi.RLO, bidi.PDF:
+			if trailing {
+				levels[i] = paraLevel
+			}
+		default:
+			trailing = false
+		}
+	}
+}
+
+// L2. Reorders the given indices in place based on the given levels,
+// which must be in the same (logical) order. Both slices are modified.
+func bidiReorderLine(indices []int, levels []uint8) {
+	if len(levels) == 0 {
+		return
+	}
+	var highest uint8
+	var lowestOdd uint8 = 255
+	for _, level := range levels {
+		if level > highest {
+			highest = level
+		}
+		if level&1 == 1 && level < lowestOdd {
+			lowestOdd = level
+		}
+	}
+
+	for level := highest; level >= lowestOdd && level > 0; level-- {
+		for i := 0; i < len(levels); i++ {
+			if levels[i] < level {
+				continue
+			}
+			end := i
+			for end < len(levels) && levels[end] >= level {
+				end += 1
+			}
+			for a, b := i, end-1; a < b; a, b = a+1, b-1 {
+				indices[a], indices[b] = indices[b], indices[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = end
+		}
+	}
+}
+
+// L4. Returns the mirrored code point for characters with the
+// Bidi_Mirrored property, or the code point itself otherwise.
+// Only brackets and a few common symbols are covered.
+func bidiMirror(codePoint rune) rune {
+	switch codePoint {
+	case '<':
+		return '>'
+	case '>':
+		return '<'
+	case '«':
+		return '»'
+	case '»':
+		return '«'
+	case '‹':
+		return '›'
+	case '›':
+		return '‹'
+	case '≤':
+		return '≥'
+	case '≥':
+		return '≤'
+	}
+	props, _ := bidi.LookupRune(codePoint)
+	if props.IsBracket() {
+		return []rune(bidi.ReverseString(string(codePoint)))[0]
+	}
+	return codePoint
+}
+
+// ---- helpers ----
+
+func bidiNextLevel(level uint8, rtl bool) uint8 {
+	if rtl {
+		return (level + 1) | 1
+	}
+	return (level + 2) &^ 1
+}
+
+func bidiLevelClass(level uint8) bidi.Class {
+	if level&1 == 1 {
+		return bidi.R
+	}
+	return bidi.L
+}
+
+func bidiIsNeutral(class bidi.Class) bool {
+	switch class {
+	case bidi.B, bidi.S, bidi.WS, bidi.ON, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+		return true
+	default:
+		return false
+	}
+}
+
+// Numbers count as R for neutral resolution (N1).
+func bidiStrongForNeutrals(class bidi.Class) bidi.Class {
+	switch class {
+	case bidi.EN, bidi.AN:
+		return bidi.R
+	default:
+		return class
+	}
+}
+
+func bidiBracketsMatch(opening, closing rune) bool {
+	if bidiMirror(opening) == closing {
+		return true
+	}
+	// canonical equivalents for angle brackets
+	return (opening == '〈' || opening == '〈') && (closing == '〉' || closing == '〉')
+}
+
+func resizeBidiSlice[T any](slice []T, size int) []T {
+	if cap(slice) >= size {
+		return slice[:size]
+	}
+	return make([]T, size)
+}
+
+func maxUint8(a, b uint8) uint8 {
+	if a >= b {
+		return a
+	}
+	return b
+}
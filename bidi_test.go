@@ -0,0 +1,140 @@
+package etxt
+
+import (
+	"testing"
+)
+
+func TestBidiLevels(t *testing.T) {
+	tests := []struct {
+		text      string
+		paraLevel int8
+		expected  []uint8
+		resolved  uint8
+	}{
+		{"abc", -1, []uint8{0, 0, 0}, 0},
+		{"אבג", -1, []uint8{1, 1, 1}, 1},
+		{"ab אב", 0, []uint8{0, 0, 0, 1, 1}, 0},
+		{"ab אב", 1, []uint8{2, 2, 1, 1, 1}, 1},
+		{"אב 12", -1, []uint8{1, 1, 1, 2, 2}, 1},
+		{"ab (אב)", 0, []uint8{0, 0, 0, 0, 1, 1, 0}, 0},
+		{"אב (ab)", 1, []uint8{1, 1, 1, 1, 2, 2, 1}, 1},
+		{"a⁧אב⁩c", 0, []uint8{0, 0, 1, 1, 0, 0}, 0},
+		{"‮ab‬", 0, []uint8{0, 1, 1, 1}, 0},
+		{"123", -1, []uint8{0, 0, 0}, 0},
+		{"", -1, []uint8{}, 0},
+	}
+
+	var resolver bidiResolver
+	for _, test := range tests {
+		level := resolver.resolve([]rune(test.text), test.paraLevel)
+		if level != test.resolved {
+			t.Fatalf("text %q: expected paragraph level %d, got %d", test.text, test.resolved, level)
+		}
+		if !sameUint8s(resolver.levels, test.expected) {
+			t.Fatalf("text %q: expected levels %v, got %v", test.text, test.expected, resolver.levels)
+		}
+	}
+}
+
+func TestBidiReorder(t *testing.T) {
+	tests := []struct {
+		text      string
+		paraLevel int8
+		visual    string
+	}{
+		{"abc", 0, "abc"},
+		{"abc אבג def", 0, "abc גבא def"},
+		{"אבג abc דהו", 1, "והד abc גבא"},
+		{"abc אבג 123 דה", 0, "abc הד 123 גבא"},
+		{"ab (אב)", 1, "(בא) ab"},
+		{"אב (cd)", 1, "(cd) בא"},
+	}
+
+	var resolver bidiResolver
+	for _, test := range tests {
+		runes := []rune(test.text)
+		level := resolver.resolve(runes, test.paraLevel)
+		indices := make([]int, len(runes))
+		for i := range indices {
+			indices[i] = i
+		}
+		levels := append([]uint8(nil), resolver.levels...)
+		bidiResetWhitespaceLevels(levels, runes, level)
+		bidiReorderLine(indices, levels)
+		visual := make([]rune, len(runes))
+		for i, index := range indices {
+			visual[i] = runes[index]
+			if resolver.levels[index]&1 == 1 {
+				visual[i] = bidiMirror(visual[i])
+			}
+		}
+		if string(visual) != test.visual {
+			t.Fatalf("text %q: expected %q, got %q", test.text, test.visual, string(visual))
+		}
+	}
+}
+
+func TestBidiLayout(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Glyph().SetMissHandler(OnMissNotdef)
+
+	// pure left-to-right text must match the regular functions
+	texts := []string{"hello world", "\n\nhey\n", "hello world hello world\ngoodbye", "AV.AV"}
+	for _, text := range texts {
+		renderer.SetDirection(LeftToRight)
+		r1, w1 := renderer.Measure(text), renderer.MeasureWithWrap(text, 60)
+		for _, dir := range []Direction{BidiLeftToRight, BidiAuto} {
+			renderer.SetDirection(dir)
+			r2, w2 := renderer.Measure(text), renderer.MeasureWithWrap(text, 60)
+			if r1 != r2 || w1 != w2 {
+				t.Fatalf("text %q, dir %s: expected %v and %v, got %v and %v", text, dir, r1, w1, r2, w2)
+			}
+		}
+	}
+
+	// visual order must be resolved after wrapping
+	renderer.SetDirection(BidiRightToLeft)
+	layout := &renderer.layout
+	width := renderer.Measure("abc ").Width()
+	renderer.layoutString(layout, "abc def אבג", true, width)
+	if len(layout.lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(layout.lines))
+	}
+	expected := []string{"abc", "def", "גבא"}
+	for i, line := range layout.lines {
+		if !line.rtl {
+			t.Fatalf("expected right-to-left line")
+		}
+		var visual []rune
+		for _, index := range layout.order[line.orderStart:line.orderEnd] {
+			visual = append(visual, layout.runes[index].codePoint)
+		}
+		if string(visual) != expected[i] {
+			t.Fatalf("line #%d: expected %q, got %q", i, expected[i], string(visual))
+		}
+	}
+
+	// auto direction is resolved per paragraph
+	renderer.SetDirection(BidiAuto)
+	renderer.layoutString(layout, "abc\nאבג\n123", false, 0)
+	if layout.lines[0].rtl || !layout.lines[1].rtl || layout.lines[2].rtl {
+		t.Fatalf("unexpected paragraph directions")
+	}
+}
+
+func sameUint8s(a, b []uint8) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -6,8 +6,10 @@ import "sync/atomic"
 // much the entry is being used.
 type cachedMaskEntry struct {
 	Mask       GlyphMask // Read-only.
+	RGBAMask   RGBAMask  // Read-only. Only relevant if isRGBA.
 	lastAccess uint64
 	byteSize   uint32 // Read-only.
+	isRGBA     bool   // Read-only.
 }
 
 func (self *cachedMaskEntry) UpdateAccess(accessTick uint64) {
@@ -30,3 +32,13 @@ func newCachedMaskEntry(mask GlyphMask, accessTick uint64) *cachedMaskEntry {
 		byteSize:   GlyphMaskByteSize(mask),
 	}
 }
+
+// Creates a new cached mask entry for the given RGBAMask.
+func newCachedRGBAMaskEntry(mask RGBAMask, accessTick uint64) *cachedMaskEntry {
+	return &cachedMaskEntry{
+		RGBAMask:   mask,
+		lastAccess: accessTick,
+		byteSize:   RGBAMaskByteSize(mask),
+		isRGBA:     true,
+	}
+}
@@ -69,9 +69,18 @@ func (self *DefaultCache) removeRandOldEntry() {
 
 // Stores the given mask with the given key.
 func (self *DefaultCache) PassMask(key [3]uint64, mask GlyphMask) {
-	// create mask cached entry
 	tick := self.toNextAccessTick()
-	maskEntry := newCachedMaskEntry(mask, tick)
+	self.passEntry(key, newCachedMaskEntry(mask, tick))
+}
+
+// Stores the given RGBA mask with the given key. RGBA masks and
+// regular masks share the capacity, but keys should be different.
+func (self *DefaultCache) PassRGBAMask(key [3]uint64, mask RGBAMask) {
+	tick := self.toNextAccessTick()
+	self.passEntry(key, newCachedRGBAMaskEntry(mask, tick))
+}
+
+func (self *DefaultCache) passEntry(key [3]uint64, maskEntry *cachedMaskEntry) {
 	maskSize := uint64(maskEntry.ByteSize())
 	if maskSize > atomic.LoadUint64(&self.capacity) {
 		return
@@ -151,16 +160,33 @@ func (self *DefaultCache) NumEntries() int {
 
 // Gets the mask associated to the given key.
 func (self *DefaultCache) GetMask(key [3]uint64) (GlyphMask, bool) {
+	entry := self.getEntry(key, false)
+	if entry == nil {
+		return nil, false
+	}
+	return entry.Mask, true
+}
+
+// Gets the RGBA mask associated to the given key.
+func (self *DefaultCache) GetRGBAMask(key [3]uint64) (RGBAMask, bool) {
+	entry := self.getEntry(key, true)
+	if entry == nil {
+		return nil, false
+	}
+	return entry.RGBAMask, true
+}
+
+func (self *DefaultCache) getEntry(key [3]uint64, isRGBA bool) *cachedMaskEntry {
 	self.mutex.RLock()
 	entry, found := self.cachedMasks[key]
 	self.mutex.RUnlock()
-	if !found {
-		return nil, false
+	if !found || entry.isRGBA != isRGBA {
+		return nil
 	}
 
 	tick := self.toNextAccessTick()
 	entry.UpdateAccess(tick)
-	return entry.Mask, true
+	return entry
 }
 
 // Like GetMask, but doesn't update the last access for the mask
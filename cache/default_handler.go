@@ -7,7 +7,11 @@ import "golang.org/x/image/font/sfnt"
 import "github.com/tinne26/etxt/fract"
 import "github.com/tinne26/etxt/mask"
 
-var _ GlyphCacheHandler = (*DefaultCacheHandler)(nil)
+var _ RGBAMaskCacheHandler = (*DefaultCacheHandler)(nil)
+
+// Key bit used to keep RGBA masks apart from regular masks. It's one
+// of the variant bits (see the NotifyVariantChange() comment below).
+const rgbaMaskKeyBit = 0x0000000010000000
 
 // A default implementation of [GlyphCacheHandler].
 type DefaultCacheHandler struct {
@@ -63,6 +67,22 @@ func (self *DefaultCacheHandler) PassMask(index sfnt.GlyphIndex, mask GlyphMask)
 	self.cache.PassMask(self.activeKey, mask)
 }
 
+// Implements [RGBAMaskCacheHandler].GetRGBAMask(...)
+func (self *DefaultCacheHandler) GetRGBAMask(index sfnt.GlyphIndex) (RGBAMask, bool) {
+	self.activeKey[2] = (self.activeKey[2] & ^uint64(0x000000000000FFFF)) | uint64(index)
+	key := self.activeKey
+	key[2] |= rgbaMaskKeyBit
+	return self.cache.GetRGBAMask(key)
+}
+
+// Implements [RGBAMaskCacheHandler].PassRGBAMask(...)
+func (self *DefaultCacheHandler) PassRGBAMask(index sfnt.GlyphIndex, mask RGBAMask) {
+	self.activeKey[2] = (self.activeKey[2] & ^uint64(0x000000000000FFFF)) | uint64(index)
+	key := self.activeKey
+	key[2] |= rgbaMaskKeyBit
+	self.cache.PassRGBAMask(key, mask)
+}
+
 // Provides access to the underlying [DefaultCache].
 func (self *DefaultCacheHandler) Cache() *DefaultCache {
 	return self.cache
@@ -74,3 +74,36 @@ func TestDefaultHandler(t *testing.T) {
 		t.Fatalf("expected %d bytes, got %d", constMaskSizeFactor, gotSize)
 	}
 }
+
+func TestDefaultHandlerRGBAMasks(t *testing.T) {
+	rast := mask.DefaultRasterizer{}
+	cache := NewDefaultCache(16 * 1024 * 1024)
+	handler := cache.NewHandler()
+	handler.NotifyRasterizerChange(&rast)
+	handler.NotifySizeChange(12 << 6)
+
+	// regular and RGBA masks with the same configuration are kept apart
+	alphaMask, rgbaMask := newEmptyGlyphMask(4, 4), newEmptyRGBAMask(4, 4)
+	handler.PassMask(9, alphaMask)
+	_, found := handler.GetRGBAMask(9)
+	if found {
+		t.Fatal("unexpected RGBA mask in the cache")
+	}
+	handler.PassRGBAMask(9, rgbaMask)
+	if cache.NumEntries() != 2 {
+		t.Fatalf("expected 2 cache entries, got %d", cache.NumEntries())
+	}
+	gotAlpha, found := handler.GetMask(9)
+	if !found || gotAlpha != alphaMask {
+		t.Fatal("expected regular mask in the cache")
+	}
+	gotRGBA, found := handler.GetRGBAMask(9)
+	if !found || gotRGBA != rgbaMask {
+		t.Fatal("expected RGBA mask in the cache")
+	}
+
+	expectedSize := GlyphMaskByteSize(alphaMask) + RGBAMaskByteSize(rgbaMask)
+	if cache.CurrentSize() != int(expectedSize) {
+		t.Fatalf("expected %d bytes, got %d", expectedSize, cache.CurrentSize())
+	}
+}
@@ -7,6 +7,11 @@ import "image"
 // Alias for etxt.GlyphMask.
 type GlyphMask = *image.Alpha
 
+// Mask type for [RGBAMaskCacheHandler]. Without Ebitengine, glyph masks
+// can only hold a single coverage value per pixel, so RGBA masks use
+// [*image.RGBA] instead.
+type RGBAMask = *image.RGBA
+
 const constMaskSizeFactor = 56
 
 func GlyphMaskByteSize(mask GlyphMask) uint32 {
@@ -17,6 +22,15 @@ func GlyphMaskByteSize(mask GlyphMask) uint32 {
 	return maskDimsByteSize(w, h)
 }
 
+// Returns the size of an [RGBAMask] in bytes.
+func RGBAMaskByteSize(mask RGBAMask) uint32 {
+	if mask == nil {
+		return constMaskSizeFactor
+	}
+	w, h := mask.Rect.Dx(), mask.Rect.Dy()
+	return uint32(w*h)*4 + constMaskSizeFactor
+}
+
 func maskDimsByteSize(width, height int) uint32 {
 	return uint32(width*height) + constMaskSizeFactor
 }
@@ -25,3 +39,8 @@ func maskDimsByteSize(width, height int) uint32 {
 func newEmptyGlyphMask(width, height int) GlyphMask {
 	return GlyphMask(image.NewAlpha(image.Rect(0, 0, width, height)))
 }
+
+// used for testing purposes
+func newEmptyRGBAMask(width, height int) RGBAMask {
+	return RGBAMask(image.NewRGBA(image.Rect(0, 0, width, height)))
+}
@@ -11,6 +11,11 @@ import "github.com/hajimehoshi/ebiten/v2"
 // [etxt.GlyphMask]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10#GlyphMask
 type GlyphMask = *ebiten.Image
 
+// Mask type for [RGBAMaskCacheHandler]. With Ebitengine, this is
+// the same as [GlyphMask], as Ebitengine images already hold RGBA
+// values.
+type RGBAMask = *ebiten.Image
+
 // Based on Ebitengine internals.
 const constMaskSizeFactor = 192
 
@@ -28,6 +33,12 @@ func GlyphMaskByteSize(mask GlyphMask) uint32 {
 	return maskDimsByteSize(bounds.Dx(), bounds.Dy())
 }
 
+// Returns an approximation of an [RGBAMask] size in bytes.
+// See [GlyphMaskByteSize]() for details.
+func RGBAMaskByteSize(mask RGBAMask) uint32 {
+	return GlyphMaskByteSize(mask)
+}
+
 func maskDimsByteSize(width, height int) uint32 {
 	return uint32(width*height)*4 + constMaskSizeFactor
 }
@@ -36,3 +47,8 @@ func maskDimsByteSize(width, height int) uint32 {
 func newEmptyGlyphMask(width, height int) GlyphMask {
 	return GlyphMask(ebiten.NewImage(width, height))
 }
+
+// used for testing purposes
+func newEmptyRGBAMask(width, height int) RGBAMask {
+	return RGBAMask(ebiten.NewImage(width, height))
+}
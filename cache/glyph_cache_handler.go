@@ -69,3 +69,23 @@ type GlyphCacheHandler interface {
 	// and you may not want to keep lots of superfluous duplicated masks for
 	// hinted and unhinted configs.
 }
+
+// An optional extension of [GlyphCacheHandler] for handlers that can also
+// store RGBA masks. etxt uses RGBA masks for glyphs that can't be drawn
+// with a single coverage value per pixel, like subpixel antialiased
+// glyphs or color glyphs, which can't be represented as [GlyphMask]
+// without Ebitengine.
+//
+// Renderers never pass RGBA masks through the regular [GlyphCacheHandler]
+// methods, and they only cache them if the handler implements this
+// interface. RGBA masks must be kept apart from regular masks that are
+// passed under the same configuration.
+type RGBAMaskCacheHandler interface {
+	GlyphCacheHandler
+
+	// Like GetMask(), but for RGBA masks.
+	GetRGBAMask(sfnt.GlyphIndex) (RGBAMask, bool)
+
+	// Like PassMask(), but for RGBA masks.
+	PassRGBAMask(sfnt.GlyphIndex, RGBAMask)
+}
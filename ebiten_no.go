@@ -24,8 +24,10 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"math"
 
 	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
 )
 
 type Target = draw.Image
@@ -46,9 +48,20 @@ const (
 	//       semi-transparency, I should look more into it.
 )
 
+// Glyph masks can only hold a single coverage value per pixel, so
+// subpixel masks and composed color glyphs use RGBA images instead.
+// See loadLCDMask() and colorLoadMask().
+type rgbaMask = *image.RGBA
+
 // this doesn't do anything in gtxt, only ebiten needs it
 func convertAlphaImageToGlyphMask(i *image.Alpha) GlyphMask { return i }
 
+// Converts a subpixel mask generated by mask.LCDRasterizer.
+func convertLCDImageToRGBAMask(lcd *image.Alpha) rgbaMask { return lcdMaskToRGBA(lcd) }
+
+// Converts a composed color glyph (premultiplied RGBA).
+func convertRGBAImageToRGBAMask(rgba *image.RGBA) rgbaMask { return rgba }
+
 // Underlying default glyph drawing function for renderers.
 // Can be overridden with Renderer.Glyph().SetDrawFunc(...).
 func (self *Renderer) defaultDrawFunc(target Target, origin fract.Point, mask GlyphMask) {
@@ -67,110 +80,243 @@ func (self *Renderer) defaultDrawFunc(target Target, origin fract.Point, mask Gl
 	shift.X, shift.Y = -shift.X, -shift.Y
 	srcRect = targetRect.Add(shift)
 
+	self.mixImageInto(mask, target, srcRect, targetRect, self.blendMixFunc())
+}
+
+// Returns the color mixing function for the current blend mode.
+func (self *Renderer) blendMixFunc() func(new, curr color.Color) color.Color {
 	switch self.state.blendMode {
 	case BlendReplace: // ---- source only ----
-		self.mixImageInto(mask, target, srcRect, targetRect,
-			func(new, _ color.Color) color.Color { return new })
+		return func(new, _ color.Color) color.Color { return new }
 	case BlendOver: // ---- default mixing ----
-		self.mixImageInto(mask, target, srcRect, targetRect, blendOverFunc)
+		return blendOverFunc
 	case BlendCut: // ---- remove alpha mode ----
-		self.mixImageInto(mask, target, srcRect, targetRect,
-			func(new, curr color.Color) color.Color {
-				_, _, _, na := new.RGBA()
-				if na == 0 {
-					return curr
-				}
-				cr, cg, cb, ca := curr.RGBA()
-
-				alpha := ca - na
-				if alpha < 0 {
-					alpha = 0
-				}
-				return color.RGBA64{
-					R: min32As16(cr, alpha),
-					G: min32As16(cg, alpha),
-					B: min32As16(cb, alpha),
-					A: uint16(alpha),
-				}
-			})
+		return func(new, curr color.Color) color.Color {
+			_, _, _, na := new.RGBA()
+			if na == 0 {
+				return curr
+			}
+			cr, cg, cb, ca := curr.RGBA()
+
+			alpha := ca - na
+			if alpha < 0 {
+				alpha = 0
+			}
+			return color.RGBA64{
+				R: min32As16(cr, alpha),
+				G: min32As16(cg, alpha),
+				B: min32As16(cb, alpha),
+				A: uint16(alpha),
+			}
+		}
 	case BlendMultiply: // ---- multiplicative blending ----
-		self.mixImageInto(mask, target, srcRect, targetRect,
-			func(new, curr color.Color) color.Color {
-				nr, ng, nb, na := new.RGBA()
-				cr, cg, cb, ca := curr.RGBA()
-				pureMult := color.RGBA64{
-					R: uint16(nr * cr / 0xFFFF),
-					G: uint16(ng * cg / 0xFFFF),
-					B: uint16(nb * cb / 0xFFFF),
-					A: uint16(na * ca / 0xFFFF),
-				}
-				return blendOverFunc(pureMult, curr)
-			})
+		return func(new, curr color.Color) color.Color {
+			nr, ng, nb, na := new.RGBA()
+			cr, cg, cb, ca := curr.RGBA()
+			pureMult := color.RGBA64{
+				R: uint16(nr * cr / 0xFFFF),
+				G: uint16(ng * cg / 0xFFFF),
+				B: uint16(nb * cb / 0xFFFF),
+				A: uint16(na * ca / 0xFFFF),
+			}
+			return blendOverFunc(pureMult, curr)
+		}
 	case BlendAdd: // --- additive blending ----
-		self.mixImageInto(mask, target, srcRect, targetRect,
-			func(new, curr color.Color) color.Color {
-				nr, ng, nb, na := new.RGBA()
-				if na == 0 {
-					return curr
-				}
-				cr, cg, cb, ca := curr.RGBA()
-				return color.RGBA64{
-					R: uint16N(nr + cr),
-					G: uint16N(ng + cg),
-					B: uint16N(nb + cb),
-					A: uint16N(na + ca),
-				}
-			})
+		return func(new, curr color.Color) color.Color {
+			nr, ng, nb, na := new.RGBA()
+			if na == 0 {
+				return curr
+			}
+			cr, cg, cb, ca := curr.RGBA()
+			return color.RGBA64{
+				R: uint16N(nr + cr),
+				G: uint16N(ng + cg),
+				B: uint16N(nb + cb),
+				A: uint16N(na + ca),
+			}
+		}
 	case BlendSub: // --- subtractive blending (only color) ----
-		self.mixImageInto(mask, target, srcRect, targetRect,
-			func(new, curr color.Color) color.Color {
-				nr, ng, nb, na := new.RGBA()
-				if na == 0 {
-					return curr
-				}
-				cr, cg, cb, ca := curr.RGBA()
-				return color.RGBA64{
-					R: uint32subFloor16(cr, nr),
-					G: uint32subFloor16(cg, ng),
-					B: uint32subFloor16(cb, nb),
-					A: uint16(ca),
-				}
-			})
+		return func(new, curr color.Color) color.Color {
+			nr, ng, nb, na := new.RGBA()
+			if na == 0 {
+				return curr
+			}
+			cr, cg, cb, ca := curr.RGBA()
+			return color.RGBA64{
+				R: uint32subFloor16(cr, nr),
+				G: uint32subFloor16(cg, ng),
+				B: uint32subFloor16(cb, nb),
+				A: uint16(ca),
+			}
+		}
 	case BlendHue: // ---- max alpha, proportional hue blending ----
-		self.mixImageInto(mask, target, srcRect, targetRect,
-			func(new, curr color.Color) color.Color {
-				var nr, ng, nb, na uint32 = new.RGBA()
-				if na == 0 {
-					return curr
-				}
-				cr, cg, cb, ca := curr.RGBA()
-				if ca == 0 {
-					return new
-				}
-
-				// hue contribution is proportional to alpha.
-				// if both alphas are equal, hue contributions are 50/50
-				ta := ca + na // alpha sum (total)
-				ma := ca      // max alpha
-				if na > ca {
-					ma = na
-				}
-				r := (((nr + cr) >> 1) * ma) / (ta >> 1) // shifts prevent overflows
-				g := (((ng + cg) >> 1) * ma) / (ta >> 1)
-				b := (((nb + cb) >> 1) * ma) / (ta >> 1)
-				partial := color.RGBA64{
-					R: uint16(r),
-					G: uint16(g),
-					B: uint16(b),
-					A: uint16(ma),
-				}
-				return blendOverFunc(partial, curr)
-			})
+		return func(new, curr color.Color) color.Color {
+			var nr, ng, nb, na uint32 = new.RGBA()
+			if na == 0 {
+				return curr
+			}
+			cr, cg, cb, ca := curr.RGBA()
+			if ca == 0 {
+				return new
+			}
+
+			// hue contribution is proportional to alpha.
+			// if both alphas are equal, hue contributions are 50/50
+			ta := ca + na // alpha sum (total)
+			ma := ca      // max alpha
+			if na > ca {
+				ma = na
+			}
+			r := (((nr + cr) >> 1) * ma) / (ta >> 1) // shifts prevent overflows
+			g := (((ng + cg) >> 1) * ma) / (ta >> 1)
+			b := (((nb + cb) >> 1) * ma) / (ta >> 1)
+			partial := color.RGBA64{
+				R: uint16(r),
+				G: uint16(g),
+				B: uint16(b),
+				A: uint16(ma),
+			}
+			return blendOverFunc(partial, curr)
+		}
 	default:
 		panic("unexpected blend mode")
 	}
 }
 
+// Draws a text decoration rect with the current color and blend mode.
+func (self *Renderer) drawDecorationRect(target Target, rect image.Rectangle) {
+	rect = rect.Intersect(target.Bounds())
+	if rect.Empty() {
+		return
+	}
+	mask := image.NewAlpha(rect)
+	for i := range mask.Pix {
+		mask.Pix[i] = 255
+	}
+	self.defaultDrawFunc(target, fract.Point{}, mask)
+}
+
+// Draws a distance field mask scaled by the given factor, thresholding
+// it on the CPU. See renderer_sdf.go.
+func (self *Renderer) drawSDFMask(target Target, origin fract.Point, field GlyphMask, sdf *mask.SDFRasterizer, scale float64) {
+	if field == nil {
+		return
+	} // spaces and empty glyphs will be nil
+
+	// compute the target rect covered by the scaled field
+	originX, originY := origin.ToFloat64s()
+	minX := originX + float64(field.Rect.Min.X)*scale
+	minY := originY + float64(field.Rect.Min.Y)*scale
+	maxX := originX + float64(field.Rect.Max.X)*scale
+	maxY := originY + float64(field.Rect.Max.Y)*scale
+	rect := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+	rect = rect.Intersect(target.Bounds())
+	if rect.Empty() {
+		return
+	}
+
+	// threshold the field into a regular mask and draw it
+	alpha := image.NewAlpha(rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		fieldY := (float64(y) + 0.5 - minY) / scale
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			fieldX := (float64(x) + 0.5 - minX) / scale
+			opacity := sdf.OpacityAt(field, fieldX, fieldY, scale)
+			alpha.Pix[alpha.PixOffset(x, y)] = uint8(opacity*255 + 0.5)
+		}
+	}
+	self.defaultDrawFunc(target, fract.Point{}, alpha)
+}
+
+// Draws a subpixel mask generated by mask.LCDRasterizer, blending it
+// per channel. See convertLCDImageToRGBAMask().
+func (self *Renderer) drawLCDMask(target Target, origin fract.Point, mask rgbaMask) {
+	if mask == nil {
+		return
+	} // spaces and empty glyphs will be nil
+
+	// compute src and target rects within bounds
+	targetBounds := target.Bounds()
+	srcRect := mask.Rect
+	shift := image.Pt(origin.X.ToIntFloor(), origin.Y.ToIntFloor())
+	targetRect := targetBounds.Intersect(srcRect.Add(shift))
+	if targetRect.Empty() {
+		return
+	}
+	shift.X, shift.Y = -shift.X, -shift.Y
+	srcRect = targetRect.Add(shift)
+
+	self.mixLCDImageInto(mask, target, srcRect, targetRect, self.blendMixFunc())
+}
+
+// Draws a composed color glyph mask. The colors are already part of
+// the mask, so the text color is not applied.
+func (self *Renderer) drawColorMask(target Target, origin fract.Point, mask rgbaMask) {
+	if mask == nil {
+		return
+	} // spaces and empty glyphs will be nil
+
+	// compute src and target rects within bounds
+	targetBounds := target.Bounds()
+	srcRect := mask.Rect
+	shift := image.Pt(origin.X.ToIntFloor(), origin.Y.ToIntFloor())
+	targetRect := targetBounds.Intersect(srcRect.Add(shift))
+	if targetRect.Empty() {
+		return
+	}
+	shift.X, shift.Y = -shift.X, -shift.Y
+	srcRect = targetRect.Add(shift)
+
+	mixFunc := self.blendMixFunc()
+	for y := 0; y < srcRect.Dy(); y++ {
+		offset := mask.PixOffset(srcRect.Min.X, srcRect.Min.Y+y)
+		for x := 0; x < srcRect.Dx(); x++ {
+			pixel := mask.Pix[offset+x*4 : offset+x*4+4]
+			newColor := color.RGBA{pixel[0], pixel[1], pixel[2], pixel[3]}
+			tarX, tarY := targetRect.Min.X+x, targetRect.Min.Y+y
+			target.Set(tarX, tarY, mixFunc(newColor, target.At(tarX, tarY)))
+		}
+	}
+}
+
+// Like mixImageInto(), but for subpixel masks. The color is mixed with
+// full coverage, and the result is then interpolated with the current
+// target color per channel, using the max coverage for the alpha.
+func (self *Renderer) mixLCDImageInto(src rgbaMask, target draw.Image, srcRect, tarRect image.Rectangle, mixFunc func(color.Color, color.Color) color.Color) {
+	width := srcRect.Dx()
+	height := srcRect.Dy()
+	tarOffX := tarRect.Min.X
+	tarOffY := tarRect.Min.Y
+
+	directColor := self.state.fontColor
+	for y := 0; y < height; y++ {
+		offset := src.PixOffset(srcRect.Min.X, srcRect.Min.Y+y)
+		for x := 0; x < width; x++ {
+			levels := src.Pix[offset+x*4 : offset+x*4+3]
+			maxLevel := src.Pix[offset+x*4+3]
+			if maxLevel == 0 {
+				continue
+			}
+
+			// mix with full coverage and interpolate per channel
+			currColor := target.At(tarOffX+x, tarOffY+y)
+			mr, mg, mb, ma := mixFunc(directColor, currColor).RGBA()
+			cr, cg, cb, ca := currColor.RGBA()
+			target.Set(tarOffX+x, tarOffY+y, color.RGBA64{
+				R: lerpLevel16(cr, mr, levels[0]),
+				G: lerpLevel16(cg, mg, levels[1]),
+				B: lerpLevel16(cb, mb, levels[2]),
+				A: lerpLevel16(ca, ma, maxLevel),
+			})
+		}
+	}
+}
+
+// Interpolates between a and b, where level 0 is a and level 255 is b.
+func lerpLevel16(a, b uint32, level uint8) uint16 {
+	return uint16((a*(255-uint32(level)) + b*uint32(level)) / 255)
+}
+
 // All this code is extremely slow due to using a very straightforward
 // implementation. Making this faster, though, is not so trivial.
 func (self *Renderer) mixImageInto(src GlyphMask, target draw.Image, srcRect, tarRect image.Rectangle, mixFunc func(color.Color, color.Color) color.Color) {
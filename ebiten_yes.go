@@ -8,6 +8,7 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
 )
 
 //import "golang.org/x/image/font/sfnt"
@@ -41,6 +42,11 @@ type GlyphMask = *ebiten.Image
 // [Blend]: https://pkg.go.dev/github.com/hajimehoshi/ebiten/v2#Blend
 type BlendMode = ebiten.Blend
 
+// Ebitengine images can already hold RGBA values, so subpixel masks
+// and composed color glyphs use the same type as glyph masks. See
+// loadLCDMask() and colorLoadMask().
+type rgbaMask = *ebiten.Image
+
 // Underlying default glyph drawing function for renderers.
 // Can be overridden with Renderer.Glyph().SetDrawFunc(...).
 func (self *Renderer) defaultDrawFunc(target Target, origin fract.Point, mask GlyphMask) {
@@ -61,6 +67,144 @@ func (self *Renderer) defaultDrawFunc(target Target, origin fract.Point, mask Gl
 	target.DrawImage(mask, &opts)
 }
 
+// Blend factors for the first pass of drawLCDMask(), which attenuates
+// the target per channel: target *= (1 - coverage*alpha).
+var lcdAttenuateBlend = ebiten.Blend{
+	BlendFactorSourceRGB:        ebiten.BlendFactorZero,
+	BlendFactorSourceAlpha:      ebiten.BlendFactorZero,
+	BlendFactorDestinationRGB:   ebiten.BlendFactorOneMinusSourceColor,
+	BlendFactorDestinationAlpha: ebiten.BlendFactorOneMinusSourceAlpha,
+	BlendOperationRGB:           ebiten.BlendOperationAdd,
+	BlendOperationAlpha:         ebiten.BlendOperationAdd,
+}
+
+// Draws a subpixel mask generated by mask.LCDRasterizer, blending it
+// per channel. See convertLCDImageToRGBAMask().
+//
+// Per channel blending requires two passes, so it's only done with
+// ebiten.BlendSourceOver. Other blend modes are applied in a single
+// pass, which only blends the color per channel.
+func (self *Renderer) drawLCDMask(target Target, origin fract.Point, mask rgbaMask) {
+	if mask == nil {
+		return
+	} // spaces and empty glyphs will be nil
+
+	opts := ebiten.DrawImageOptions{}
+	srcRect := mask.Bounds()
+	opts.GeoM.Translate(float64(origin.X.ToIntFloor()+srcRect.Min.X), float64(origin.Y.ToIntFloor()+srcRect.Min.Y))
+	r, g, b, a := colorToFloat32(self.state.fontColor)
+	if self.state.blendMode == ebiten.BlendSourceOver {
+		opts.ColorScale.Scale(a, a, a, a)
+		opts.Blend = lcdAttenuateBlend
+		target.DrawImage(mask, &opts)
+		opts.ColorScale.Reset()
+		opts.Blend = ebiten.BlendLighter
+	} else {
+		opts.Blend = self.state.blendMode
+	}
+	opts.ColorScale.Scale(r, g, b, a)
+	target.DrawImage(mask, &opts)
+}
+
+// Draws a composed color glyph mask. The colors are already part of
+// the mask, so the text color is not applied.
+func (self *Renderer) drawColorMask(target Target, origin fract.Point, mask rgbaMask) {
+	if mask == nil {
+		return
+	} // spaces and empty glyphs will be nil
+
+	opts := ebiten.DrawImageOptions{}
+	srcRect := mask.Bounds()
+	opts.GeoM.Translate(float64(origin.X.ToIntFloor()+srcRect.Min.X), float64(origin.Y.ToIntFloor()+srcRect.Min.Y))
+	opts.Blend = self.state.blendMode
+	target.DrawImage(mask, &opts)
+}
+
+// Source for decoration rects, created on first use. The white pixel
+// is taken from the center of a bigger image to avoid bleeding edges
+// when scaling it.
+var decorationPixel *ebiten.Image
+
+// Draws a text decoration rect with the current color and blend mode.
+func (self *Renderer) drawDecorationRect(target Target, rect image.Rectangle) {
+	if decorationPixel == nil {
+		img := ebiten.NewImage(3, 3)
+		img.Fill(color.White)
+		decorationPixel = img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+	}
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(float64(rect.Dx()), float64(rect.Dy()))
+	opts.GeoM.Translate(float64(rect.Min.X), float64(rect.Min.Y))
+	r, g, b, a := colorToFloat32(self.state.fontColor)
+	opts.ColorScale.Scale(r, g, b, a)
+	opts.Blend = self.state.blendMode
+	target.DrawImage(decorationPixel, &opts)
+}
+
+// Kage shader for drawing distance field masks, created on first use.
+// Mask values are interpolated manually, as shaders only sample the
+// nearest texels. The outline width and the softness are given in
+// target pixels, the spread in mask pixels.
+var sdfShader *ebiten.Shader
+
+const sdfShaderSource = `package main
+
+var Scale float
+var Spread float
+var Softness float
+var OutlineWidth float
+
+func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+	texSize := imageSrcTextureSize()
+	pos := texCoord*texSize - 0.5
+	base := floor(pos)
+	t := pos - base
+	a := imageSrc0At((base + vec2(0.5, 0.5)) / texSize).a
+	b := imageSrc0At((base + vec2(1.5, 0.5)) / texSize).a
+	c := imageSrc0At((base + vec2(0.5, 1.5)) / texSize).a
+	d := imageSrc0At((base + vec2(1.5, 1.5)) / texSize).a
+	value := mix(mix(a, b, t.x), mix(c, d, t.x), t.y)
+	dist := (value*255 - 128) / 127 * Spread * Scale
+	if OutlineWidth > 0 {
+		dist = OutlineWidth/2 - abs(dist)
+	}
+	return color * clamp(dist/Softness+0.5, 0, 1)
+}
+`
+
+// Draws a distance field mask scaled by the given factor, thresholding
+// it with a shader. See renderer_sdf.go.
+func (self *Renderer) drawSDFMask(target Target, origin fract.Point, field GlyphMask, sdf *mask.SDFRasterizer, scale float64) {
+	if field == nil {
+		return
+	} // spaces and empty glyphs will be nil
+
+	if sdfShader == nil {
+		shader, err := ebiten.NewShader([]byte(sdfShaderSource))
+		if err != nil {
+			panic("SDF shader compilation error: " + err.Error())
+		}
+		sdfShader = shader
+	}
+
+	opts := ebiten.DrawRectShaderOptions{}
+	srcRect := field.Bounds()
+	originX, originY := origin.ToFloat64s()
+	opts.GeoM.Scale(scale, scale)
+	opts.GeoM.Translate(originX+float64(srcRect.Min.X)*scale, originY+float64(srcRect.Min.Y)*scale)
+	r, g, b, a := colorToFloat32(self.state.fontColor)
+	opts.ColorScale.Scale(r, g, b, a)
+	opts.Blend = self.state.blendMode
+	opts.Images[0] = field
+	opts.Uniforms = map[string]any{
+		"Scale":        float32(scale),
+		"Spread":       float32(sdf.GetSpread()),
+		"Softness":     float32(sdf.GetEdgeSoftness()),
+		"OutlineWidth": float32(sdf.GetOutlineWidth()),
+	}
+	target.DrawRectShader(srcRect.Dx(), srcRect.Dy(), sdfShader, &opts)
+}
+
 // Convert a color to its float64 [0, 1.0] components.
 // This could actually be memorized to make DefaultDrawFunc work better
 // in most cases, but I don't know if it's worth the extra complexity.
@@ -107,3 +251,19 @@ func convertAlphaImageToGlyphMask(alpha *image.Alpha) GlyphMask {
 	opts := ebiten.NewImageFromImageOptions{PreserveBounds: true}
 	return ebiten.NewImageFromImageWithOptions(rgba, &opts)
 }
+
+// Converts a subpixel mask generated by mask.LCDRasterizer. See
+// lcdMaskToRGBA() for the format.
+func convertLCDImageToRGBAMask(lcd *image.Alpha) rgbaMask {
+	if lcd == nil {
+		return nil
+	}
+	opts := ebiten.NewImageFromImageOptions{PreserveBounds: true}
+	return ebiten.NewImageFromImageWithOptions(lcdMaskToRGBA(lcd), &opts)
+}
+
+// Converts a composed color glyph (premultiplied RGBA).
+func convertRGBAImageToRGBAMask(rgba *image.RGBA) rgbaMask {
+	opts := ebiten.NewImageFromImageOptions{PreserveBounds: true}
+	return ebiten.NewImageFromImageWithOptions(rgba, &opts)
+}
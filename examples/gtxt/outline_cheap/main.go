@@ -21,8 +21,8 @@ import (
 
 // This example draws text with a cheap and simple outline, made by
 // repeatedly drawing text slightly shifted to the left, right, up
-// and down. For higher quality outlines, see the OutlineRasterizer
-// instead and the gtxt/outline example.
+// and down. For higher quality outlines, see mask.StrokeRasterizer
+// instead.
 //
 // If you want a more advanced example on how to draw glyphs individually,
 // check gtxt/mirror instead. This example uses the renderer's DrawMask func,
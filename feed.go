@@ -14,6 +14,9 @@ import (
 // As a rule of thumb, you should only resort to feeds if
 // neither [Renderer.Draw]() nor [RendererGlyph.SetDrawFunc]()
 // give you enough control to do what you want.
+//
+// Feeds only support horizontal text directions, and they will panic
+// if used while the renderer's direction is [TopToBottom].
 type Feed struct {
 	Renderer       *Renderer       // associated renderer
 	Position       fract.Point     // the feed's working pen position or origin
@@ -101,7 +104,13 @@ func (self *Feed) Reset() {
 //
 // Quantization will be checked before every drawing operation and adjusted
 // if necessary (even vertical quantization).
+//
+// Tab characters ('\t') are handled like [Feed.Tab]().
 func (self *Feed) Draw(target Target, codePoint rune) {
+	if codePoint == '\t' {
+		self.Tab()
+		return
+	}
 	index, skip := self.Renderer.getGlyphIndex(self.Renderer.GetFont(), codePoint)
 	if !skip {
 		self.DrawGlyph(target, index)
@@ -132,6 +141,8 @@ func (self *Feed) Advance(codePoint rune) {
 	//       and one would rather Advance() than Draw(). Fair enough?
 	if codePoint == '\n' {
 		self.LineBreak()
+	} else if codePoint == '\t' {
+		self.Tab()
 	} else {
 		index, skip := self.Renderer.getGlyphIndex(self.Renderer.GetFont(), codePoint)
 		if !skip {
@@ -148,6 +159,9 @@ func (self *Feed) AdvanceGlyph(glyphIndex sfnt.GlyphIndex) {
 // Advances the feed's position with a line break.
 func (self *Feed) LineBreak() {
 	renderer := self.Renderer
+	if renderer.state.textDirection.isVertical() {
+		panic(feedVerticalPanicMsg)
+	}
 
 	// advance
 	self.Position.Y += renderer.state.fontSizer.LineAdvance(
@@ -162,6 +176,26 @@ func (self *Feed) LineBreak() {
 	self.LineBreakAcc += 1
 }
 
+// Advances the feed's position to the next tab stop, measured from
+// LineBreakX. Feeds can't look ahead, so all tab stops are treated
+// as [TabLeft] stops. See [RendererLayout.SetTabStops]().
+func (self *Feed) Tab() {
+	renderer := self.Renderer
+	switch renderer.GetDirection() {
+	case TopToBottom:
+		panic(feedVerticalPanicMsg)
+	case RightToLeft, BidiRightToLeft:
+		stop, _ := renderer.layoutNextTabStop(self.LineBreakX - self.Position.X)
+		self.Position.X = self.LineBreakX - stop
+	default:
+		stop, _ := renderer.layoutNextTabStop(self.Position.X - self.LineBreakX)
+		self.Position.X = self.LineBreakX + stop
+	}
+	self.PrevGlyphIndex = 0 // (no kerning across tabs)
+}
+
+const feedVerticalPanicMsg = "feeds don't support TopToBottom text"
+
 // Private traverse method used for Draw and Advance.
 func (self *Feed) traverseGlyph(target Target, glyphIndex sfnt.GlyphIndex, drawMode bool) {
 	// make sure all relevant properties are initialized
@@ -171,7 +205,7 @@ func (self *Feed) traverseGlyph(target Target, glyphIndex sfnt.GlyphIndex, drawM
 	// traverse in the proper direction
 	dir := self.Renderer.GetDirection()
 	switch dir {
-	case LeftToRight:
+	case LeftToRight, BidiLeftToRight, BidiAuto:
 		// TODO: revise both advances and compare with renderer.drawGlyphLTR
 		//       and renderer.advanceGlyphLTR and so on
 
@@ -194,7 +228,7 @@ func (self *Feed) traverseGlyph(target Target, glyphIndex sfnt.GlyphIndex, drawM
 
 		// advance
 		self.Position.X += renderer.getOpAdvance(glyphIndex)
-	case RightToLeft:
+	case RightToLeft, BidiRightToLeft:
 		// advance
 		self.Position.X -= renderer.getOpAdvance(glyphIndex)
 
@@ -212,6 +246,8 @@ func (self *Feed) traverseGlyph(target Target, glyphIndex sfnt.GlyphIndex, drawM
 		if drawMode {
 			renderer.internalGlyphDraw(target, glyphIndex, self.Position)
 		}
+	case TopToBottom:
+		panic(feedVerticalPanicMsg)
 	default:
 		panic(dir)
 	}
@@ -0,0 +1,36 @@
+package etxt
+
+import (
+	"testing"
+)
+
+func TestFeedVertical(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.SetDirection(TopToBottom)
+	feed := NewFeed(renderer)
+
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"Advance", func() { feed.Advance('a') }},
+		{"Advance('\\n')", func() { feed.Advance('\n') }},
+		{"LineBreak", feed.LineBreak},
+		{"Tab", feed.Tab},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != feedVerticalPanicMsg {
+					t.Fatalf("%s: expected panic %q, got %v", test.name, feedVerticalPanicMsg, r)
+				}
+			}()
+			test.fn()
+		}()
+	}
+}
@@ -0,0 +1,180 @@
+package font
+
+import (
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"sort"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Palette entry index used by color layers that must be drawn
+// with the text color instead of a palette color.
+const ForegroundPaletteEntry = 0xFFFF
+
+// A layer of a color glyph, as defined in the 'COLR' table. Each
+// layer is a regular glyph of the same font that must be drawn with
+// the given palette entry color.
+type ColorLayer struct {
+	Glyph        sfnt.GlyphIndex
+	PaletteEntry uint16 // ForegroundPaletteEntry for the text color
+}
+
+// Color glyph data, as defined in the 'COLR' (version 0) and 'CPAL'
+// tables of color fonts (mostly emoji and icon fonts). Color glyphs
+// are composed of multiple layers, each drawn with a different color
+// from the selected palette.
+//
+// [sfnt.Font] doesn't expose these tables, so they have to be parsed
+// separately from the raw font data with [ParseColorGlyphs](). The
+// result can be used with [RendererGlyph.SetColorGlyphs]().
+//
+// [RendererGlyph.SetColorGlyphs]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10#RendererGlyph.SetColorGlyphs
+type ColorGlyphs struct {
+	baseGlyphs     []colorBaseGlyph // sorted by glyph index
+	layers         []ColorLayer
+	palettes       [][]color.NRGBA
+	usesForeground bool
+}
+
+type colorBaseGlyph struct {
+	glyph      sfnt.GlyphIndex
+	firstLayer uint16
+	numLayers  uint16
+}
+
+var errInvalidColorTables = errors.New("invalid or truncated color tables")
+
+// Parses the color glyph data from the given font data. If the font
+// doesn't have 'COLR' and 'CPAL' tables, [ErrNotFound] is returned.
+// Only the version 0 records are used, which are also present in
+// most fonts with version 1 tables. Font collections are not supported.
+//
+// The returned data doesn't keep any reference to the given data.
+func ParseColorGlyphs(fontBytes []byte) (*ColorGlyphs, error) {
+	colr, err := findTable(fontBytes, "COLR")
+	if err != nil {
+		return nil, err
+	}
+	cpal, err := findTable(fontBytes, "CPAL")
+	if err != nil {
+		return nil, err
+	}
+
+	colors := &ColorGlyphs{}
+	err = colors.parseCOLR(colr)
+	if err != nil {
+		return nil, err
+	}
+	err = colors.parseCPAL(cpal)
+	if err != nil {
+		return nil, err
+	}
+	return colors, nil
+}
+
+func (self *ColorGlyphs) parseCOLR(colr []byte) error {
+	if len(colr) < 14 || binary.BigEndian.Uint16(colr[0:]) > 1 {
+		return errInvalidColorTables
+	}
+	numBaseGlyphs := int(binary.BigEndian.Uint16(colr[2:]))
+	baseGlyphsOffset := int(binary.BigEndian.Uint32(colr[4:]))
+	layersOffset := int(binary.BigEndian.Uint32(colr[8:]))
+	numLayers := int(binary.BigEndian.Uint16(colr[12:]))
+	if baseGlyphsOffset+numBaseGlyphs*6 > len(colr) || layersOffset+numLayers*4 > len(colr) {
+		return errInvalidColorTables
+	}
+
+	self.layers = make([]ColorLayer, numLayers)
+	for i := range self.layers {
+		record := colr[layersOffset+i*4:]
+		self.layers[i].Glyph = sfnt.GlyphIndex(binary.BigEndian.Uint16(record[0:]))
+		self.layers[i].PaletteEntry = binary.BigEndian.Uint16(record[2:])
+		if self.layers[i].PaletteEntry == ForegroundPaletteEntry {
+			self.usesForeground = true
+		}
+	}
+
+	self.baseGlyphs = make([]colorBaseGlyph, numBaseGlyphs)
+	for i := range self.baseGlyphs {
+		record := colr[baseGlyphsOffset+i*6:]
+		base := &self.baseGlyphs[i]
+		base.glyph = sfnt.GlyphIndex(binary.BigEndian.Uint16(record[0:]))
+		base.firstLayer = binary.BigEndian.Uint16(record[2:])
+		base.numLayers = binary.BigEndian.Uint16(record[4:])
+		if int(base.firstLayer)+int(base.numLayers) > numLayers {
+			return errInvalidColorTables
+		}
+	}
+	sort.Slice(self.baseGlyphs, func(i, j int) bool {
+		return self.baseGlyphs[i].glyph < self.baseGlyphs[j].glyph
+	}) // (records should already be sorted, but we don't rely on it)
+	return nil
+}
+
+func (self *ColorGlyphs) parseCPAL(cpal []byte) error {
+	if len(cpal) < 12 {
+		return errInvalidColorTables
+	}
+	numEntries := int(binary.BigEndian.Uint16(cpal[2:]))
+	numPalettes := int(binary.BigEndian.Uint16(cpal[4:]))
+	numColors := int(binary.BigEndian.Uint16(cpal[6:]))
+	colorsOffset := int(binary.BigEndian.Uint32(cpal[8:]))
+	if numPalettes == 0 || len(cpal) < 12+numPalettes*2 || colorsOffset+numColors*4 > len(cpal) {
+		return errInvalidColorTables
+	}
+
+	self.palettes = make([][]color.NRGBA, numPalettes)
+	for i := range self.palettes {
+		first := int(binary.BigEndian.Uint16(cpal[12+i*2:]))
+		if first+numEntries > numColors {
+			return errInvalidColorTables
+		}
+		palette := make([]color.NRGBA, numEntries)
+		for j := range palette {
+			record := cpal[colorsOffset+(first+j)*4:]
+			palette[j] = color.NRGBA{R: record[2], G: record[1], B: record[0], A: record[3]}
+		}
+		self.palettes[i] = palette
+	}
+	return nil
+}
+
+// Returns the layers of the given glyph, in drawing order, or nil if
+// the glyph is not a color glyph. The returned slice must not be
+// modified.
+func (self *ColorGlyphs) Layers(index sfnt.GlyphIndex) []ColorLayer {
+	i := sort.Search(len(self.baseGlyphs), func(i int) bool {
+		return self.baseGlyphs[i].glyph >= index
+	})
+	if i == len(self.baseGlyphs) || self.baseGlyphs[i].glyph != index {
+		return nil
+	}
+	base := self.baseGlyphs[i]
+	if base.numLayers == 0 {
+		return nil
+	}
+	return self.layers[base.firstLayer : base.firstLayer+base.numLayers]
+}
+
+// Returns the number of palettes. There's always at least one.
+func (self *ColorGlyphs) NumPalettes() int {
+	return len(self.palettes)
+}
+
+// Returns the color of the given palette entry. The bool will be false
+// if the palette or the entry are out of range, including the case of
+// [ForegroundPaletteEntry].
+func (self *ColorGlyphs) PaletteColor(palette int, entry uint16) (color.NRGBA, bool) {
+	if palette < 0 || palette >= len(self.palettes) || int(entry) >= len(self.palettes[palette]) {
+		return color.NRGBA{}, false
+	}
+	return self.palettes[palette][entry], true
+}
+
+// Returns whether any of the color layers uses the text color
+// (see [ForegroundPaletteEntry]).
+func (self *ColorGlyphs) UsesForeground() bool {
+	return self.usesForeground
+}
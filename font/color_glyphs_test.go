@@ -0,0 +1,96 @@
+package font
+
+import (
+	"image/color"
+	"os"
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Returns minimal 'COLR' and 'CPAL' tables: glyph 5 has layers 7 (entry 1)
+// and 8 (foreground), glyph 2 has layer 3 (entry 0), and there are two
+// palettes with two entries each.
+func testColorTables() []testTable {
+	colr := []byte{
+		0, 0, 0, 2, // version, num base glyphs
+		0, 0, 0, 14, 0, 0, 0, 26, 0, 3, // base glyphs offset, layers offset, num layers
+		0, 5, 0, 1, 0, 2, // glyph 5, first layer 1, two layers
+		0, 2, 0, 0, 0, 1, // glyph 2, first layer 0, one layer
+		0, 3, 0, 0, // glyph 3, entry 0
+		0, 7, 0, 1, // glyph 7, entry 1
+		0, 8, 0xFF, 0xFF, // glyph 8, foreground
+	}
+	cpal := []byte{
+		0, 0, 0, 2, 0, 2, 0, 4, // version, num entries, num palettes, num colors
+		0, 0, 0, 16, 0, 0, 0, 2, // colors offset, palette indices
+		0, 0, 255, 255, 255, 0, 0, 128, // BGRA red, BGRA semi-transparent blue
+		0, 255, 0, 255, 0, 0, 0, 0, // BGRA green, BGRA transparent
+	}
+	return []testTable{{"COLR", colr}, {"CPAL", cpal}}
+}
+
+func TestParseColorGlyphs(t *testing.T) {
+	// fonts without color tables
+	data, err := os.ReadFile("test/Go-Regular.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseColorGlyphs(data)
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	colors, err := ParseColorGlyphs(testFontData(testColorTables()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := colors.Layers(5)
+	if len(layers) != 2 || layers[0] != (ColorLayer{7, 1}) || layers[1] != (ColorLayer{8, ForegroundPaletteEntry}) {
+		t.Fatalf("unexpected layers for glyph 5: %v", layers)
+	}
+	layers = colors.Layers(2)
+	if len(layers) != 1 || layers[0] != (ColorLayer{3, 0}) {
+		t.Fatalf("unexpected layers for glyph 2: %v", layers)
+	}
+	for _, index := range []sfnt.GlyphIndex{0, 3, 6, 100} {
+		if colors.Layers(index) != nil {
+			t.Fatalf("unexpected layers for glyph %d", index)
+		}
+	}
+	if !colors.UsesForeground() {
+		t.Fatalf("expected foreground usage")
+	}
+
+	// palettes
+	if colors.NumPalettes() != 2 {
+		t.Fatalf("expected 2 palettes, got %d", colors.NumPalettes())
+	}
+	tests := []struct {
+		palette int
+		entry   uint16
+		color   color.NRGBA
+		ok      bool
+	}{
+		{0, 0, color.NRGBA{255, 0, 0, 255}, true},
+		{0, 1, color.NRGBA{0, 0, 255, 128}, true},
+		{1, 0, color.NRGBA{0, 255, 0, 255}, true},
+		{1, 1, color.NRGBA{0, 0, 0, 0}, true},
+		{2, 0, color.NRGBA{}, false},
+		{0, ForegroundPaletteEntry, color.NRGBA{}, false},
+	}
+	for _, test := range tests {
+		paletteColor, ok := colors.PaletteColor(test.palette, test.entry)
+		if paletteColor != test.color || ok != test.ok {
+			t.Fatalf("palette %d entry %d: unexpected color %v (%t)", test.palette, test.entry, paletteColor, ok)
+		}
+	}
+
+	// truncated tables
+	tables := testColorTables()
+	tables[0].data = tables[0].data[:len(tables[0].data)-2]
+	_, err = ParseColorGlyphs(testFontData(tables))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,77 @@
+package font
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Text decoration metrics, as defined in the 'post' (underline) and
+// 'OS/2' (strikeout) tables of a font. Values are given in font units
+// with the y axis pointing up, so positive positions are above the
+// baseline. Positions indicate the top of the decoration strokes.
+type DecorationMetrics struct {
+	UnitsPerEm         int
+	UnderlinePosition  int // typically negative
+	UnderlineThickness int
+	StrikeoutPosition  int
+	StrikeoutThickness int
+}
+
+// Returns the decoration metrics of the given font. If the font doesn't
+// have the relevant tables or their values are not usable, reasonable
+// defaults are derived from the font's em size and x-height instead.
+// Strikeout metrics are also derived for fonts that are part of a
+// collection, as [sfnt.Font] doesn't expose the 'OS/2' table directly
+// and the raw data can't be recovered in that case.
+func GetDecorationMetrics(font *sfnt.Font) (DecorationMetrics, error) {
+	unitsPerEm := int(font.UnitsPerEm())
+	metrics := DecorationMetrics{UnitsPerEm: unitsPerEm}
+
+	// underline metrics from the 'post' table
+	if post := font.PostTable(); post != nil && post.UnderlineThickness > 0 {
+		metrics.UnderlinePosition = int(post.UnderlinePosition)
+		metrics.UnderlineThickness = int(post.UnderlineThickness)
+	} else {
+		metrics.UnderlineThickness = maxInt(unitsPerEm/20, 1)
+		metrics.UnderlinePosition = -unitsPerEm / 10
+	}
+
+	// strikeout metrics from the 'OS/2' table
+	buffer := getSfntBuffer()
+	defer releaseSfntBuffer(buffer)
+	var data bytes.Buffer
+	if _, err := font.WriteSourceTo(buffer, &data); err == nil {
+		os2, err := findTable(data.Bytes(), "OS/2")
+		if err == nil && len(os2) >= 30 {
+			thickness := int(int16(binary.BigEndian.Uint16(os2[26:])))
+			if thickness > 0 {
+				metrics.StrikeoutThickness = thickness
+				metrics.StrikeoutPosition = int(int16(binary.BigEndian.Uint16(os2[28:])))
+				return metrics, nil
+			}
+		}
+	}
+
+	// strikeout fallback: centered on half the x-height
+	fontMetrics, err := font.Metrics(buffer, fixed.I(unitsPerEm), 0)
+	if err != nil {
+		return metrics, err
+	}
+	xHeight := fontMetrics.XHeight.Round()
+	if xHeight <= 0 {
+		xHeight = unitsPerEm / 2
+	}
+	metrics.StrikeoutThickness = metrics.UnderlineThickness
+	metrics.StrikeoutPosition = (xHeight + metrics.StrikeoutThickness) / 2
+	return metrics, nil
+}
+
+func maxInt(a, b int) int {
+	if a >= b {
+		return a
+	}
+	return b
+}
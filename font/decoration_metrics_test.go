@@ -0,0 +1,29 @@
+package font
+
+import "testing"
+
+func TestGetDecorationMetrics(t *testing.T) {
+	ensureTestAssetsLoaded()
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	metrics, err := GetDecorationMetrics(testFontA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.UnitsPerEm != int(testFontA.UnitsPerEm()) {
+		t.Fatalf("expected %d units per em, got %d", testFontA.UnitsPerEm(), metrics.UnitsPerEm)
+	}
+	if metrics.UnderlineThickness <= 0 || metrics.StrikeoutThickness <= 0 {
+		t.Fatalf("expected positive thicknesses, got %+v", metrics)
+	}
+	if metrics.UnderlinePosition >= metrics.StrikeoutPosition {
+		t.Fatalf("expected underline below strikeout, got %+v", metrics)
+	}
+	if post := testFontA.PostTable(); post != nil && post.UnderlineThickness > 0 {
+		if metrics.UnderlinePosition != int(post.UnderlinePosition) {
+			t.Fatalf("expected underline position %d, got %d", post.UnderlinePosition, metrics.UnderlinePosition)
+		}
+	}
+}
@@ -0,0 +1,653 @@
+package font
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sort"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Glyph positioning data, as defined in the 'GPOS' table. Only pair
+// adjustments from the 'kern' feature (lookup type 2, both glyph pairs
+// and class-based pairs) and mark attachments from the 'mark' and
+// 'mkmk' features (lookup types 4 and 6) are supported. Values are
+// given in font units; see [sfnt.Font.UnitsPerEm]().
+//
+// [sfnt.Font] only exposes the legacy 'kern' table, while most modern
+// fonts only include kerning in 'GPOS', so it has to be parsed separately
+// from the raw font data with [ParseGlyphPositioning](). The result can
+// be used with [RendererGlyph.SetPositioning]() for kerning and mark
+// attachment.
+//
+// [RendererGlyph.SetPositioning]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10#RendererGlyph.SetPositioning
+type GlyphPositioning struct {
+	pairLookups [][]gposPairSubtable
+	markLookups [][]gposMarkSubtable
+}
+
+type gposPairSubtable struct {
+	format   uint16
+	coverage gposCoverage
+
+	// format 1: pairs for each coverage index, sorted by second glyph
+	pairSets [][]gposPair
+
+	// format 2: values for each class pair
+	classDef1   gposClassDef
+	classDef2   gposClassDef
+	class1Count int
+	class2Count int
+	classValues []int16
+}
+
+type gposPair struct {
+	second sfnt.GlyphIndex
+	value  int16
+}
+
+type gposMarkSubtable struct {
+	markCoverage gposCoverage
+	baseCoverage gposCoverage // base glyphs, or marks for mark-to-mark
+	numClasses   int
+	markClasses  []uint16     // for each mark coverage index
+	markAnchors  []gposAnchor // for each mark coverage index
+	baseAnchors  []gposAnchor // for each base coverage index and mark class
+}
+
+type gposAnchor struct {
+	x, y int16
+	ok   bool
+}
+
+type gposCoverage []gposRange // sorted by first glyph
+
+type gposRange struct {
+	first sfnt.GlyphIndex
+	last  sfnt.GlyphIndex
+	value uint16 // coverage index of the first glyph, or class
+}
+
+type gposClassDef []gposRange // sorted by first glyph
+
+const (
+	gposLookupPair       = 2
+	gposLookupMarkToBase = 4
+	gposLookupMarkToMark = 6
+	gposLookupExtension  = 9
+)
+
+var errInvalidGPOS = errors.New("invalid or truncated GPOS table")
+
+// Parses the glyph positioning data from the given font data. If the
+// font doesn't have a 'GPOS' table, [ErrNotFound] is returned. Lookups
+// are used regardless of the script and language system, and lookup
+// flags are ignored. Font collections are not supported.
+//
+// The returned data doesn't keep any reference to the given data.
+func ParseGlyphPositioning(fontBytes []byte) (*GlyphPositioning, error) {
+	gpos, err := findTable(fontBytes, "GPOS")
+	if err != nil {
+		return nil, err
+	}
+	if len(gpos) < 10 || binary.BigEndian.Uint16(gpos[0:]) != 1 {
+		return nil, errInvalidGPOS
+	}
+	featureList, ok := gposSubtable(gpos, binary.BigEndian.Uint16(gpos[6:]))
+	if !ok {
+		return nil, errInvalidGPOS
+	}
+	lookupList, ok := gposSubtable(gpos, binary.BigEndian.Uint16(gpos[8:]))
+	if !ok {
+		return nil, errInvalidGPOS
+	}
+	kernLookups, markLookups, err := gposFeatureLookups(featureList)
+	if err != nil {
+		return nil, err
+	}
+
+	positioning := &GlyphPositioning{}
+	for _, index := range kernLookups {
+		lookupType, subtables, err := gposLookupSubtables(lookupList, index)
+		if err != nil {
+			return nil, err
+		}
+		if lookupType != gposLookupPair {
+			continue
+		}
+		var lookup []gposPairSubtable
+		for _, data := range subtables {
+			subtable, err := parseGPOSPairSubtable(data)
+			if err != nil {
+				return nil, err
+			}
+			if subtable != nil {
+				lookup = append(lookup, *subtable)
+			}
+		}
+		if len(lookup) > 0 {
+			positioning.pairLookups = append(positioning.pairLookups, lookup)
+		}
+	}
+	for _, index := range markLookups {
+		lookupType, subtables, err := gposLookupSubtables(lookupList, index)
+		if err != nil {
+			return nil, err
+		}
+		if lookupType != gposLookupMarkToBase && lookupType != gposLookupMarkToMark {
+			continue
+		}
+		var lookup []gposMarkSubtable
+		for _, data := range subtables {
+			subtable, err := parseGPOSMarkSubtable(data)
+			if err != nil {
+				return nil, err
+			}
+			if subtable != nil {
+				lookup = append(lookup, *subtable)
+			}
+		}
+		if len(lookup) > 0 {
+			positioning.markLookups = append(positioning.markLookups, lookup)
+		}
+	}
+	return positioning, nil
+}
+
+// Returns whether the data has any pair adjustments.
+func (self *GlyphPositioning) HasKerning() bool {
+	return len(self.pairLookups) > 0
+}
+
+// Returns whether the data has any mark attachments.
+func (self *GlyphPositioning) HasMarks() bool {
+	return len(self.markLookups) > 0
+}
+
+// Returns the horizontal advance adjustment between the given pair of
+// glyphs, in font units. Only the advance of the first glyph is taken
+// into account, which is what fonts use for regular kerning. The bool
+// will be false if no pair adjustment applies to the given glyphs.
+func (self *GlyphPositioning) Kern(left, right sfnt.GlyphIndex) (int, bool) {
+	var kern int
+	var found bool
+	for _, lookup := range self.pairLookups {
+		for i := range lookup {
+			if value, ok := lookup[i].kern(left, right); ok {
+				kern += int(value)
+				found = true
+				break
+			}
+		}
+	}
+	return kern, found
+}
+
+// Returns whether the given glyph can be attached to other glyphs
+// as a mark.
+func (self *GlyphPositioning) IsMark(index sfnt.GlyphIndex) bool {
+	for _, lookup := range self.markLookups {
+		for i := range lookup {
+			if _, ok := lookup[i].markCoverage.index(index); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the offset that must be applied to the origin of the given
+// mark glyph, relative to the origin of the given base glyph, to attach
+// it to the base. The base can also be another mark. Values are given
+// in font units, with y pointing up. The bool will be false if the mark
+// can't be attached to the base.
+func (self *GlyphPositioning) MarkOffset(base, mark sfnt.GlyphIndex) (x, y int, ok bool) {
+	for _, lookup := range self.markLookups {
+		for i := range lookup {
+			if x, y, ok := lookup[i].offset(base, mark); ok {
+				return x, y, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// ---- subtables ----
+
+func (self *gposPairSubtable) kern(left, right sfnt.GlyphIndex) (int16, bool) {
+	coverageIndex, ok := self.coverage.index(left)
+	if !ok {
+		return 0, false
+	}
+	if self.format == 1 {
+		if coverageIndex >= len(self.pairSets) {
+			return 0, false
+		}
+		pairs := self.pairSets[coverageIndex]
+		i := sort.Search(len(pairs), func(i int) bool { return pairs[i].second >= right })
+		if i == len(pairs) || pairs[i].second != right {
+			return 0, false
+		}
+		return pairs[i].value, true
+	}
+
+	class1, class2 := self.classDef1.class(left), self.classDef2.class(right)
+	if class1 >= self.class1Count || class2 >= self.class2Count {
+		return 0, false
+	}
+	return self.classValues[class1*self.class2Count+class2], true
+}
+
+func (self *gposMarkSubtable) offset(base, mark sfnt.GlyphIndex) (int, int, bool) {
+	markIndex, ok := self.markCoverage.index(mark)
+	if !ok || markIndex >= len(self.markClasses) {
+		return 0, 0, false
+	}
+	baseIndex, ok := self.baseCoverage.index(base)
+	if !ok {
+		return 0, 0, false
+	}
+	class := int(self.markClasses[markIndex])
+	if class >= self.numClasses || baseIndex*self.numClasses+class >= len(self.baseAnchors) {
+		return 0, 0, false
+	}
+	markAnchor := self.markAnchors[markIndex]
+	baseAnchor := self.baseAnchors[baseIndex*self.numClasses+class]
+	if !markAnchor.ok || !baseAnchor.ok {
+		return 0, 0, false
+	}
+	return int(baseAnchor.x) - int(markAnchor.x), int(baseAnchor.y) - int(markAnchor.y), true
+}
+
+func (self gposCoverage) index(glyph sfnt.GlyphIndex) (int, bool) {
+	i := sort.Search(len(self), func(i int) bool { return self[i].last >= glyph })
+	if i == len(self) || self[i].first > glyph {
+		return 0, false
+	}
+	return int(self[i].value) + int(glyph-self[i].first), true
+}
+
+func (self gposClassDef) class(glyph sfnt.GlyphIndex) int {
+	i := sort.Search(len(self), func(i int) bool { return self[i].last >= glyph })
+	if i == len(self) || self[i].first > glyph {
+		return 0
+	}
+	return int(self[i].value)
+}
+
+// ---- parsing helpers ----
+
+// Returns the data starting at the given offset, or false if the
+// offset is null or out of bounds.
+func gposSubtable(data []byte, offset uint16) ([]byte, bool) {
+	if offset == 0 || int(offset) >= len(data) {
+		return nil, false
+	}
+	return data[offset:], true
+}
+
+// Returns the sorted indices of the lookups referenced by the 'kern'
+// feature and by the 'mark' and 'mkmk' features.
+func gposFeatureLookups(featureList []byte) (kern, mark []int, err error) {
+	if len(featureList) < 2 {
+		return nil, nil, errInvalidGPOS
+	}
+	numFeatures := int(binary.BigEndian.Uint16(featureList[0:]))
+	if len(featureList) < 2+numFeatures*6 {
+		return nil, nil, errInvalidGPOS
+	}
+	for i := 0; i < numFeatures; i++ {
+		record := featureList[2+i*6:]
+		tag := string(record[0:4])
+		if tag != "kern" && tag != "mark" && tag != "mkmk" {
+			continue
+		}
+		feature, ok := gposSubtable(featureList, binary.BigEndian.Uint16(record[4:]))
+		if !ok || len(feature) < 4 {
+			return nil, nil, errInvalidGPOS
+		}
+		numLookups := int(binary.BigEndian.Uint16(feature[2:]))
+		if len(feature) < 4+numLookups*2 {
+			return nil, nil, errInvalidGPOS
+		}
+		for j := 0; j < numLookups; j++ {
+			index := int(binary.BigEndian.Uint16(feature[4+j*2:]))
+			if tag == "kern" {
+				kern = append(kern, index)
+			} else {
+				mark = append(mark, index)
+			}
+		}
+	}
+	return sortedUniqueInts(kern), sortedUniqueInts(mark), nil
+}
+
+// Returns the type and the subtables of the given lookup. Extension
+// subtables are resolved.
+func gposLookupSubtables(lookupList []byte, index int) (uint16, [][]byte, error) {
+	if len(lookupList) < 2 || index >= int(binary.BigEndian.Uint16(lookupList[0:])) {
+		return 0, nil, errInvalidGPOS
+	}
+	if len(lookupList) < 2+(index+1)*2 {
+		return 0, nil, errInvalidGPOS
+	}
+	lookup, ok := gposSubtable(lookupList, binary.BigEndian.Uint16(lookupList[2+index*2:]))
+	if !ok || len(lookup) < 6 {
+		return 0, nil, errInvalidGPOS
+	}
+	lookupType := binary.BigEndian.Uint16(lookup[0:])
+	numSubtables := int(binary.BigEndian.Uint16(lookup[4:]))
+	if len(lookup) < 6+numSubtables*2 {
+		return 0, nil, errInvalidGPOS
+	}
+
+	subtables := make([][]byte, 0, numSubtables)
+	resolvedType := lookupType
+	for i := 0; i < numSubtables; i++ {
+		subtable, ok := gposSubtable(lookup, binary.BigEndian.Uint16(lookup[6+i*2:]))
+		if !ok {
+			return 0, nil, errInvalidGPOS
+		}
+		if lookupType == gposLookupExtension {
+			// all the extension subtables must have the same type
+			if len(subtable) < 8 || binary.BigEndian.Uint16(subtable[0:]) != 1 {
+				return 0, nil, errInvalidGPOS
+			}
+			extType := binary.BigEndian.Uint16(subtable[2:])
+			extOffset := binary.BigEndian.Uint32(subtable[4:])
+			if extType == gposLookupExtension || (i > 0 && extType != resolvedType) {
+				return 0, nil, errInvalidGPOS
+			}
+			if extOffset == 0 || uint64(extOffset) >= uint64(len(subtable)) {
+				return 0, nil, errInvalidGPOS
+			}
+			resolvedType = extType
+			subtable = subtable[extOffset:]
+		}
+		subtables = append(subtables, subtable)
+	}
+	return resolvedType, subtables, nil
+}
+
+// Parses a pair adjustment subtable. Only the advance of the first
+// glyph is kept. Unknown formats return a nil subtable.
+func parseGPOSPairSubtable(data []byte) (*gposPairSubtable, error) {
+	if len(data) < 10 {
+		return nil, errInvalidGPOS
+	}
+	subtable := &gposPairSubtable{format: binary.BigEndian.Uint16(data[0:])}
+	if subtable.format != 1 && subtable.format != 2 {
+		return nil, nil
+	}
+	var err error
+	subtable.coverage, err = parseGPOSCoverage(data, binary.BigEndian.Uint16(data[2:]))
+	if err != nil {
+		return nil, err
+	}
+	valueFormat1 := binary.BigEndian.Uint16(data[4:])
+	valueFormat2 := binary.BigEndian.Uint16(data[6:])
+	valuesSize := gposValueRecordSize(valueFormat1) + gposValueRecordSize(valueFormat2)
+	advanceOffset := gposXAdvanceOffset(valueFormat1)
+	readAdvance := func(record []byte) int16 {
+		if advanceOffset == -1 {
+			return 0
+		}
+		return int16(binary.BigEndian.Uint16(record[advanceOffset:]))
+	}
+
+	if subtable.format == 1 {
+		numPairSets := int(binary.BigEndian.Uint16(data[8:]))
+		if len(data) < 10+numPairSets*2 {
+			return nil, errInvalidGPOS
+		}
+		subtable.pairSets = make([][]gposPair, numPairSets)
+		for i := 0; i < numPairSets; i++ {
+			pairSet, ok := gposSubtable(data, binary.BigEndian.Uint16(data[10+i*2:]))
+			if !ok || len(pairSet) < 2 {
+				return nil, errInvalidGPOS
+			}
+			numPairs := int(binary.BigEndian.Uint16(pairSet[0:]))
+			recordSize := 2 + valuesSize
+			if len(pairSet) < 2+numPairs*recordSize {
+				return nil, errInvalidGPOS
+			}
+			pairs := make([]gposPair, numPairs)
+			for j := range pairs {
+				record := pairSet[2+j*recordSize:]
+				pairs[j].second = sfnt.GlyphIndex(binary.BigEndian.Uint16(record[0:]))
+				pairs[j].value = readAdvance(record[2:])
+			}
+			sort.Slice(pairs, func(a, b int) bool { return pairs[a].second < pairs[b].second })
+			subtable.pairSets[i] = pairs
+		}
+		return subtable, nil
+	}
+
+	if len(data) < 16 {
+		return nil, errInvalidGPOS
+	}
+	subtable.classDef1, err = parseGPOSClassDef(data, binary.BigEndian.Uint16(data[8:]))
+	if err != nil {
+		return nil, err
+	}
+	subtable.classDef2, err = parseGPOSClassDef(data, binary.BigEndian.Uint16(data[10:]))
+	if err != nil {
+		return nil, err
+	}
+	subtable.class1Count = int(binary.BigEndian.Uint16(data[12:]))
+	subtable.class2Count = int(binary.BigEndian.Uint16(data[14:]))
+	numValues := subtable.class1Count * subtable.class2Count
+	if len(data) < 16+numValues*valuesSize {
+		return nil, errInvalidGPOS
+	}
+	subtable.classValues = make([]int16, numValues)
+	for i := range subtable.classValues {
+		subtable.classValues[i] = readAdvance(data[16+i*valuesSize:])
+	}
+	return subtable, nil
+}
+
+// Parses a mark-to-base or mark-to-mark attachment subtable, which
+// share the same structure. Unknown formats return a nil subtable.
+func parseGPOSMarkSubtable(data []byte) (*gposMarkSubtable, error) {
+	if len(data) < 12 {
+		return nil, errInvalidGPOS
+	}
+	if binary.BigEndian.Uint16(data[0:]) != 1 {
+		return nil, nil
+	}
+	var err error
+	subtable := &gposMarkSubtable{}
+	subtable.markCoverage, err = parseGPOSCoverage(data, binary.BigEndian.Uint16(data[2:]))
+	if err != nil {
+		return nil, err
+	}
+	subtable.baseCoverage, err = parseGPOSCoverage(data, binary.BigEndian.Uint16(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+	subtable.numClasses = int(binary.BigEndian.Uint16(data[6:]))
+	markArray, ok := gposSubtable(data, binary.BigEndian.Uint16(data[8:]))
+	if !ok {
+		return nil, errInvalidGPOS
+	}
+	baseArray, ok := gposSubtable(data, binary.BigEndian.Uint16(data[10:]))
+	if !ok {
+		return nil, errInvalidGPOS
+	}
+
+	// mark records: class and anchor
+	if len(markArray) < 2 {
+		return nil, errInvalidGPOS
+	}
+	numMarks := int(binary.BigEndian.Uint16(markArray[0:]))
+	if len(markArray) < 2+numMarks*4 {
+		return nil, errInvalidGPOS
+	}
+	subtable.markClasses = make([]uint16, numMarks)
+	subtable.markAnchors = make([]gposAnchor, numMarks)
+	for i := 0; i < numMarks; i++ {
+		record := markArray[2+i*4:]
+		subtable.markClasses[i] = binary.BigEndian.Uint16(record[0:])
+		subtable.markAnchors[i], err = parseGPOSAnchor(markArray, binary.BigEndian.Uint16(record[2:]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// base records: one anchor per mark class
+	if len(baseArray) < 2 {
+		return nil, errInvalidGPOS
+	}
+	numBases := int(binary.BigEndian.Uint16(baseArray[0:]))
+	numAnchors := numBases * subtable.numClasses
+	if len(baseArray) < 2+numAnchors*2 {
+		return nil, errInvalidGPOS
+	}
+	subtable.baseAnchors = make([]gposAnchor, numAnchors)
+	for i := 0; i < numAnchors; i++ {
+		subtable.baseAnchors[i], err = parseGPOSAnchor(baseArray, binary.BigEndian.Uint16(baseArray[2+i*2:]))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return subtable, nil
+}
+
+// Parses an anchor table. Null offsets return an anchor that's not
+// ok. Only the design coordinates are used, all formats have them.
+func parseGPOSAnchor(data []byte, offset uint16) (gposAnchor, error) {
+	if offset == 0 {
+		return gposAnchor{}, nil
+	}
+	anchor, ok := gposSubtable(data, offset)
+	if !ok || len(anchor) < 6 {
+		return gposAnchor{}, errInvalidGPOS
+	}
+	x := int16(binary.BigEndian.Uint16(anchor[2:]))
+	y := int16(binary.BigEndian.Uint16(anchor[4:]))
+	return gposAnchor{x: x, y: y, ok: true}, nil
+}
+
+func parseGPOSCoverage(data []byte, offset uint16) (gposCoverage, error) {
+	table, ok := gposSubtable(data, offset)
+	if !ok || len(table) < 4 {
+		return nil, errInvalidGPOS
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	var coverage gposCoverage
+	switch binary.BigEndian.Uint16(table[0:]) {
+	case 1: // glyph array, merged into ranges
+		if len(table) < 4+count*2 {
+			return nil, errInvalidGPOS
+		}
+		for i := 0; i < count; i++ {
+			glyph := sfnt.GlyphIndex(binary.BigEndian.Uint16(table[4+i*2:]))
+			if n := len(coverage); n > 0 {
+				last := &coverage[n-1]
+				if glyph == last.last+1 && int(last.value)+int(last.last-last.first)+1 == i {
+					last.last = glyph
+					continue
+				}
+			}
+			coverage = append(coverage, gposRange{glyph, glyph, uint16(i)})
+		}
+	case 2: // glyph ranges
+		ranges, err := parseGPOSRanges(table[4:], count)
+		if err != nil {
+			return nil, err
+		}
+		coverage = gposCoverage(ranges)
+	default:
+		return nil, errInvalidGPOS
+	}
+	sort.Slice(coverage, func(a, b int) bool { return coverage[a].first < coverage[b].first })
+	return coverage, nil
+}
+
+func parseGPOSClassDef(data []byte, offset uint16) (gposClassDef, error) {
+	if offset == 0 { // all glyphs in class 0
+		return nil, nil
+	}
+	table, ok := gposSubtable(data, offset)
+	if !ok || len(table) < 4 {
+		return nil, errInvalidGPOS
+	}
+	var classDef gposClassDef
+	switch binary.BigEndian.Uint16(table[0:]) {
+	case 1: // class array, merged into ranges
+		if len(table) < 6 {
+			return nil, errInvalidGPOS
+		}
+		start := int(binary.BigEndian.Uint16(table[2:]))
+		count := int(binary.BigEndian.Uint16(table[4:]))
+		if len(table) < 6+count*2 || start+count > 0x10000 {
+			return nil, errInvalidGPOS
+		}
+		for i := 0; i < count; i++ {
+			glyph := sfnt.GlyphIndex(start + i)
+			class := binary.BigEndian.Uint16(table[6+i*2:])
+			if n := len(classDef); n > 0 && classDef[n-1].value == class && classDef[n-1].last+1 == glyph {
+				classDef[n-1].last = glyph
+			} else if class != 0 {
+				classDef = append(classDef, gposRange{glyph, glyph, class})
+			}
+		}
+	case 2: // class ranges
+		ranges, err := parseGPOSRanges(table[4:], int(binary.BigEndian.Uint16(table[2:])))
+		if err != nil {
+			return nil, err
+		}
+		classDef = gposClassDef(ranges)
+	default:
+		return nil, errInvalidGPOS
+	}
+	sort.Slice(classDef, func(a, b int) bool { return classDef[a].first < classDef[b].first })
+	return classDef, nil
+}
+
+// Parses range records (start glyph, end glyph and a value), used
+// by both coverage and class definition tables.
+func parseGPOSRanges(data []byte, count int) ([]gposRange, error) {
+	if len(data) < count*6 {
+		return nil, errInvalidGPOS
+	}
+	ranges := make([]gposRange, count)
+	for i := range ranges {
+		record := data[i*6:]
+		ranges[i].first = sfnt.GlyphIndex(binary.BigEndian.Uint16(record[0:]))
+		ranges[i].last = sfnt.GlyphIndex(binary.BigEndian.Uint16(record[2:]))
+		ranges[i].value = binary.BigEndian.Uint16(record[4:])
+		if ranges[i].last < ranges[i].first {
+			return nil, errInvalidGPOS
+		}
+	}
+	return ranges, nil
+}
+
+// Returns the size of a value record with the given format.
+func gposValueRecordSize(format uint16) int {
+	return bits.OnesCount16(format&0x00FF) * 2
+}
+
+// Returns the offset of the x advance within a value record with
+// the given format, or -1 if not present.
+func gposXAdvanceOffset(format uint16) int {
+	if format&0x0004 == 0 {
+		return -1
+	}
+	return bits.OnesCount16(format&0x0003) * 2
+}
+
+func sortedUniqueInts(values []int) []int {
+	sort.Ints(values)
+	n := 0
+	for i, value := range values {
+		if i == 0 || value != values[n-1] {
+			values[n] = value
+			n += 1
+		}
+	}
+	return values[:n]
+}
@@ -0,0 +1,170 @@
+package font
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Returns an extension subtable wrapping the given subtable.
+func testExtension(lookupType int, subtable []byte) []byte {
+	return append(testBE16(1, lookupType, 0, 8), subtable...)
+}
+
+// Returns a lookup with the given subtables.
+func testLookup(lookupType int, subtables ...[]byte) []byte {
+	lookup := testBE16(lookupType, 0, len(subtables))
+	offset := 6 + 2*len(subtables)
+	for _, subtable := range subtables {
+		lookup = append(lookup, testBE16(offset)...)
+		offset += len(subtable)
+	}
+	for _, subtable := range subtables {
+		lookup = append(lookup, subtable...)
+	}
+	return lookup
+}
+
+// Returns a minimal 'GPOS' table:
+//   - 'kern': lookup 0 (extension), with a glyph pair subtable for glyph 3
+//     (-50 with glyph 4, 0 with glyph 6) and a class-based subtable for
+//     glyphs 3 to 5.
+//   - 'mark': lookup 1, attaching marks 10 (class 0) and 11 (class 1)
+//     to bases 3 and 5 (which has no anchor for class 1).
+//   - 'mkmk': lookup 2, attaching mark 12 to mark 10.
+//   - lookup 3, a pair adjustment not referenced by any feature.
+func testPositioningTable() []byte {
+	pairs := testBE16(
+		1, 22, 4, 0, 1, 12, // format, coverage, value formats, pair sets
+		2, 4, -50, 6, 0, // pair set
+		1, 1, 3, // coverage
+	)
+	classes := testBE16(
+		2, 40, 5, 0, 50, 62, 2, 3, // format, coverage, value formats, class defs, class counts
+		0, 0, 0, -10, 0, -20, // class1 0 (x placement, x advance)
+		0, 0, 7, -30, 0, -40, // class1 1
+		2, 1, 3, 5, 0, // coverage
+		1, 3, 3, 1, 0, 1, // class def 1
+		2, 2, 4, 4, 1, 6, 7, 2, // class def 2
+	)
+	unused := testBE16(1, 22, 4, 0, 1, 12, 1, 4, -999, 0, 0, 1, 1, 3)
+	markBase := testBE16(
+		1, 62, 70, 2, 12, 34, // format, coverages, classes, arrays
+		2, 0, 10, 1, 16, 1, 100, 500, 1, 120, -20, // mark array
+		2, 10, 16, 22, 0, 1, 300, 700, 1, 310, -10, 1, 400, 800, // base array
+		1, 2, 10, 11, // mark coverage
+		1, 2, 3, 5, // base coverage
+	)
+	markMark := testBE16(
+		1, 34, 40, 1, 12, 24, // format, coverages, classes, arrays
+		1, 0, 6, 1, 0, 0, // mark1 array
+		1, 4, 1, 100, 650, // mark2 array
+		1, 1, 12, // mark1 coverage
+		1, 1, 10, // mark2 coverage
+	)
+	lookups := [][]byte{
+		testLookup(9, testExtension(2, pairs), testExtension(2, classes)),
+		testLookup(4, markBase),
+		testLookup(6, markMark),
+		testLookup(2, unused),
+	}
+	lookupList := testBE16(len(lookups))
+	offset := 2 + 2*len(lookups)
+	for _, lookup := range lookups {
+		lookupList = append(lookupList, testBE16(offset)...)
+		offset += len(lookup)
+	}
+	for _, lookup := range lookups {
+		lookupList = append(lookupList, lookup...)
+	}
+
+	featureList := testBE16(3)
+	for i, tag := range []string{"kern", "mark", "mkmk"} {
+		featureList = append(featureList, tag...)
+		featureList = append(featureList, testBE16(20+i*6)...)
+	}
+	featureList = append(featureList, testBE16(0, 1, 0, 0, 1, 1, 0, 1, 2)...)
+
+	gpos := testBE16(1, 0, 10, 12, 12+len(featureList), 0)
+	gpos = append(gpos, featureList...)
+	return append(gpos, lookupList...)
+}
+
+func TestParseGlyphPositioning(t *testing.T) {
+	// fonts without a positioning table
+	data, err := os.ReadFile("test/Go-Regular.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseGlyphPositioning(data)
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	positioning, err := ParseGlyphPositioning(testFontData([]testTable{{"GPOS", testPositioningTable()}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !positioning.HasKerning() || !positioning.HasMarks() {
+		t.Fatalf("expected kerning and marks")
+	}
+
+	// pair adjustments
+	kernTests := []struct {
+		left, right sfnt.GlyphIndex
+		kern        int
+		ok          bool
+	}{
+		{3, 4, -50, true}, // glyph pair, before class pairs
+		{3, 6, 0, true},   // glyph pair with zero value
+		{5, 6, -40, true},
+		{4, 7, -20, true},
+		{5, 5, 0, true}, // class 0 for the second glyph
+		{3, 8, 0, true},
+		{9, 4, 0, false},
+		{4, 3, 0, true},
+	}
+	for _, test := range kernTests {
+		kern, ok := positioning.Kern(test.left, test.right)
+		if kern != test.kern || ok != test.ok {
+			t.Fatalf("Kern(%d, %d): expected (%d, %t), got (%d, %t)", test.left, test.right, test.kern, test.ok, kern, ok)
+		}
+	}
+
+	// mark attachments
+	markTests := []struct {
+		base, mark sfnt.GlyphIndex
+		x, y       int
+		ok         bool
+	}{
+		{3, 10, 200, 200, true},
+		{3, 11, 190, 10, true},
+		{5, 10, 300, 300, true},
+		{5, 11, 0, 0, false}, // null anchor
+		{10, 12, 100, 650, true},
+		{3, 12, 0, 0, false},
+		{4, 10, 0, 0, false},
+	}
+	for _, test := range markTests {
+		x, y, ok := positioning.MarkOffset(test.base, test.mark)
+		if x != test.x || y != test.y || ok != test.ok {
+			t.Fatalf("MarkOffset(%d, %d): expected (%d, %d, %t), got (%d, %d, %t)", test.base, test.mark, test.x, test.y, test.ok, x, y, ok)
+		}
+	}
+	for glyph := sfnt.GlyphIndex(0); glyph < 16; glyph++ {
+		isMark := (glyph >= 10 && glyph <= 12)
+		if positioning.IsMark(glyph) != isMark {
+			t.Fatalf("IsMark(%d): expected %t", glyph, isMark)
+		}
+	}
+
+	// truncated data
+	table := testPositioningTable()
+	for _, size := range []int{0, 9, 20, 60, 150, 250} {
+		_, err = ParseGlyphPositioning(testFontData([]testTable{{"GPOS", table[:size]}}))
+		if err == nil || err == ErrNotFound {
+			t.Fatalf("expected error for table truncated to %d bytes, got %v", size, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package font
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Helpers for the parsing of font tables not exposed by [sfnt.Font].
+
+var errInvalidTableDir = errors.New("invalid or truncated font table directory")
+
+// Returns the data of the given table, or ErrNotFound if missing.
+func findTable(fontBytes []byte, tag string) ([]byte, error) {
+	if len(fontBytes) < 12 {
+		return nil, errInvalidTableDir
+	}
+	if string(fontBytes[0:4]) == "ttcf" {
+		return nil, errors.New("font collections are not supported")
+	}
+	numTables := int(binary.BigEndian.Uint16(fontBytes[4:]))
+	if len(fontBytes) < 12+numTables*16 {
+		return nil, errInvalidTableDir
+	}
+	for i := 0; i < numTables; i++ {
+		record := fontBytes[12+i*16:]
+		if string(record[0:4]) != tag {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(record[8:]))
+		length := int(binary.BigEndian.Uint32(record[12:]))
+		if offset < 0 || length < 0 || offset+length > len(fontBytes) || offset+length < offset {
+			return nil, errInvalidTableDir
+		}
+		return fontBytes[offset : offset+length], nil
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,57 @@
+package font
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// Returns the given values as big endian uint16s.
+func testBE16(values ...int) []byte {
+	data := make([]byte, 0, len(values)*2)
+	for _, value := range values {
+		data = append(data, byte(uint16(value)>>8), byte(uint16(value)))
+	}
+	return data
+}
+
+type testTable struct {
+	tag  string
+	data []byte
+}
+
+// Returns minimal font data with the given tables.
+func testFontData(tables []testTable) []byte {
+	data := make([]byte, 12+16*len(tables))
+	binary.BigEndian.PutUint16(data[4:], uint16(len(tables)))
+	for i, table := range tables {
+		record := data[12+16*i:]
+		copy(record, table.tag)
+		binary.BigEndian.PutUint32(record[8:], uint32(len(data)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table.data)))
+		data = append(data, table.data...)
+	}
+	return data
+}
+
+func TestFindTable(t *testing.T) {
+	data := testFontData([]testTable{{"head", []byte{1, 2}}, {"vhea", []byte{3}}})
+	table, err := findTable(data, "vhea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table) != 1 || table[0] != 3 {
+		t.Fatalf("unexpected table data %v", table)
+	}
+	_, err = findTable(data, "vmtx")
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	_, err = findTable(data[:len(data)-1], "vhea")
+	if err == nil || err == ErrNotFound {
+		t.Fatalf("expected error for truncated data, got %v", err)
+	}
+	_, err = findTable([]byte{0, 1, 0, 0}, "head")
+	if err == nil || err == ErrNotFound {
+		t.Fatalf("expected error for truncated directory, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package font
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// Vertical glyph metrics, as defined in the 'vhea' and 'vmtx' tables
+// of fonts designed for vertical text (mostly CJK fonts). Values are
+// given in font units; see [VertMetrics.UnitsPerEm]().
+//
+// [sfnt.Font] doesn't expose these tables, so they have to be parsed
+// separately from the raw font data with [ParseVertMetrics](). The
+// result can be used with [RendererGlyph.SetVertMetrics]().
+//
+// [RendererGlyph.SetVertMetrics]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10#RendererGlyph.SetVertMetrics
+type VertMetrics struct {
+	unitsPerEm uint16
+	advances   []uint16 // one per long vertical metric
+	bearings   []int16  // top side bearings, one per glyph
+}
+
+var errInvalidVertTables = errors.New("invalid or truncated vertical metrics tables")
+
+// Parses the vertical metrics from the given font data. If the font
+// doesn't have 'vhea' and 'vmtx' tables, [ErrNotFound] is returned.
+// Font collections are not supported.
+//
+// The returned metrics don't keep any reference to the given data.
+func ParseVertMetrics(fontBytes []byte) (*VertMetrics, error) {
+	head, err := findTable(fontBytes, "head")
+	if err != nil {
+		return nil, err
+	}
+	vhea, err := findTable(fontBytes, "vhea")
+	if err != nil {
+		return nil, err
+	}
+	vmtx, err := findTable(fontBytes, "vmtx")
+	if err != nil {
+		return nil, err
+	}
+	if len(head) < 20 || len(vhea) < 36 {
+		return nil, errInvalidVertTables
+	}
+
+	metrics := &VertMetrics{unitsPerEm: binary.BigEndian.Uint16(head[18:])}
+	if metrics.unitsPerEm == 0 {
+		return nil, errInvalidVertTables
+	}
+
+	// long metrics are followed by top side bearings for the rest
+	// of the glyphs, which use the last advance
+	numLongMetrics := int(binary.BigEndian.Uint16(vhea[34:]))
+	if numLongMetrics == 0 || len(vmtx) < numLongMetrics*4 {
+		return nil, errInvalidVertTables
+	}
+	numBearings := numLongMetrics + (len(vmtx)-numLongMetrics*4)/2
+	metrics.advances = make([]uint16, numLongMetrics)
+	metrics.bearings = make([]int16, numBearings)
+	for i := 0; i < numLongMetrics; i++ {
+		metrics.advances[i] = binary.BigEndian.Uint16(vmtx[i*4:])
+		metrics.bearings[i] = int16(binary.BigEndian.Uint16(vmtx[i*4+2:]))
+	}
+	for i := numLongMetrics; i < numBearings; i++ {
+		offset := numLongMetrics*4 + (i-numLongMetrics)*2
+		metrics.bearings[i] = int16(binary.BigEndian.Uint16(vmtx[offset:]))
+	}
+	return metrics, nil
+}
+
+// Returns the font units per em, needed to scale the other values.
+func (self *VertMetrics) UnitsPerEm() int {
+	return int(self.unitsPerEm)
+}
+
+// Returns the vertical advance of the given glyph.
+func (self *VertMetrics) Advance(index sfnt.GlyphIndex) int {
+	if int(index) >= len(self.advances) {
+		return int(self.advances[len(self.advances)-1])
+	}
+	return int(self.advances[index])
+}
+
+// Returns the top side bearing of the given glyph: the distance from
+// the top of its vertical advance to the top of its bounds. The bool
+// will be false if the glyph is not covered by the metrics.
+func (self *VertMetrics) TopSideBearing(index sfnt.GlyphIndex) (int, bool) {
+	if int(index) >= len(self.bearings) {
+		return 0, false
+	}
+	return int(self.bearings[index]), true
+}
@@ -0,0 +1,59 @@
+package font
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestParseVertMetrics(t *testing.T) {
+	// fonts without vertical tables
+	data, err := os.ReadFile("test/Go-Regular.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseVertMetrics(data)
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	_, err = ParseVertMetrics([]byte{0, 1, 0, 0})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	// minimal font data with head, vhea and vmtx tables: two long
+	// metrics and one extra top side bearing
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint16(head[18:], 1000)
+	vhea := make([]byte, 36)
+	binary.BigEndian.PutUint16(vhea[34:], 2)
+	vmtx := []byte{0x03, 0xE8, 0x00, 0x32, 0x01, 0xF4, 0xFF, 0xF6, 0x00, 0x64}
+	data = testFontData([]testTable{{"head", head}, {"vhea", vhea}, {"vmtx", vmtx}})
+
+	metrics, err := ParseVertMetrics(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.UnitsPerEm() != 1000 {
+		t.Fatalf("expected 1000 units per em, got %d", metrics.UnitsPerEm())
+	}
+	advances := []int{1000, 500, 500, 500}
+	bearings := []int{50, -10, 100, 0}
+	for i := range advances {
+		if metrics.Advance(sfnt.GlyphIndex(i)) != advances[i] {
+			t.Fatalf("glyph %d: expected advance %d, got %d", i, advances[i], metrics.Advance(sfnt.GlyphIndex(i)))
+		}
+		bearing, ok := metrics.TopSideBearing(sfnt.GlyphIndex(i))
+		if bearing != bearings[i] || ok != (i < 3) {
+			t.Fatalf("glyph %d: unexpected top side bearing %d (%t)", i, bearing, ok)
+		}
+	}
+
+	// truncated tables
+	_, err = ParseVertMetrics(data[:len(data)-4])
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -5,6 +5,7 @@ go 1.18
 require (
 	github.com/hajimehoshi/ebiten/v2 v2.5.0
 	golang.org/x/image v0.9.0
+	golang.org/x/text v0.11.0
 )
 
 require (
@@ -15,5 +16,4 @@ require (
 	golang.org/x/mobile v0.0.0-20230301163155-e0f57694e12c // indirect
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/sys v0.6.0 // indirect
-	golang.org/x/text v0.11.0 // indirect
 )
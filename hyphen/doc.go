@@ -0,0 +1,16 @@
+// The hyphen subpackage defines the [Hyphenator] interface used
+// within etxt and provides a default implementation based on TeX
+// hyphenation patterns.
+//
+// Hyphenators can be set on renderers through RendererLayout.SetHyphenator()
+// in order to allow line wrapping within words. This is mostly relevant
+// for narrow columns of text, where wrapping only at spaces can leave
+// large gaps at the end of the lines.
+//
+// Hyphenation patterns are language specific. The [hyph-utf8] project
+// maintains pattern files for many languages; the "hyph-*.tex" and
+// "hyph-*.pat.txt" files can be loaded directly with [ParsePatterns]()
+// and similar functions.
+//
+// [hyph-utf8]: https://github.com/hyphenation/tex-hyphen
+package hyphen
@@ -0,0 +1,13 @@
+package hyphen
+
+// Hyphenators find the points where words can be hyphenated.
+//
+// Words given to hyphenators are always runs of letters without
+// any spaces or punctuation, but they can have any case.
+type Hyphenator interface {
+	// Sets points[i] to true for each index i where the word can be
+	// hyphenated, that is, where a hyphen can be placed between
+	// word[i-1] and word[i]. The points slice has the same length
+	// as the word, and all its values are false on entry.
+	Hyphenate(word []rune, points []bool)
+}
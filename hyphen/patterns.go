@@ -0,0 +1,251 @@
+package hyphen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var _ Hyphenator = (*Patterns)(nil)
+
+// A [Hyphenator] implementation based on Frank Liang's algorithm,
+// the one used by TeX, which uses language specific hyphenation
+// patterns and a list of exceptions.
+//
+// Patterns can be loaded with [ParsePatterns](), [ParsePatternsFromPath]()
+// or [ParsePatternsFromFS](). Like renderers, patterns are not safe for
+// concurrent use.
+type Patterns struct {
+	patterns   map[string][]uint8 // letters -> inter-letter values
+	exceptions map[string][]bool  // word -> hyphenation points
+	maxLength  int                // max pattern length, in runes
+	leftMin    int
+	rightMin   int
+
+	// buffers
+	word    []byte
+	offsets []int
+	values  []uint8
+}
+
+// Parses hyphenation patterns in TeX format. Both the contents of
+// "\patterns{...}" and "\hyphenation{...}" commands are processed,
+// and comments starting with '%' are ignored. If the data doesn't
+// contain any "\patterns" command, all the contents are parsed as
+// patterns. Only UTF-8 data is supported.
+//
+// The minimum number of letters before and after a hyphen are set
+// to 2 and 3 by default, like in TeX. See [Patterns.SetMinLengths]().
+func ParsePatterns(reader io.Reader) (*Patterns, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		return nil, errors.New("hyphenation patterns must be UTF-8 encoded")
+	}
+
+	self := &Patterns{
+		patterns:   make(map[string][]uint8),
+		exceptions: make(map[string][]bool),
+		leftMin:    2,
+		rightMin:   3,
+	}
+	content := stripComments(string(data))
+	exceptions, content, _, err := commandContents(content, `\hyphenation`)
+	if err != nil {
+		return nil, err
+	}
+	patterns, _, hasPatterns, err := commandContents(content, `\patterns`)
+	if err != nil {
+		return nil, err
+	}
+	if !hasPatterns {
+		patterns = content
+	}
+
+	for _, pattern := range strings.Fields(patterns) {
+		err = self.addPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, exception := range strings.Fields(exceptions) {
+		self.addException(exception)
+	}
+	return self, nil
+}
+
+// Same as [ParsePatterns](), but reading the data from the given path.
+func ParsePatternsFromPath(path string) (*Patterns, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParsePatterns(file)
+}
+
+// Same as [ParsePatterns](), but for embedded filesystems.
+func ParsePatternsFromFS(filesys fs.FS, path string) (*Patterns, error) {
+	file, err := filesys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParsePatterns(file)
+}
+
+// Sets the minimum number of letters that must appear before and
+// after a hyphen. Values below 1 are invalid. Defaults are 2 and 3.
+func (self *Patterns) SetMinLengths(left, right int) {
+	if left < 1 || right < 1 {
+		panic("hyphenation min lengths must be at least 1")
+	}
+	self.leftMin, self.rightMin = left, right
+}
+
+// Returns the minimum number of letters before and after a hyphen.
+// See [Patterns.SetMinLengths]() for more details.
+func (self *Patterns) GetMinLengths() (left, right int) {
+	return self.leftMin, self.rightMin
+}
+
+// Implements [Hyphenator].
+func (self *Patterns) Hyphenate(word []rune, points []bool) {
+	n := len(word)
+	if n < self.leftMin+self.rightMin {
+		return
+	}
+
+	// build the lowercase word with boundary markers
+	self.word = append(self.word[:0], '.')
+	self.offsets = append(self.offsets[:0], 0)
+	for _, codePoint := range word {
+		self.offsets = append(self.offsets, len(self.word))
+		self.word = utf8.AppendRune(self.word, unicode.ToLower(codePoint))
+	}
+	self.offsets = append(self.offsets, len(self.word))
+	self.word = append(self.word, '.')
+	self.offsets = append(self.offsets, len(self.word))
+
+	// exceptions take precedence over patterns
+	if exception, found := self.exceptions[string(self.word[1:len(self.word)-1])]; found {
+		for i := self.leftMin; i <= n-self.rightMin; i++ {
+			points[i] = exception[i]
+		}
+		return
+	}
+
+	// apply all matching patterns, keeping the max values
+	size := n + 2 // in runes, including boundary markers
+	self.values = self.values[:0]
+	for i := 0; i <= size; i++ {
+		self.values = append(self.values, 0)
+	}
+	for i := 0; i < size; i++ {
+		maxEnd := i + self.maxLength
+		if maxEnd > size {
+			maxEnd = size
+		}
+		for j := i + 1; j <= maxEnd; j++ {
+			values, found := self.patterns[string(self.word[self.offsets[i]:self.offsets[j]])]
+			if !found {
+				continue
+			}
+			for k, value := range values {
+				if value > self.values[i+k] {
+					self.values[i+k] = value
+				}
+			}
+		}
+	}
+
+	// odd values are hyphenation points (the value before
+	// word[i] is at i + 1 due to the initial boundary marker)
+	for i := self.leftMin; i <= n-self.rightMin; i++ {
+		points[i] = (self.values[i+1]&1 == 1)
+	}
+}
+
+// ---- parsing helpers ----
+
+// Parses a pattern like "hen5at" into its letters and values.
+func (self *Patterns) addPattern(pattern string) error {
+	var letters []rune
+	values := []uint8{0}
+	for _, codePoint := range pattern {
+		if codePoint >= '0' && codePoint <= '9' {
+			values[len(values)-1] = uint8(codePoint - '0')
+		} else {
+			letters = append(letters, unicode.ToLower(codePoint))
+			values = append(values, 0)
+		}
+	}
+	if len(letters) == 0 {
+		return errors.New("invalid hyphenation pattern '" + pattern + "'")
+	}
+	self.patterns[string(letters)] = values
+	if len(letters) > self.maxLength {
+		self.maxLength = len(letters)
+	}
+	return nil
+}
+
+// Parses an exception like "ta-ble" into its word and hyphenation points.
+func (self *Patterns) addException(exception string) {
+	var letters []rune
+	var points []bool = []bool{false}
+	for _, codePoint := range exception {
+		if codePoint == '-' {
+			points[len(points)-1] = true
+		} else {
+			letters = append(letters, unicode.ToLower(codePoint))
+			points = append(points, false)
+		}
+	}
+	self.exceptions[string(letters)] = points[:len(letters)]
+}
+
+func stripComments(content string) string {
+	var builder strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		if index := strings.IndexByte(line, '%'); index != -1 {
+			line = line[:index]
+		}
+		builder.WriteString(line)
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+// Returns the contents of all the occurrences of the given command,
+// the remaining content without them, and whether any was found.
+func commandContents(content string, command string) (string, string, bool, error) {
+	var contents, rest strings.Builder
+	var found bool
+	for {
+		index := strings.Index(content, command)
+		if index == -1 {
+			rest.WriteString(content)
+			return contents.String(), rest.String(), found, nil
+		}
+		rest.WriteString(content[:index])
+		content = strings.TrimLeft(content[index+len(command):], " \t\r\n")
+		if !strings.HasPrefix(content, "{") {
+			return "", "", false, errors.New("expected '{' after " + command)
+		}
+		end := strings.IndexByte(content, '}')
+		if end == -1 {
+			return "", "", false, errors.New("missing '}' after " + command)
+		}
+		contents.WriteString(content[1:end])
+		contents.WriteByte(' ')
+		content = content[end+1:]
+		found = true
+	}
+}
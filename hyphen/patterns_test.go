@@ -0,0 +1,89 @@
+package hyphen
+
+import (
+	"strings"
+	"testing"
+)
+
+// patterns from Liang's thesis, plus some exceptions
+const testPatterns = `
+% comment line
+\patterns{
+hy3ph he2n hena4 hen5at 1na n2at 1tio 2io o2n % trailing comment
+}
+\hyphenation{
+ta-ble
+}
+`
+
+func TestPatterns(t *testing.T) {
+	patterns, err := ParsePatterns(strings.NewReader(testPatterns))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		word     string
+		expected string
+	}{
+		{"hyphenation", "hy-phen-ation"},
+		{"Hyphenation", "Hy-phen-ation"},
+		{"table", "ta-ble"},
+		{"Table", "Ta-ble"},
+		{"nation", "na-tion"},
+		{"on", "on"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		word := []rune(test.word)
+		points := make([]bool, len(word))
+		patterns.Hyphenate(word, points)
+		if result := applyPoints(word, points); result != test.expected {
+			t.Fatalf("word %q: expected %q, got %q", test.word, test.expected, result)
+		}
+	}
+
+	// min lengths
+	patterns.SetMinLengths(3, 3)
+	word := []rune("hyphenation")
+	points := make([]bool, len(word))
+	patterns.Hyphenate(word, points)
+	if result := applyPoints(word, points); result != "hyphen-ation" {
+		t.Fatalf("expected %q, got %q", "hyphen-ation", result)
+	}
+
+	// plain pattern lists
+	patterns, err = ParsePatterns(strings.NewReader("hy3ph\nhe2n hena4 hen5at\n\\hyphenation{hyp-hen}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	word = []rune("hyphen")
+	points = make([]bool, len(word))
+	patterns.Hyphenate(word, points)
+	if result := applyPoints(word, points); result != "hyp-hen" {
+		t.Fatalf("expected %q, got %q", "hyp-hen", result)
+	}
+	word = []rune("hyphens")
+	points = make([]bool, len(word))
+	patterns.Hyphenate(word, points)
+	if result := applyPoints(word, points); result != "hy-phens" {
+		t.Fatalf("expected %q, got %q", "hy-phens", result)
+	}
+
+	// invalid data
+	_, err = ParsePatterns(strings.NewReader(`\patterns{ hy3ph`))
+	if err == nil {
+		t.Fatal("expected error for unclosed command")
+	}
+}
+
+func applyPoints(word []rune, points []bool) string {
+	var result []rune
+	for i, codePoint := range word {
+		if points[i] {
+			result = append(result, '-')
+		}
+		result = append(result, codePoint)
+	}
+	return string(result)
+}
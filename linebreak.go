@@ -0,0 +1,297 @@
+package etxt
+
+import "unicode"
+
+// This file contains an implementation of the Unicode Line Breaking
+// Algorithm (UAX #14), used by the layout process to find line wrapping
+// opportunities.
+//
+// Neither the standard library nor x/text provide line breaking
+// properties, so a reduced class table is included here. It covers
+// ASCII, common punctuation, spaces and CJK ranges, with anything
+// else being treated as alphabetic (rule LB1). The pair rules are
+// applied quite literally from LB4 to LB31, with a few exceptions:
+//   - Hebrew letters, regional indicators, emoji modifiers and Korean
+//     syllable blocks aren't distinguished (HL -> AL, RI/EB/EM -> ID,
+//     H2/H3/JL/JV/JT -> ID).
+//   - LB25 uses the simplified pair based version instead of the
+//     full numeric expression.
+//   - Mandatory breaks (BK, CR, NL) are reported as regular break
+//     opportunities. Only '\n' starts a new paragraph.
+
+type lineBreakClass uint8
+
+const (
+	lbAL  lineBreakClass = iota // alphabetic (default)
+	lbBA                        // break after (e.g. soft hyphen, en dash)
+	lbBB                        // break before
+	lbB2                        // break opportunity before and after (em dash)
+	lbBK                        // mandatory break
+	lbCL                        // close punctuation
+	lbCM                        // combining mark
+	lbCP                        // close parenthesis
+	lbCR                        // carriage return
+	lbEX                        // exclamation / interrogation
+	lbGL                        // non-breaking ("glue")
+	lbHY                        // hyphen-minus
+	lbID                        // ideographic
+	lbIN                        // inseparable (e.g. ellipsis)
+	lbIS                        // infix numeric separator
+	lbLF                        // line feed
+	lbNL                        // next line
+	lbNS                        // nonstarter
+	lbNU                        // numeric
+	lbOP                        // open punctuation
+	lbPO                        // postfix numeric
+	lbPR                        // prefix numeric
+	lbQU                        // quotation
+	lbSP                        // space
+	lbSY                        // symbols allowing break after (slash)
+	lbWJ                        // word joiner
+	lbZW                        // zero width space
+	lbZWJ                       // zero width joiner
+)
+
+// Reusable buffers for line break opportunities.
+type lineBreaker struct {
+	classes []lineBreakClass
+	attach  []bool // combining marks attached to their base (LB9)
+}
+
+// Computes the line break opportunities for the given paragraph
+// runes, which shouldn't contain paragraph separators. After the
+// call, breaks[i] will be true if a line can be wrapped before
+// runes[i]. The breaks slice must be at least as long as runes.
+func (self *lineBreaker) resolve(runes []rune, breaks []bool) {
+	n := len(runes)
+	self.classes = ensureSliceSize(self.classes, n)[:n]
+	self.attach = ensureSliceSize(self.attach, n)[:n]
+
+	// LB9, LB10: combining marks take the class of their base
+	for i, codePoint := range runes {
+		class := lineBreakClassOf(codePoint)
+		self.attach[i] = false
+		if class == lbCM || class == lbZWJ {
+			if i > 0 && lineBreakCanAttach(self.classes[i-1]) {
+				class = self.classes[i-1]
+				self.attach[i] = true
+			} else if class == lbCM {
+				class = lbAL
+			}
+		}
+		self.classes[i] = class
+	}
+
+	// LB2: never break at the start of text
+	var lastNonSpace int = -1 // index of the last non SP rune
+	for i := 0; i < n; i++ {
+		breaks[i] = (i > 0 && self.breakBefore(runes, i, lastNonSpace))
+		if self.classes[i] != lbSP {
+			lastNonSpace = i
+		}
+	}
+}
+
+// Returns whether a line can be wrapped between runes i - 1 and i.
+// lastNonSpace is the index of the last non SP rune before i, or -1.
+func (self *lineBreaker) breakBefore(runes []rune, i int, lastNonSpace int) bool {
+	a, b := self.classes[i-1], self.classes[i]
+	var c lineBreakClass = lbSP // class before the preceding spaces
+	if lastNonSpace != -1 {
+		c = self.classes[lastNonSpace]
+	}
+
+	switch {
+	case a == lbBK: // LB4
+		return true
+	case a == lbCR && b == lbLF: // LB5
+		return false
+	case a == lbCR || a == lbLF || a == lbNL: // LB5
+		return true
+	case b == lbBK || b == lbCR || b == lbLF || b == lbNL: // LB6
+		return false
+	case b == lbSP || b == lbZW: // LB7
+		return false
+	case c == lbZW: // LB8
+		return true
+	case runes[i-1] == '\u200D': // LB8a
+		return false
+	case self.attach[i]: // LB9
+		return false
+	case a == lbWJ || b == lbWJ: // LB11
+		return false
+	case a == lbGL: // LB12
+		return false
+	case b == lbGL && a != lbSP && a != lbBA && a != lbHY: // LB12a
+		return false
+	case b == lbCL || b == lbCP || b == lbEX || b == lbIS || b == lbSY: // LB13
+		return false
+	case c == lbOP: // LB14
+		return false
+	case c == lbQU && b == lbOP: // LB15
+		return false
+	case (c == lbCL || c == lbCP) && b == lbNS: // LB16
+		return false
+	case c == lbB2 && b == lbB2: // LB17
+		return false
+	case a == lbSP: // LB18
+		return true
+	case a == lbQU || b == lbQU: // LB19
+		return false
+	case b == lbBA || b == lbHY || b == lbNS || a == lbBB: // LB21
+		return false
+	case b == lbIN: // LB22
+		return false
+	case (a == lbAL && b == lbNU) || (a == lbNU && b == lbAL): // LB23
+		return false
+	case (a == lbPR && b == lbID) || (a == lbID && b == lbPO): // LB23a
+		return false
+	case (a == lbPR || a == lbPO) && b == lbAL: // LB24
+		return false
+	case a == lbAL && (b == lbPR || b == lbPO): // LB24
+		return false
+	case lineBreakNumericPair(a, b): // LB25
+		return false
+	case a == lbAL && b == lbAL: // LB28
+		return false
+	case a == lbIS && b == lbAL: // LB29
+		return false
+	case (a == lbAL || a == lbNU) && b == lbOP: // LB30
+		return false
+	case a == lbCP && (b == lbAL || b == lbNU): // LB30
+		return false
+	default: // LB31
+		return true
+	}
+}
+
+// LB25, simplified version.
+func lineBreakNumericPair(a, b lineBreakClass) bool {
+	switch b {
+	case lbPO, lbPR:
+		return a == lbCL || a == lbCP || a == lbNU
+	case lbOP:
+		return a == lbPO || a == lbPR
+	case lbNU:
+		return a == lbPO || a == lbPR || a == lbHY || a == lbIS || a == lbNU || a == lbSY
+	default:
+		return false
+	}
+}
+
+// LB9. Returns whether combining marks can attach to a base of the given class.
+func lineBreakCanAttach(base lineBreakClass) bool {
+	switch base {
+	case lbBK, lbCR, lbLF, lbNL, lbSP, lbZW:
+		return false
+	default:
+		return true
+	}
+}
+
+// Returns the line break class of the given code point, with LB1
+// already applied.
+func lineBreakClassOf(codePoint rune) lineBreakClass {
+	switch codePoint {
+	case ' ':
+		return lbSP
+	case '\n':
+		return lbLF
+	case '\r':
+		return lbCR
+	case '\v', '\f', '\u2028', '\u2029':
+		return lbBK
+	case '\u0085':
+		return lbNL
+	case '\t', '\u00AD', '\u058A', '\u1680', '\u2010', '\u2012', '\u2013', '\u205F', '\u3000', '|':
+		return lbBA
+	case '´', 'ˈ', 'ˌ', '˟':
+		return lbBB
+	case '\u2014':
+		return lbB2
+	case '-':
+		return lbHY
+	case '\u00A0', '\u2007', '\u2011', '\u202F', '\u034F', '\u180E':
+		return lbGL
+	case '\u200B':
+		return lbZW
+	case '\u2060', '\uFEFF':
+		return lbWJ
+	case '\u200D':
+		return lbZWJ
+	case '}', '、', '。', '，', '．', '｝', '」', '』', '】':
+		return lbCL
+	case ')', ']', '）', '］':
+		return lbCP
+	case '(', '[', '{', '¡', '¿', '‚', '„', '〈', '《', '「', '『', '【', '（', '［', '｛':
+		return lbOP
+	case '!', '?', '！', '？':
+		return lbEX
+	case ',', '.', ':', ';', '\u037E', '։', '،', '؍', '߸', '⁄', '︐', '︓', '︔':
+		return lbIS
+	case '/':
+		return lbSY
+	case '$', '+', '\\', '£', '¤', '¥', '±', '№', '−', '∓':
+		return lbPR
+	case '%', '¢', '°', '‰', '‱', '′', '″', '‴', '‵', '‶', '‷', '℃', '℉':
+		return lbPO
+	case '"', '\'', '«', '»', '‘', '’', '‛', '“', '”', '‟', '‹', '›':
+		return lbQU
+	case '․', '‥', '…':
+		return lbIN
+	case '‼', '‽', '⁇', '⁈', '⁉', '々', '\u301C', '〻', '〼', '゛', '゜', 'ゝ', 'ゞ', '\u30A0', '・', 'ー', 'ヽ', 'ヾ', '：', '；', '･':
+		return lbNS
+	}
+
+	switch {
+	case codePoint >= '₠' && codePoint <= '⃏': // currency symbols
+		return lbPR
+	case codePoint >= '\u2000' && codePoint <= '\u200A': // spaces other than figure space
+		return lbBA
+	case lineBreakIsSmallKana(codePoint): // CJ -> NS (LB1)
+		return lbNS
+	case codePoint >= '⺀' && codePoint <= '⿿',
+		codePoint >= '぀' && codePoint <= 'ヿ',
+		codePoint >= '㄰' && codePoint <= '㆏',
+		codePoint >= '㐀' && codePoint <= '䶿',
+		codePoint >= '一' && codePoint <= '鿿',
+		codePoint >= 'ꥠ' && codePoint <= '꥿',
+		codePoint >= '가' && codePoint <= '힯',
+		codePoint >= '豈' && codePoint <= '﫿',
+		codePoint >= '！' && codePoint <= '｠',
+		codePoint >= 0x1F000 && codePoint <= 0x1FAFF,
+		codePoint >= 0x20000 && codePoint <= 0x3FFFD:
+		return lbID
+	case unicode.IsDigit(codePoint):
+		return lbNU
+	case unicode.Is(unicode.M, codePoint):
+		return lbCM
+	case unicode.IsControl(codePoint):
+		return lbCM
+	default:
+		return lbAL
+	}
+}
+
+// Small hiragana and katakana (class CJ).
+func lineBreakIsSmallKana(codePoint rune) bool {
+	switch codePoint {
+	case 'ぁ', 'ぃ', 'ぅ', 'ぇ', 'ぉ', 'っ', 'ゃ', 'ゅ', 'ょ', 'ゎ', 'ゕ', 'ゖ',
+		'ァ', 'ィ', 'ゥ', 'ェ', 'ォ', 'ッ', 'ャ', 'ュ', 'ョ', 'ヮ', 'ヵ', 'ヶ':
+		return true
+	default:
+		return codePoint >= 'ㇰ' && codePoint <= 'ㇿ'
+	}
+}
+
+// Returns whether the given code point is a default ignorable that
+// should never be drawn by the layout process, even if the font has
+// a glyph for it. Soft hyphens are only made visible at line wraps.
+func lineBreakIsInvisible(codePoint rune) bool {
+	switch codePoint {
+	case '\u00AD', '\u200B', '\u2060', '\uFEFF':
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,171 @@
+package etxt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/hyphen"
+)
+
+func TestLineBreakOpportunities(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string // '|' marks break opportunities before each rune
+	}{
+		{"hello world", "      |    "},
+		{"hello  world", "       |    "},
+		{"well-known", "     |    "},
+		{"-5 and 3-4", "   |   |  "},
+		{"a/b.c", "  |  "},
+		{"a\u00A0b c", "    |"},
+		{"a\u200Bb", "  |"},
+		{"a\u00ADb", "  |"},
+		{"say \"hi\" (ok)!", "    |         "}, // LB15
+		{"say hi (ok)!", "    |  |    "},
+		{"end .", "     "},
+		{"漢字かな", " |||"},
+		{"é f", "   |"},
+		{"", ""},
+	}
+
+	var breaker lineBreaker
+	for _, test := range tests {
+		runes := []rune(test.text)
+		breaks := make([]bool, len(runes))
+		breaker.resolve(runes, breaks)
+		var result []rune
+		for _, isBreak := range breaks {
+			if isBreak {
+				result = append(result, '|')
+			} else {
+				result = append(result, ' ')
+			}
+		}
+		if string(result) != test.expected {
+			t.Fatalf("text %q: expected breaks %q, got %q", test.text, test.expected, string(result))
+		}
+	}
+}
+
+func TestLineBreakWrap(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Fract().SetHorzQuantization(QtNone)
+
+	lineTexts := func() []string {
+		var texts []string
+		for _, line := range renderer.layout.lines {
+			var text []rune
+			for i := line.runeStart; i < line.runeEnd; i++ {
+				if !renderer.layout.runes[i].skip {
+					text = append(text, renderer.layout.runes[i].codePoint)
+				}
+			}
+			texts = append(texts, string(text))
+		}
+		return texts
+	}
+
+	tests := []struct {
+		text     string
+		limit    string // text whose width is used as the width limit
+		expected []string
+	}{
+		{"hello world", "hello wo", []string{"hello", "world"}},
+		{"well-known", "well-kn", []string{"well-", "known"}},
+		{"hello\u00A0world", "hello wo", []string{"hello\u00A0wo", "rld"}},
+		{"http://x.com/abc", "http://x.c", []string{"http://", "x.com/abc"}},
+		{"super\u200Bcalifragilistic", "supercali", []string{"super", "califragi", "listic"}},
+		{"super\u00ADcalifragilistic", "supercali", []string{"super\u00AD", "califragi", "listic"}},
+	}
+
+	for _, test := range tests {
+		for _, dir := range []Direction{LeftToRight, RightToLeft} {
+			renderer.SetDirection(dir)
+			limit := renderer.Measure(test.limit).Width() + fract.One
+			renderer.layoutString(&renderer.layout, test.text, true, limit)
+			texts := lineTexts()
+			if len(texts) != len(test.expected) {
+				t.Fatalf("text %q: expected lines %q, got %q", test.text, test.expected, texts)
+			}
+			for i := range texts {
+				if texts[i] != test.expected[i] {
+					t.Fatalf("text %q: expected lines %q, got %q", test.text, test.expected, texts)
+				}
+			}
+		}
+	}
+
+	// soft hyphens are only visible at line wraps
+	renderer.SetDirection(LeftToRight)
+	limit := renderer.Measure("supercali").Width()
+	renderer.layoutString(&renderer.layout, "super\u00ADcalifragilistic", true, limit)
+	if renderer.layout.lines[0].width != renderer.Measure("super-").Width() {
+		t.Fatalf("expected soft hyphen to be visible at line wrap")
+	}
+	rect := renderer.MeasureWithWrap("super\u00ADman", 9999)
+	if rect.Width() != renderer.Measure("superman").Width() {
+		t.Fatalf("expected soft hyphen to be invisible")
+	}
+}
+
+func TestHyphenationWrap(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	patterns, err := hyphen.ParsePatterns(strings.NewReader("hy3ph he2n hena4 hen5at 1na n2at 1tio 2io o2n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Fract().SetHorzQuantization(QtNone)
+	limit := renderer.Measure("the hyphen-").Width() + fract.One
+
+	visualText := func(line layoutLine) string {
+		var text []rune
+		for _, index := range renderer.layout.order[line.orderStart:line.orderEnd] {
+			text = append(text, renderer.layout.runes[index].codePoint)
+		}
+		return string(text)
+	}
+
+	for _, dir := range []Direction{LeftToRight, RightToLeft, BidiLeftToRight} {
+		renderer.SetDirection(dir)
+		renderer.Layout().SetHyphenator(nil)
+		renderer.layoutString(&renderer.layout, "the hyphenation", true, limit)
+		if len(renderer.layout.lines) != 2 || renderer.layout.lines[0].change.InsertedHyphen {
+			t.Fatalf("dir %s: unexpected hyphenation", dir)
+		}
+
+		renderer.Layout().SetHyphenator(patterns)
+		renderer.layoutString(&renderer.layout, "the hyphenation", true, limit)
+		lines := renderer.layout.lines
+		if len(lines) != 2 {
+			t.Fatalf("dir %s: expected 2 lines, got %d", dir, len(lines))
+		}
+		if !lines[0].change.InsertedHyphen || lines[1].change.InsertedHyphen {
+			t.Fatalf("dir %s: expected hyphen on the first line only", dir)
+		}
+		expected := [2]string{"the hyphen-", "ation"}
+		if dir == RightToLeft {
+			expected = [2]string{"-nehpyh eht", "noita"}
+		}
+		if visualText(lines[0]) != expected[0] || visualText(lines[1]) != expected[1] {
+			t.Fatalf("dir %s: unexpected lines %q, %q", dir, visualText(lines[0]), visualText(lines[1]))
+		}
+		if lines[0].width > limit {
+			t.Fatalf("dir %s: line exceeds the width limit", dir)
+		}
+		if hyphen := renderer.layout.runes[lines[0].hyphenRune]; hyphen.byteIndex != len("the hyphe") {
+			t.Fatalf("dir %s: expected inserted hyphen at byte index %d, got %d", dir, len("the hyphe"), hyphen.byteIndex)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package mask
+
+import (
+	"image"
+	"math"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
+
+var _ SizeAwareRasterizer = (*BlurRasterizer)(nil)
+
+// Blur kernels for [BlurRasterizer].
+type BlurKind uint8
+
+const (
+	BlurGaussian BlurKind = iota // smooth falloff, good for shadows and glows
+	BlurBox                      // uniform falloff, faster to compute
+)
+
+// Returns the string representation of the [BlurKind]
+// (e.g., "BlurGaussian", "BlurBox").
+func (self BlurKind) String() string {
+	switch self {
+	case BlurGaussian:
+		return "BlurGaussian"
+	case BlurBox:
+		return "BlurBox"
+	default:
+		return "UnknownBlurKind"
+	}
+}
+
+// A rasterizer wrapper that blurs the masks generated by another
+// rasterizer, which can be used to draw soft shadows and glows. The
+// blur is applied as two separable passes (horizontal and vertical),
+// and the resulting masks are expanded to fit the blurred edges.
+//
+// Shadows and glows are typically drawn by rendering the text with
+// this rasterizer first, in the shadow color, and then rendering the
+// text again with a regular rasterizer on top.
+//
+// The zero value wraps a [DefaultRasterizer] and has a blur radius
+// of zero, which leaves masks untouched.
+type BlurRasterizer struct {
+	rasterizer  Rasterizer // nil if using defaultBase
+	defaultBase DefaultRasterizer
+	onChange    func(Rasterizer)
+
+	radius fract.Unit
+	kind   BlurKind
+
+	kernel       []float64 // cached kernel for kernelRadius and kernelKind
+	kernelRadius fract.Unit
+	kernelKind   BlurKind
+	buffer       []float64 // buffer for the separable passes
+	auxBuffer    []float64 // buffer for the separable passes
+}
+
+// Sets the rasterizer whose masks will be blurred. If nil, a
+// [DefaultRasterizer] will be used.
+//
+// Changes on the wrapped rasterizer configuration are forwarded,
+// so they will also be notified to the renderer.
+func (self *BlurRasterizer) SetRasterizer(rasterizer Rasterizer) {
+	if rasterizer == self.rasterizer {
+		return
+	}
+	self.getBase().SetOnChangeFunc(nil)
+	self.rasterizer = rasterizer
+	if self.onChange != nil {
+		self.getBase().SetOnChangeFunc(self.notifyBaseChange)
+	}
+	self.notifyChange()
+}
+
+// Returns the wrapped rasterizer. See [BlurRasterizer.SetRasterizer]().
+func (self *BlurRasterizer) GetRasterizer() Rasterizer {
+	return self.getBase()
+}
+
+// Sets the blur radius in pixels. Values outside the [0, 255] range
+// will be clamped, and the precision is quantized to 1/64ths of a
+// pixel. Masks are expanded by the radius, rounded up, on each side.
+//
+// For [BlurGaussian], the standard deviation is half the radius.
+// For [BlurBox], each pixel is averaged with its neighbours up to
+// the radius distance.
+func (self *BlurRasterizer) SetRadius(radius float64) {
+	if radius < 0 {
+		radius = 0
+	} else if radius > 255 {
+		radius = 255
+	}
+	fractRadius := fract.FromFloat64Down(radius)
+	if fractRadius == self.radius {
+		return
+	}
+	self.radius = fractRadius
+	self.notifyChange()
+}
+
+// Returns the blur radius in pixels.
+func (self *BlurRasterizer) GetRadius() float64 {
+	return self.radius.ToFloat64()
+}
+
+// Sets the blur kernel. The default is [BlurGaussian].
+func (self *BlurRasterizer) SetKind(kind BlurKind) {
+	if kind > BlurBox {
+		panic("invalid blur kind")
+	}
+	if kind == self.kind {
+		return
+	}
+	self.kind = kind
+	if self.radius != 0 {
+		self.notifyChange()
+	}
+}
+
+// Returns the blur kernel. See [BlurRasterizer.SetKind]().
+func (self *BlurRasterizer) GetKind() BlurKind {
+	return self.kind
+}
+
+// Satisfies the [SizeAwareRasterizer] interface. The size is only
+// forwarded to the wrapped rasterizer, the blur doesn't depend on it.
+func (self *BlurRasterizer) NotifySizeChange(size fract.Unit) {
+	if sizeAware, ok := self.getBase().(SizeAwareRasterizer); ok {
+		sizeAware.NotifySizeChange(size)
+	}
+}
+
+// Satisfies the [Rasterizer] interface. Changes on the wrapped
+// rasterizer are forwarded.
+func (self *BlurRasterizer) SetOnChangeFunc(onChange func(Rasterizer)) {
+	self.onChange = onChange
+	if onChange == nil {
+		self.getBase().SetOnChangeFunc(nil)
+	} else {
+		self.getBase().SetOnChangeFunc(self.notifyBaseChange)
+	}
+}
+
+// Satisfies the [Rasterizer] interface. The signature for the blur
+// rasterizer is the signature of the wrapped rasterizer when the
+// radius is zero. Otherwise, it's the signature of the wrapped
+// rasterizer XORed with the following bits:
+//   - 0xFF00000000000000 bits being 0xB1 for [BlurGaussian] or 0xB0
+//     for [BlurBox].
+//   - 0x0000FFFF00000000 bits encoding the radius in 64ths of a pixel.
+//
+// Since the wrapped rasterizer can use the same bits, signatures
+// are not guaranteed to be unique across all possible combinations,
+// but collisions require unusual configurations.
+func (self *BlurRasterizer) Signature() uint64 {
+	signature := self.getBase().Signature()
+	if self.radius == 0 {
+		return signature
+	}
+	blurBits := uint64(0xB100000000000000)
+	if self.kind == BlurBox {
+		blurBits = 0xB000000000000000
+	}
+	return signature ^ blurBits ^ (uint64(self.radius) << 32)
+}
+
+// Satisfies the [Rasterizer] interface.
+func (self *BlurRasterizer) Rasterize(outline sfnt.Segments, origin fract.Point) (*image.Alpha, error) {
+	mask, err := self.getBase().Rasterize(outline, origin)
+	if err != nil || mask == nil || self.radius == 0 {
+		return mask, err
+	}
+
+	kernel := self.getKernel()
+	expand := len(kernel) / 2
+	bounds := mask.Rect.Inset(-expand)
+	width, height := bounds.Dx(), bounds.Dy()
+	self.buffer = setBufferSize(self.buffer, width*height)
+	self.auxBuffer = setBufferSize(self.auxBuffer, width*height)
+	fastFillFloat64(self.buffer, 0)
+
+	// copy the original mask to the center of the buffer
+	maskWidth, maskHeight := mask.Rect.Dx(), mask.Rect.Dy()
+	for y := 0; y < maskHeight; y++ {
+		row := mask.Pix[y*mask.Stride : y*mask.Stride+maskWidth]
+		start := (y+expand)*width + expand
+		for x, alpha := range row {
+			self.buffer[start+x] = float64(alpha)
+		}
+	}
+
+	// separable passes
+	blurPass(self.buffer, self.auxBuffer, kernel, width, height, 1, width)
+	blurPass(self.auxBuffer, self.buffer, kernel, height, width, width, 1)
+
+	blurred := image.NewAlpha(bounds)
+	for i, value := range self.buffer {
+		if value >= 254.5 {
+			blurred.Pix[i] = 255
+		} else if value > 0 {
+			blurred.Pix[i] = uint8(value + 0.5)
+		}
+	}
+	return blurred, nil
+}
+
+func (self *BlurRasterizer) getBase() Rasterizer {
+	if self.rasterizer == nil {
+		return &self.defaultBase
+	}
+	return self.rasterizer
+}
+
+func (self *BlurRasterizer) notifyChange() {
+	if self.onChange != nil {
+		self.onChange(self)
+	}
+}
+
+func (self *BlurRasterizer) notifyBaseChange(Rasterizer) {
+	self.notifyChange()
+}
+
+// Returns the 1D kernel for the current radius and kind, with an
+// odd length and weights adding up to one.
+func (self *BlurRasterizer) getKernel() []float64 {
+	if self.kernel != nil && self.kernelRadius == self.radius && self.kernelKind == self.kind {
+		return self.kernel
+	}
+	self.kernelRadius, self.kernelKind = self.radius, self.kind
+
+	radius := self.radius.ToFloat64()
+	expand := self.radius.ToIntCeil()
+	self.kernel = setBufferSize(self.kernel, expand*2+1)
+	var total float64
+	for i := -expand; i <= expand; i++ {
+		var weight float64
+		switch self.kind {
+		case BlurGaussian:
+			sigma := radius / 2
+			weight = math.Exp(-float64(i*i) / (2 * sigma * sigma))
+		case BlurBox:
+			// outermost taps are weighted by the fractional part of the radius
+			weight = clampUnit64(radius + 1 - abs64(float64(i)))
+		default:
+			panic(self.kind)
+		}
+		self.kernel[i+expand] = weight
+		total += weight
+	}
+	for i := range self.kernel {
+		self.kernel[i] /= total
+	}
+	return self.kernel
+}
+
+// Convolves the source with the given kernel along one axis, writing
+// the results to the target. The lines are traversed with lineStride
+// and the values within each line with valueStride.
+func blurPass(source, target, kernel []float64, lineLen, numLines, valueStride, lineStride int) {
+	expand := len(kernel) / 2
+	for line := 0; line < numLines; line++ {
+		start := line * lineStride
+		for i := 0; i < lineLen; i++ {
+			var value float64
+			minK, maxK := expand-i, lineLen-1-i+expand
+			if minK < 0 {
+				minK = 0
+			}
+			if maxK >= len(kernel) {
+				maxK = len(kernel) - 1
+			}
+			for k := minK; k <= maxK; k++ {
+				value += source[start+(i+k-expand)*valueStride] * kernel[k]
+			}
+			target[start+i*valueStride] = value
+		}
+	}
+}
+
+func setBufferSize(buffer []float64, size int) []float64 {
+	if cap(buffer) >= size {
+		return buffer[:size]
+	}
+	return make([]float64, size)
+}
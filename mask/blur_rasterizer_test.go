@@ -0,0 +1,96 @@
+package mask
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestBlurRasterizer(t *testing.T) {
+	var segments []sfnt.Segment
+	segments = moveTo(segments, 10*64, 10*64)
+	segments = lineTo(segments, 30*64, 10*64)
+	segments = lineTo(segments, 30*64, 30*64)
+	segments = lineTo(segments, 10*64, 30*64)
+	segments = lineTo(segments, 10*64, 10*64)
+	outline := sfnt.Segments(segments)
+
+	var rast BlurRasterizer
+	for _, kind := range []BlurKind{BlurGaussian, BlurBox} {
+		rast.SetKind(kind)
+		rast.SetRadius(0)
+		original, err := Rasterize(outline, &rast, fract.Point{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rast.SetRadius(3.5)
+		mask, err := Rasterize(outline, &rast, fract.Point{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// bounds must be expanded by the radius, rounded up
+		if mask.Rect != original.Rect.Inset(-4) {
+			t.Fatalf("%s: expected bounds %v, got %v", kind, original.Rect.Inset(-4), mask.Rect)
+		}
+
+		// the center must stay opaque, the edges must be soft and the
+		// total coverage must be approximately preserved
+		if alpha := mask.AlphaAt(20, 20).A; alpha != 255 {
+			t.Fatalf("%s: expected opaque center, got alpha %d", kind, alpha)
+		}
+		prev := uint8(255)
+		for x := 12; x >= 6; x-- {
+			alpha := mask.AlphaAt(x, 20).A
+			if alpha > prev || alpha == 255 && x < 10 {
+				t.Fatalf("%s: unexpected falloff at x = %d (alpha %d)", kind, x, alpha)
+			}
+			prev = alpha
+		}
+		if alpha := mask.AlphaAt(8, 20).A; alpha == 0 {
+			t.Fatalf("%s: expected blurred edge at (8, 20)", kind)
+		}
+		if diff := maskCoverage(mask) - maskCoverage(original); diff < -400 || diff > 400 {
+			t.Fatalf("%s: coverage changed by %d", kind, diff)
+		}
+	}
+}
+
+func TestBlurRasterizerSignature(t *testing.T) {
+	var rast BlurRasterizer
+	var stroke StrokeRasterizer
+	var changes int
+	rast.SetOnChangeFunc(func(Rasterizer) { changes += 1 })
+
+	if rast.Signature() != (&DefaultRasterizer{}).Signature() {
+		t.Fatalf("expected zero radius to keep the wrapped signature")
+	}
+	rast.SetRadius(2)
+	gaussian := rast.Signature()
+	rast.SetKind(BlurBox)
+	if rast.Signature() == gaussian {
+		t.Fatalf("expected different signatures for different kinds")
+	}
+	rast.SetRadius(3)
+	if rast.Signature() == gaussian {
+		t.Fatalf("expected different signatures for different radii")
+	}
+	rast.SetRasterizer(&stroke)
+	stroke.SetWidth(2) // must be forwarded
+	if changes != 5 {
+		t.Fatalf("expected 5 changes, got %d", changes)
+	}
+	if rast.Signature() == stroke.Signature() {
+		t.Fatalf("expected blur to modify the wrapped signature")
+	}
+}
+
+func maskCoverage(mask *image.Alpha) int {
+	var total int
+	for _, alpha := range mask.Pix {
+		total += int(alpha)
+	}
+	return total
+}
@@ -12,6 +12,27 @@ import (
 
 var _ Rasterizer = (*FauxRasterizer)(nil)
 
+// Faux-bold modes for [FauxRasterizer]. See [FauxRasterizer.SetBoldMode]().
+type FauxBoldMode uint8
+
+const (
+	FauxBoldSmear  FauxBoldMode = iota // widens the mask rows (default)
+	FauxBoldExpand                     // expands the glyph outline
+)
+
+// Returns the string representation of the [FauxBoldMode]
+// (e.g., "FauxBoldSmear", "FauxBoldExpand").
+func (self FauxBoldMode) String() string {
+	switch self {
+	case FauxBoldSmear:
+		return "FauxBoldSmear"
+	case FauxBoldExpand:
+		return "FauxBoldExpand"
+	default:
+		return "UnknownFauxBoldMode"
+	}
+}
+
 // TODO: there's an index out of bounds error when we set
 //       high values for SetExtraWidth, take a look at that
 
@@ -46,6 +67,8 @@ type FauxRasterizer struct {
 	xwidth        fract.Unit
 	xwidthTailMod uint16
 	xwidthTail    []uint8 // internal implementation detail
+	boldMode      FauxBoldMode
+	expander      outlineExpander // for FauxBoldExpand
 }
 
 // Sets the oblique skewing factor, which is expected to be in [-1, 1].
@@ -108,9 +131,11 @@ func (self *FauxRasterizer) GetSkewFactor() float32 {
 // account for this widening, you can use a [sizer.PaddedAdvanceSizer],
 // link the rasterizer to it through [FauxRasterizer.SetAuxOnChangeFunc]()
 // and update the padding with the value of [FauxRasterizer.GetExtraWidth](),
-// for example.
+// for example. With [FauxBoldExpand], the [sizer.FauxBoldSizer] can
+// be used instead.
 //
 // [sizer.PaddedAdvanceSizer]: https://pkg.go.dev/github.com/tinne26/etxt/sizer@v0.0.10#PaddedAdvanceSizer
+// [sizer.FauxBoldSizer]: https://pkg.go.dev/github.com/tinne26/etxt/sizer@v0.0.10#FauxBoldSizer
 func (self *FauxRasterizer) SetExtraWidth(extraWidth float32) {
 	// normalize and store new skewing factor
 	if extraWidth <= 0 {
@@ -152,13 +177,50 @@ func (self *FauxRasterizer) SetExtraWidth(extraWidth float32) {
 
 		// update cache signature
 		self.signature = self.signature & 0xFFFFF0FF_FFFF0000
-		self.signature |= 0x00000B00_00000000 // flag for "active bold"
+		self.signature |= self.boldModeFlag()
 		self.signature |= uint64(self.xwidth)
 	}
 
 	self.notifyChange()
 }
 
+// Sets the faux-bold mode. The default mode is [FauxBoldSmear], which
+// widens the rasterized mask horizontally. [FauxBoldExpand] instead
+// offsets the glyph contours outwards before rasterization, making
+// glyphs thicker in both axes: they become wider by the extra width
+// towards the right and taller by the extra width towards the top,
+// so the glyph origin and the baseline are preserved. This is slower,
+// but the results are closer to a real bold.
+//
+// The strength of the effect is still set through [FauxRasterizer.SetExtraWidth]().
+func (self *FauxRasterizer) SetBoldMode(mode FauxBoldMode) {
+	if mode > FauxBoldExpand {
+		panic("invalid faux bold mode")
+	}
+	if mode == self.boldMode {
+		return
+	}
+	self.boldMode = mode
+	if self.xwidth == 0 {
+		return // no need to notify, the result doesn't change
+	}
+	self.signature = self.signature & 0xFFFFF0FF_FFFFFFFF
+	self.signature |= self.boldModeFlag()
+	self.notifyChange()
+}
+
+// Returns the faux-bold mode. See [FauxRasterizer.SetBoldMode]().
+func (self *FauxRasterizer) GetBoldMode() FauxBoldMode {
+	return self.boldMode
+}
+
+func (self *FauxRasterizer) boldModeFlag() uint64 {
+	if self.boldMode == FauxBoldExpand {
+		return 0x00000E00_00000000 // flag for "active expanded bold"
+	}
+	return 0x00000B00_00000000 // flag for "active bold"
+}
+
 // round the given uint16 to the next power of two (stays
 // as it is if the value is already a power of two)
 func uint16RoundToNextPow2(value uint16) uint16 {
@@ -182,7 +244,8 @@ func (self *FauxRasterizer) GetExtraWidth() float32 {
 //   - 0xFF00000000000000 unused bits customizable through type embedding.
 //   - 0x00FF000000000000 bits being 0xFA (self signature byte).
 //   - 0x0000F00000000000 bits being 0x1 if italics are enabled.
-//   - 0x00000F0000000000 bits being 0xB if bold is enabled.
+//   - 0x00000F0000000000 bits being 0xB if bold is enabled, or 0xE
+//     if bold is enabled with [FauxBoldExpand].
 //   - 0x000000FF00000000 bits being zero, currently undefined.
 //   - 0x00000000FFFF0000 bits encoding the skew [-1, 1] in the [0, 65535]
 //     range, with the "zero skew" not having a representation (signatures
@@ -203,13 +266,21 @@ func (self *FauxRasterizer) pointToFloat32Coords(point fract.Point) (float32, fl
 
 // Satisfies the [Rasterizer] interface.
 func (self *FauxRasterizer) Rasterize(outline sfnt.Segments, origin fract.Point) (*image.Alpha, error) {
+	expand := (self.xwidth > 0 && self.boldMode == FauxBoldExpand)
+	if expand { // faux bold pre-processing
+		// edges are offset by half the extra width, and the result is
+		// shifted so the glyph grows only towards the right and the top
+		half := self.xwidth.ToFloat64() / 2
+		outline = self.expander.Expand(outline, half, half, -half)
+	}
+
 	rectOffset := self.prepareForOutline(outline, origin)
 	mask := image.NewAlpha(self.rasterizer.Bounds())
 	processOutline(self, outline)
 	self.rasterizer.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
 	mask.Rect = mask.Rect.Add(rectOffset)
 
-	if self.xwidth > 0 { // faux bold post-processing
+	if self.xwidth > 0 && !expand { // faux bold post-processing
 		self.applyExtraWidth(mask.Pix, mask.Stride)
 	}
 	return mask, nil
@@ -257,7 +328,8 @@ func (self *FauxRasterizer) prepareForOutline(outline sfnt.Segments, origin frac
 	}
 
 	// adjust the bounds accounting for faux-bold extra width
-	if self.xwidth > 0 {
+	// (expanded outlines already have the right bounds)
+	if self.xwidth > 0 && self.boldMode != FauxBoldExpand {
 		bounds.Max.X += self.xwidth.Ceil()
 	}
 
@@ -276,10 +348,9 @@ func (self *FauxRasterizer) prepareForOutline(outline sfnt.Segments, origin frac
 //
 // ...
 //
-// Better faux-bold would have to be done through shape expansion anyway,
-// working directly with the outline points, but that's tricky to do (e.g:
-// github.com/libass/libass/blob/7bf4bee0fc9a1d6257a105a3c19df6cf08733f8e/
-// libass/ass_outline.c#L499)... but even freetype's faux-bold is not perfect.
+// Better faux-bold has to be done through shape expansion anyway, working
+// directly with the outline points (see FauxBoldExpand), but this is still
+// the default mode for backwards compatibility.
 
 func (self *FauxRasterizer) applyExtraWidth(pixels []uint8, stride int) {
 	// extra width is applied independently to each row
@@ -1,6 +1,12 @@
 package mask
 
-import "testing"
+import (
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
 
 func TestFauxBoldWhole(t *testing.T) {
 	tests := []struct {
@@ -70,6 +76,54 @@ func TestFauxBoldFract(t *testing.T) {
 	}
 }
 
+func TestFauxBoldExpand(t *testing.T) {
+	rast := FauxRasterizer{}
+	var changes int
+	rast.SetOnChangeFunc(func(Rasterizer) { changes += 1 })
+	rast.SetBoldMode(FauxBoldExpand)
+	if changes != 0 {
+		t.Fatalf("unexpected change notification without extra width")
+	}
+	rast.SetExtraWidth(4)
+	expandSignature := rast.Signature()
+	rast.SetBoldMode(FauxBoldSmear)
+	if changes != 2 || rast.Signature() == expandSignature {
+		t.Fatalf("expected bold mode to change the signature")
+	}
+	rast.SetBoldMode(FauxBoldExpand)
+
+	// square from (10, 10) to (30, 30), in both orientations. the
+	// result must grow 4 pixels towards the right and the top
+	var clockwise, counterClockwise []sfnt.Segment
+	clockwise = moveTo(clockwise, 10*64, 10*64)
+	clockwise = lineTo(clockwise, 30*64, 10*64)
+	clockwise = lineTo(clockwise, 30*64, 30*64)
+	clockwise = lineTo(clockwise, 10*64, 30*64)
+	clockwise = lineTo(clockwise, 10*64, 10*64)
+	counterClockwise = moveTo(counterClockwise, 10*64, 10*64)
+	counterClockwise = lineTo(counterClockwise, 10*64, 30*64)
+	counterClockwise = lineTo(counterClockwise, 30*64, 30*64)
+	counterClockwise = lineTo(counterClockwise, 30*64, 10*64)
+	counterClockwise = lineTo(counterClockwise, 10*64, 10*64)
+	for _, segments := range [][]sfnt.Segment{clockwise, counterClockwise} {
+		mask, err := Rasterize(sfnt.Segments(segments), &rast, fract.Point{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mask.Rect != image.Rect(10, 6, 34, 30) {
+			t.Fatalf("expected expanded bounds (10, 6)-(34, 30), got %v", mask.Rect)
+		}
+		for _, point := range []image.Point{{10, 6}, {33, 6}, {10, 29}, {33, 29}, {20, 20}} {
+			if alpha := mask.AlphaAt(point.X, point.Y).A; alpha != 255 {
+				t.Fatalf("expected alpha 255 at (%d, %d), got %d", point.X, point.Y, alpha)
+			}
+		}
+	}
+	if segments := sfnt.Segments(clockwise); segments.Bounds().Max.X != 30*64 {
+		t.Fatalf("original outline was modified")
+	}
+}
+
 func eqSliceUint8(a, b []uint8) bool {
 	if len(a) != len(b) {
 		return false
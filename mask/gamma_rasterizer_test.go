@@ -0,0 +1,116 @@
+package mask
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
+
+// square from (10.5, 10.5) to (20.5, 20.5), with partially covered edges
+func gammaTestSquare() sfnt.Segments {
+	var segments []sfnt.Segment
+	segments = moveTo(segments, 10*64+32, 10*64+32)
+	segments = lineTo(segments, 20*64+32, 10*64+32)
+	segments = lineTo(segments, 20*64+32, 20*64+32)
+	segments = lineTo(segments, 10*64+32, 20*64+32)
+	segments = lineTo(segments, 10*64+32, 10*64+32)
+	return sfnt.Segments(segments)
+}
+
+func TestGammaRasterizer(t *testing.T) {
+	var rast GammaRasterizer
+	var base DefaultRasterizer
+	reference, _ := Rasterize(gammaTestSquare(), &base, fract.Point{})
+	mask, err := Rasterize(gammaTestSquare(), &rast, fract.Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range mask.Pix {
+		if mask.Pix[i] != reference.Pix[i] {
+			t.Fatalf("zero value must not modify masks")
+		}
+	}
+
+	edgeAlpha := reference.AlphaAt(10, 15).A
+	if edgeAlpha == 0 || edgeAlpha == 255 {
+		t.Fatalf("expected partial coverage on the edge, got %d", edgeAlpha)
+	}
+
+	// gamma and contrast increase partial coverage only
+	for _, config := range []struct{ gamma, contrast float64 }{{1.8, 0}, {1, 0.5}} {
+		rast.SetGamma(config.gamma)
+		rast.SetContrast(config.contrast)
+		mask, _ = Rasterize(gammaTestSquare(), &rast, fract.Point{})
+		if alpha := mask.AlphaAt(10, 15).A; alpha <= edgeAlpha {
+			t.Fatalf("%+v: expected edge alpha above %d, got %d", config, edgeAlpha, alpha)
+		}
+		if mask.AlphaAt(15, 15).A != 255 || mask.AlphaAt(5, 15).A != 0 {
+			t.Fatalf("%+v: opaque and transparent pixels must remain unchanged", config)
+		}
+	}
+	rast.SetGamma(0.5)
+	rast.SetContrast(0)
+	mask, _ = Rasterize(gammaTestSquare(), &rast, fract.Point{})
+	if alpha := mask.AlphaAt(10, 15).A; alpha >= edgeAlpha {
+		t.Fatalf("expected edge alpha below %d, got %d", edgeAlpha, alpha)
+	}
+
+	// stem darkening depends on the size
+	rast.SetGamma(1)
+	rast.SetStemDarkening(1)
+	rast.SetStemDarkeningRange(10, 20)
+	for _, test := range []struct {
+		size  float64
+		alpha uint8
+	}{{8, 255}, {15, 192}, {20, edgeAlpha}, {30, edgeAlpha}} {
+		rast.NotifySizeChange(fract.FromFloat64(test.size))
+		mask, _ = Rasterize(gammaTestSquare(), &rast, fract.Point{})
+		if alpha := mask.AlphaAt(10, 15).A; alpha != test.alpha {
+			t.Fatalf("size %v: expected edge alpha %d, got %d", test.size, test.alpha, alpha)
+		}
+	}
+}
+
+func TestGammaRasterizerSignature(t *testing.T) {
+	var rast GammaRasterizer
+	var changes int
+	rast.SetOnChangeFunc(func(Rasterizer) { changes += 1 })
+
+	if rast.Signature() != (&DefaultRasterizer{}).Signature() {
+		t.Fatalf("expected neutral configuration to keep the base signature")
+	}
+	seen := map[uint64]bool{rast.Signature(): true}
+	check := func(name string) {
+		signature := rast.Signature()
+		if seen[signature] {
+			t.Fatalf("%s: repeated signature %016X", name, signature)
+		}
+		seen[signature] = true
+	}
+	rast.SetGamma(2.2)
+	check("gamma")
+	rast.SetContrast(0.25)
+	check("contrast")
+	rast.SetStemDarkening(0.5)
+	check("darkening")
+	rast.SetStemDarkeningRange(8, 32)
+	check("darkening range")
+	rast.SetRasterizer(&StrokeRasterizer{})
+	check("rasterizer")
+	rast.GetRasterizer().(*StrokeRasterizer).SetWidth(2)
+	check("base change")
+	if changes != 6 {
+		t.Fatalf("expected 6 changes, got %d", changes)
+	}
+
+	// redundant changes and size notifications must not be notified
+	rast.SetGamma(2.2)
+	rast.SetContrast(0.25)
+	rast.SetStemDarkening(0.5)
+	rast.SetStemDarkeningRange(8, 32)
+	rast.NotifySizeChange(fract.FromInt(12))
+	if changes != 6 {
+		t.Fatalf("expected 6 changes, got %d", changes)
+	}
+}
@@ -0,0 +1,272 @@
+package mask
+
+import (
+	"image"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+var _ SizeAwareRasterizer = (*LCDRasterizer)(nil)
+
+// Subpixel orders for [LCDRasterizer].
+type LCDOrder uint8
+
+const (
+	LCDOrderRGB LCDOrder = iota // most common order for LCD screens
+	LCDOrderBGR
+)
+
+// Returns the string representation of the [LCDOrder]
+// (e.g., "LCDOrderRGB", "LCDOrderBGR").
+func (self LCDOrder) String() string {
+	switch self {
+	case LCDOrderRGB:
+		return "LCDOrderRGB"
+	case LCDOrderBGR:
+		return "LCDOrderBGR"
+	default:
+		return "UnknownLCDOrder"
+	}
+}
+
+// Color fringe reduction filters for [LCDRasterizer].
+type LCDFilter uint8
+
+const (
+	LCDFilterDefault LCDFilter = iota // like FreeType's default LCD filter
+	LCDFilterLight                    // sharper, but with more color fringes
+	LCDFilterNone                     // raw subpixel coverage, strong color fringes
+)
+
+// Returns the string representation of the [LCDFilter]
+// (e.g., "LCDFilterDefault", "LCDFilterLight").
+func (self LCDFilter) String() string {
+	switch self {
+	case LCDFilterDefault:
+		return "LCDFilterDefault"
+	case LCDFilterLight:
+		return "LCDFilterLight"
+	case LCDFilterNone:
+		return "LCDFilterNone"
+	default:
+		return "UnknownLCDFilter"
+	}
+}
+
+// A rasterizer wrapper for subpixel antialiasing on LCD screens. Glyph
+// outlines are rasterized at three times the horizontal resolution with
+// the wrapped rasterizer, and a filter is applied to reduce color fringes.
+//
+// The resulting masks contain three coverage values per pixel, one for
+// each subpixel, always in red, green, blue order (the subpixel order
+// only changes how the coverage is assigned). This means that the mask
+// bounds are expressed in subpixels horizontally: Rect.Min.X and
+// Rect.Max.X are multiples of 3, and the pixel bounds can be obtained
+// by dividing them by 3.
+//
+// When an etxt renderer uses this rasterizer, the masks are blended per
+// channel, and with Ebitengine they are converted to RGB images. The
+// rasterizer must be set directly on the renderer, not wrapped, for this
+// to work. Subpixel antialiasing only makes sense for opaque text drawn
+// directly to the screen, without scaling or rotations, and it's usually
+// preferred for desktop applications rather than games.
+//
+// The zero value wraps a [DefaultRasterizer] and uses [LCDOrderRGB]
+// with [LCDFilterDefault].
+type LCDRasterizer struct {
+	rasterizer  Rasterizer // nil if using defaultBase
+	defaultBase DefaultRasterizer
+	onChange    func(Rasterizer)
+
+	order  LCDOrder
+	filter LCDFilter
+
+	segments []sfnt.Segment // buffer for the scaled outline
+}
+
+// Sets the rasterizer used for the horizontally scaled outlines. If nil,
+// a [DefaultRasterizer] will be used. Wrappers like [GammaRasterizer] can
+// be used to adjust the subpixel coverage.
+//
+// Changes on the wrapped rasterizer configuration are forwarded,
+// so they will also be notified to the renderer.
+func (self *LCDRasterizer) SetRasterizer(rasterizer Rasterizer) {
+	if rasterizer == self.rasterizer {
+		return
+	}
+	self.getBase().SetOnChangeFunc(nil)
+	self.rasterizer = rasterizer
+	if self.onChange != nil {
+		self.getBase().SetOnChangeFunc(self.notifyBaseChange)
+	}
+	self.notifyChange()
+}
+
+// Returns the wrapped rasterizer. See [LCDRasterizer.SetRasterizer]().
+func (self *LCDRasterizer) GetRasterizer() Rasterizer {
+	return self.getBase()
+}
+
+// Sets the subpixel order of the screen. The default is [LCDOrderRGB].
+func (self *LCDRasterizer) SetOrder(order LCDOrder) {
+	if order > LCDOrderBGR {
+		panic("invalid LCD order")
+	}
+	if order == self.order {
+		return
+	}
+	self.order = order
+	self.notifyChange()
+}
+
+// Returns the subpixel order. See [LCDRasterizer.SetOrder]().
+func (self *LCDRasterizer) GetOrder() LCDOrder {
+	return self.order
+}
+
+// Sets the filter used to reduce color fringes. The default
+// is [LCDFilterDefault].
+func (self *LCDRasterizer) SetFilter(filter LCDFilter) {
+	if filter > LCDFilterNone {
+		panic("invalid LCD filter")
+	}
+	if filter == self.filter {
+		return
+	}
+	self.filter = filter
+	self.notifyChange()
+}
+
+// Returns the filter. See [LCDRasterizer.SetFilter]().
+func (self *LCDRasterizer) GetFilter() LCDFilter {
+	return self.filter
+}
+
+// Satisfies the [SizeAwareRasterizer] interface. The size is only
+// forwarded to the wrapped rasterizer.
+func (self *LCDRasterizer) NotifySizeChange(size fract.Unit) {
+	if sizeAware, ok := self.getBase().(SizeAwareRasterizer); ok {
+		sizeAware.NotifySizeChange(size)
+	}
+}
+
+// Satisfies the [Rasterizer] interface. Changes on the wrapped
+// rasterizer are forwarded.
+func (self *LCDRasterizer) SetOnChangeFunc(onChange func(Rasterizer)) {
+	self.onChange = onChange
+	if onChange == nil {
+		self.getBase().SetOnChangeFunc(nil)
+	} else {
+		self.getBase().SetOnChangeFunc(self.notifyBaseChange)
+	}
+}
+
+// Satisfies the [Rasterizer] interface. The signature for the LCD
+// rasterizer is the signature of the wrapped rasterizer XORed with
+// the following bits:
+//   - 0xFF00000000000000 bits being 0x1C.
+//   - 0x0000000F00000000 bits encoding the subpixel order.
+//   - 0x000000F000000000 bits encoding the filter.
+//
+// As with other wrappers, collisions are possible but require
+// unusual configurations.
+func (self *LCDRasterizer) Signature() uint64 {
+	bits := uint64(0x1C00000000000000) | uint64(self.order)<<32 | uint64(self.filter)<<36
+	return self.getBase().Signature() ^ bits
+}
+
+// Satisfies the [Rasterizer] interface.
+func (self *LCDRasterizer) Rasterize(outline sfnt.Segments, origin fract.Point) (*image.Alpha, error) {
+	// scale the outline horizontally, including the fractional
+	// x position, which can't be represented after scaling
+	shiftX := fixed.Int26_6(origin.X.FractShift() * 3)
+	self.segments = append(self.segments[:0], outline...)
+	for i := range self.segments {
+		args := &self.segments[i].Args
+		for j := 0; j < segmentOpNumArgs(self.segments[i].Op); j++ {
+			args[j].X = args[j].X*3 + shiftX
+		}
+	}
+	subMask, err := self.getBase().Rasterize(sfnt.Segments(self.segments), fract.Point{Y: origin.Y})
+	if err != nil || subMask == nil {
+		return subMask, err
+	}
+
+	// the filter spreads coverage up to two subpixels on each side,
+	// and the result is expanded to whole pixels
+	minX := floorDiv3(subMask.Rect.Min.X-2) * 3
+	maxX := ceilDiv3(subMask.Rect.Max.X+2) * 3
+	rect := image.Rect(minX, subMask.Rect.Min.Y, maxX, subMask.Rect.Max.Y)
+	mask := image.NewAlpha(rect)
+
+	weights := self.filterWeights()
+	subMinX, subMaxX := subMask.Rect.Min.X, subMask.Rect.Max.X
+	for y := 0; y < rect.Dy(); y++ {
+		srcRow := subMask.Pix[y*subMask.Stride : y*subMask.Stride+subMask.Rect.Dx()]
+		dstRow := mask.Pix[y*mask.Stride : y*mask.Stride+rect.Dx()]
+		for x := range dstRow {
+			var value uint32
+			subX := minX + x - 2
+			for k, weight := range weights {
+				if weight != 0 && subX+k >= subMinX && subX+k < subMaxX {
+					value += uint32(srcRow[subX+k-subMinX]) * weight
+				}
+			}
+			value = (value + 128) >> 8
+			if value > 255 {
+				value = 255
+			}
+			dstRow[x] = uint8(value)
+		}
+		if self.order == LCDOrderBGR {
+			for x := 0; x < len(dstRow); x += 3 {
+				dstRow[x], dstRow[x+2] = dstRow[x+2], dstRow[x]
+			}
+		}
+	}
+	return mask, nil
+}
+
+func (self *LCDRasterizer) getBase() Rasterizer {
+	if self.rasterizer == nil {
+		return &self.defaultBase
+	}
+	return self.rasterizer
+}
+
+func (self *LCDRasterizer) notifyChange() {
+	if self.onChange != nil {
+		self.onChange(self)
+	}
+}
+
+func (self *LCDRasterizer) notifyBaseChange(Rasterizer) {
+	self.notifyChange()
+}
+
+// Returns the 5-tap filter weights, adding up to 256.
+func (self *LCDRasterizer) filterWeights() [5]uint32 {
+	switch self.filter {
+	case LCDFilterDefault:
+		return [5]uint32{0x08, 0x4D, 0x56, 0x4D, 0x08}
+	case LCDFilterLight:
+		return [5]uint32{0x00, 0x55, 0x56, 0x55, 0x00}
+	case LCDFilterNone:
+		return [5]uint32{0x00, 0x00, 0x100, 0x00, 0x00}
+	default:
+		panic(self.filter)
+	}
+}
+
+func floorDiv3(n int) int {
+	if n < 0 {
+		return -((-n + 2) / 3)
+	}
+	return n / 3
+}
+
+func ceilDiv3(n int) int {
+	return -floorDiv3(-n)
+}
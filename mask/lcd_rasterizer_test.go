@@ -0,0 +1,89 @@
+package mask
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
+
+// vertical bar from (10, 10) to (10.5, 20)
+func lcdTestBar() sfnt.Segments {
+	var segments []sfnt.Segment
+	segments = moveTo(segments, 10*64, 10*64)
+	segments = lineTo(segments, 10*64+32, 10*64)
+	segments = lineTo(segments, 10*64+32, 20*64)
+	segments = lineTo(segments, 10*64, 20*64)
+	segments = lineTo(segments, 10*64, 10*64)
+	return sfnt.Segments(segments)
+}
+
+func TestLCDRasterizer(t *testing.T) {
+	var rast LCDRasterizer
+	rast.SetFilter(LCDFilterNone)
+	mask, err := Rasterize(lcdTestBar(), &rast, fract.Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mask.Rect.Min.X%3 != 0 || mask.Rect.Max.X%3 != 0 {
+		t.Fatalf("expected subpixel bounds multiple of 3, got %v", mask.Rect)
+	}
+
+	// returns the subpixel coverage for the given pixel
+	rgbAt := func(x, y int) [3]uint8 {
+		return [3]uint8{mask.AlphaAt(x*3, y).A, mask.AlphaAt(x*3+1, y).A, mask.AlphaAt(x*3+2, y).A}
+	}
+	if rgb := rgbAt(10, 15); rgb[0] != 255 || rgb[1] < 120 || rgb[1] > 135 || rgb[2] != 0 {
+		t.Fatalf("unexpected subpixel coverage %v", rgb)
+	}
+
+	// fractional positions are applied at subpixel precision
+	mask, _ = Rasterize(lcdTestBar(), &rast, fract.Point{X: 32})
+	if rgb := rgbAt(10, 15); rgb[0] != 0 || rgb[1] < 120 || rgb[1] > 135 || rgb[2] != 255 {
+		t.Fatalf("unexpected subpixel coverage %v with fractional position", rgb)
+	}
+
+	// subpixel order
+	rast.SetOrder(LCDOrderBGR)
+	mask, _ = Rasterize(lcdTestBar(), &rast, fract.Point{})
+	if rgb := rgbAt(10, 15); rgb[0] != 0 || rgb[2] != 255 {
+		t.Fatalf("unexpected BGR subpixel coverage %v", rgb)
+	}
+
+	// filters spread the coverage to the neighbouring subpixels
+	rast.SetOrder(LCDOrderRGB)
+	rast.SetFilter(LCDFilterDefault)
+	mask, _ = Rasterize(lcdTestBar(), &rast, fract.Point{})
+	if rgb := rgbAt(9, 15); rgb[0] != 0 || rgb[1] == 0 || rgb[2] == 0 {
+		t.Fatalf("unexpected filtered coverage %v on the left", rgb)
+	}
+	if rgb := rgbAt(10, 15); rgb[2] == 0 || rgb[0] == 255 {
+		t.Fatalf("unexpected filtered coverage %v", rgb)
+	}
+}
+
+func TestLCDRasterizerSignature(t *testing.T) {
+	var rast LCDRasterizer
+	var changes int
+	rast.SetOnChangeFunc(func(Rasterizer) { changes += 1 })
+
+	seen := map[uint64]bool{(&DefaultRasterizer{}).Signature(): true}
+	check := func(name string) {
+		signature := rast.Signature()
+		if seen[signature] {
+			t.Fatalf("%s: repeated signature %016X", name, signature)
+		}
+		seen[signature] = true
+	}
+	check("zero")
+	rast.SetOrder(LCDOrderBGR)
+	check("order")
+	rast.SetFilter(LCDFilterLight)
+	check("filter")
+	rast.SetRasterizer(&GammaRasterizer{})
+	rast.GetRasterizer().(*GammaRasterizer).SetGamma(1.5)
+	check("base change")
+	if changes != 4 {
+		t.Fatalf("expected 4 changes, got %d", changes)
+	}
+}
@@ -0,0 +1,134 @@
+package mask
+
+import (
+	"math"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Similar to FreeType's FT_Outline_EmboldenXY: each point of the outline,
+// including control points, is shifted outwards along the bisector of the
+// normals of its adjacent edges, so the edges end up offset by the given
+// amount. The result can then be shifted to keep the glyph in place.
+//
+// Used by [FauxRasterizer] for FauxBoldExpand and by [GammaRasterizer]
+// for stem darkening.
+type outlineExpander struct {
+	segments []sfnt.Segment     // buffer for the expanded outline
+	refs     []*fixed.Point26_6 // buffer for contour point references
+	origs    []float64          // buffer for original contour coordinates
+}
+
+// Returns a copy of the outline with the contours expanded by the given
+// offset, in pixels, and then translated by (shiftX, shiftY). The returned
+// segments are only valid until the next call.
+func (self *outlineExpander) Expand(outline sfnt.Segments, offset, shiftX, shiftY float64) sfnt.Segments {
+	self.segments = append(self.segments[:0], outline...)
+
+	// outer contours can be clockwise or counter-clockwise depending
+	// on the font format, so we need to figure out the orientation
+	var area float64
+	self.forEachContour(func(points []*fixed.Point26_6) {
+		n := len(points)
+		for i := 0; i < n; i++ {
+			a, b := points[i], points[(i+1)%n]
+			area += float64(a.X)*float64(b.Y) - float64(b.X)*float64(a.Y)
+		}
+	})
+	if area < 0 {
+		offset = -offset // flip normals
+	}
+
+	self.forEachContour(func(points []*fixed.Point26_6) {
+		// store original coordinates, as points are modified in place
+		n := len(points)
+		origs := self.origs[:0]
+		for _, point := range points {
+			origs = append(origs, float64(point.X)/64, float64(point.Y)/64)
+		}
+		self.origs = origs
+
+		for i := 0; i < n; i++ {
+			px, py := origs[2*i], origs[2*i+1]
+			inX, inY := expandFindDir(origs, i, -1)
+			outX, outY := expandFindDir(origs, i, +1)
+
+			var offsetX, offsetY float64
+			if inX != 0 || inY != 0 { // (otherwise degenerate contour)
+				// outward normals are (dy, -dx) for positive area
+				nx, ny := inY+outY, -(inX + outX)
+				dot := inX*outX + inY*outY
+				scale := offset / math.Max(1+dot, 0.5) // limit spikes on sharp corners
+				offsetX, offsetY = nx*scale, ny*scale
+			}
+			x := px + offsetX + shiftX
+			y := py + offsetY + shiftY
+			points[i].X = fixed.Int26_6(math.Round(x * 64))
+			points[i].Y = fixed.Int26_6(math.Round(y * 64))
+		}
+	})
+	return sfnt.Segments(self.segments)
+}
+
+// Calls the given function for each contour of the expanded outline,
+// passing references to all its points, including control points. If
+// the contour is explicitly closed, the closing point is excluded, but
+// it's still updated to match the first point after the call.
+func (self *outlineExpander) forEachContour(fn func([]*fixed.Point26_6)) {
+	flush := func() {
+		refs := self.refs
+		if len(refs) > 1 && *refs[0] == *refs[len(refs)-1] {
+			fn(refs[:len(refs)-1])
+			*refs[len(refs)-1] = *refs[0]
+		} else if len(refs) > 0 {
+			fn(refs)
+		}
+		self.refs = refs[:0]
+	}
+
+	self.refs = self.refs[:0]
+	for i := range self.segments {
+		segment := &self.segments[i]
+		if segment.Op == sfnt.SegmentOpMoveTo {
+			flush()
+		}
+		for j := 0; j < segmentOpNumArgs(segment.Op); j++ {
+			self.refs = append(self.refs, &segment.Args[j])
+		}
+	}
+	flush()
+}
+
+// Returns the normalized direction from the i-th point to the next
+// different point (step = +1), or from the previous different point
+// to the i-th point (step = -1), or (0, 0) if all points are equal.
+func expandFindDir(coords []float64, i int, step int) (float64, float64) {
+	n := len(coords) / 2
+	px, py := coords[2*i], coords[2*i+1]
+	for j, k := (i+step+n)%n, 1; k < n; j, k = (j+step+n)%n, k+1 {
+		dx, dy := coords[2*j]-px, coords[2*j+1]-py
+		if dx == 0 && dy == 0 {
+			continue
+		}
+		length := math.Hypot(dx, dy)
+		if step < 0 {
+			return -dx / length, -dy / length
+		}
+		return dx / length, dy / length
+	}
+	return 0, 0
+}
+
+func segmentOpNumArgs(op sfnt.SegmentOp) int {
+	switch op {
+	case sfnt.SegmentOpMoveTo, sfnt.SegmentOpLineTo:
+		return 1
+	case sfnt.SegmentOpQuadTo:
+		return 2
+	case sfnt.SegmentOpCubeTo:
+		return 3
+	default:
+		panic("unexpected segment.Op case")
+	}
+}
@@ -38,6 +38,22 @@ type Rasterizer interface {
 	SetOnChangeFunc(func(Rasterizer))
 }
 
+// SizeAwareRasterizer is an optional interface for rasterizers whose
+// masks depend on the text size, like [GammaRasterizer] with stem
+// darkening. Renderers notify the current scaled size to rasterizers
+// implementing this interface before rasterizing glyph masks.
+//
+// Glyph caches already keep masks for different sizes apart, so the
+// size doesn't need to be part of the signature.
+type SizeAwareRasterizer interface {
+	Rasterizer
+
+	// Notifies the size at which the next outlines will be rasterized.
+	// This is a reserved function that only a Renderer should call
+	// internally, like Rasterizer.SetOnChangeFunc().
+	NotifySizeChange(size fract.Unit)
+}
+
 // Maybe I could export this, but it doesn't feel that relevant.
 type vectorTracer interface {
 	// Move to the given coordinate.
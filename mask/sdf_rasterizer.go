@@ -0,0 +1,336 @@
+package mask
+
+import (
+	"image"
+	"math"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+var _ Rasterizer = (*SDFRasterizer)(nil)
+
+// A rasterizer that generates signed distance fields instead of regular
+// coverage masks. Each mask value encodes the distance from the center
+// of the pixel to the closest point of the glyph outline: 128 is on the
+// edge, higher values are inside the glyph and lower values are outside,
+// reaching 255 and 0 at the spread distance (see [SDFRasterizer.SetSpread]()).
+//
+// Distance fields can be scaled without losing sharp edges, so when an
+// etxt renderer uses this rasterizer, glyphs are always rasterized at the
+// reference size (see [SDFRasterizer.SetReferenceSize]()) and scaled to
+// the actual text size while drawing. This means that a single cached
+// mask per glyph can be used for any size and fractional position, which
+// is great for animated text sizes.
+//
+// The edge softness and outline width only affect how the distance
+// fields are drawn, so they can be changed without invalidating cached
+// masks. Masks retrieved directly with RendererGlyph.LoadMask() are
+// raw distance fields and won't look like regular glyphs if drawn as
+// such.
+//
+// Scaled distance fields round sharp corners slightly, and thin details
+// may be lost if the reference size is too small.
+type SDFRasterizer struct {
+	onChange   func(Rasterizer)
+	normOffset fract.Point
+	segmenter  curveSegmenter
+
+	spread       fract.Unit // zero for the default
+	refSize      fract.Unit // zero for the default
+	softness     fract.Unit // zero for the default
+	outlineWidth fract.Unit
+
+	lines []float64 // buffer for the flattened outline (x0, y0, x1, y1 groups)
+}
+
+const (
+	sdfDefaultSpread   = 8 * fract.One
+	sdfDefaultRefSize  = 64 * fract.One
+	sdfDefaultSoftness = fract.One
+)
+
+// Sets the maximum distance encoded in the masks, in pixels at the
+// reference size. The default is 8. Values outside the [1, 64] range
+// will be clamped, and the precision is quantized to 1/64ths of a pixel.
+//
+// Bigger spreads allow wider outlines and softer edges, but reduce
+// the precision of the distances and make masks bigger.
+func (self *SDFRasterizer) SetSpread(spread float64) {
+	if spread < 1 {
+		spread = 1
+	} else if spread > 64 {
+		spread = 64
+	}
+	fractSpread := fract.FromFloat64Down(spread)
+	if fractSpread == self.getSpread() {
+		return
+	}
+	self.spread = fractSpread
+	self.notifyChange()
+}
+
+// Returns the spread in pixels. See [SDFRasterizer.SetSpread]().
+func (self *SDFRasterizer) GetSpread() float64 {
+	return self.getSpread().ToFloat64()
+}
+
+// Sets the size at which glyphs are rasterized when drawing with a
+// renderer. The default is 64. Values outside the [8, 512] range will
+// be clamped.
+func (self *SDFRasterizer) SetReferenceSize(size float64) {
+	if size < 8 {
+		size = 8
+	} else if size > 512 {
+		size = 512
+	}
+	self.refSize = fract.FromFloat64Down(size)
+}
+
+// Returns the reference size. See [SDFRasterizer.SetReferenceSize]().
+func (self *SDFRasterizer) GetReferenceSize() float64 {
+	return self.getRefSize().ToFloat64()
+}
+
+// Sets the width of the antialiased transition at the glyph edges,
+// in pixels at the final text size. The default is 1. Bigger values
+// can be used for blurry text, shadows and glows. Values outside the
+// [1/64, 64] range will be clamped.
+func (self *SDFRasterizer) SetEdgeSoftness(softness float64) {
+	if softness < 1.0/64.0 {
+		softness = 1.0 / 64.0
+	} else if softness > 64 {
+		softness = 64
+	}
+	self.softness = fract.FromFloat64Down(softness)
+}
+
+// Returns the edge softness. See [SDFRasterizer.SetEdgeSoftness]().
+func (self *SDFRasterizer) GetEdgeSoftness() float64 {
+	if self.softness == 0 {
+		return sdfDefaultSoftness.ToFloat64()
+	}
+	return self.softness.ToFloat64()
+}
+
+// Sets the outline width in pixels at the final text size. If zero,
+// which is the default, glyphs are filled. Otherwise, only an outline
+// of the given width, centered on the glyph edges, is drawn. Values
+// outside the [0, 64] range will be clamped.
+//
+// The outline can't be wider than twice the spread at the final size,
+// so big outlines may need bigger spreads.
+func (self *SDFRasterizer) SetOutlineWidth(width float64) {
+	if width < 0 {
+		width = 0
+	} else if width > 64 {
+		width = 64
+	}
+	self.outlineWidth = fract.FromFloat64Down(width)
+}
+
+// Returns the outline width. See [SDFRasterizer.SetOutlineWidth]().
+func (self *SDFRasterizer) GetOutlineWidth() float64 {
+	return self.outlineWidth.ToFloat64()
+}
+
+// Returns the opacity in [0, 1] for the given mask value in [0, 1],
+// where scale is the ratio between the final text size and the
+// reference size.
+func (self *SDFRasterizer) opacity(value float64, scale float64) float64 {
+	dist := (value*255 - 128) / 127 * self.GetSpread() * scale
+	if self.outlineWidth > 0 {
+		dist = self.GetOutlineWidth()/2 - math.Abs(dist)
+	}
+	return clampUnit64(math.Max(dist/self.GetEdgeSoftness()+0.5, 0))
+}
+
+// Returns the opacity in [0, 1] at the given position of the distance
+// field, interpolating the closest mask values, where scale is the
+// ratio between the final text size and the reference size. This is
+// the thresholding used to draw distance fields on the CPU.
+//
+// The position is given in pixels relative to the mask bounds origin,
+// with (0.5, 0.5) being the center of the top-left pixel. Positions
+// outside the mask are considered to be far outside the glyph.
+func (self *SDFRasterizer) OpacityAt(field *image.Alpha, x, y float64, scale float64) float64 {
+	x, y = x-0.5, y-0.5
+	fx, fy := math.Floor(x), math.Floor(y)
+	tx, ty := x-fx, y-fy
+	ix, iy := int(fx)+field.Rect.Min.X, int(fy)+field.Rect.Min.Y
+	top := interpolateAt(sdfValueAt(field, ix, iy), sdfValueAt(field, ix+1, iy), tx)
+	bottom := interpolateAt(sdfValueAt(field, ix, iy+1), sdfValueAt(field, ix+1, iy+1), tx)
+	return self.opacity(interpolateAt(top, bottom, ty), scale)
+}
+
+// Satisfies the [Rasterizer] interface.
+func (self *SDFRasterizer) SetOnChangeFunc(onChange func(Rasterizer)) {
+	self.onChange = onChange
+}
+
+// Satisfies the [Rasterizer] interface. The signature for the
+// SDF rasterizer has the following shape:
+//   - 0xFF00000000000000 unused bits customizable through type embedding.
+//   - 0x00FF000000000000 bits being 0xDF (self signature byte).
+//   - 0x0000FFFFFFFF0000 bits being zero, currently undefined.
+//   - 0x000000000000FFFF bits encoding the spread in 64ths of a pixel.
+//
+// The reference size, edge softness and outline width don't affect
+// the masks, so they are not part of the signature.
+func (self *SDFRasterizer) Signature() uint64 {
+	return 0x00DF000000000000 | uint64(self.getSpread())
+}
+
+// Satisfies the [Rasterizer] interface.
+func (self *SDFRasterizer) Rasterize(outline sfnt.Segments, origin fract.Point) (*image.Alpha, error) {
+	if self.segmenter.maxCurveSplits == 0 {
+		self.segmenter.SetThreshold(0.1)
+		self.segmenter.SetMaxSplits(8)
+	}
+
+	// get outline bounds, expanded by the spread
+	fbounds := outline.Bounds()
+	spread := self.getSpread()
+	bounds := fract.Rect{
+		Min: fract.UnitsToPoint(fract.Unit(fbounds.Min.X)-spread, fract.Unit(fbounds.Min.Y)-spread),
+		Max: fract.UnitsToPoint(fract.Unit(fbounds.Max.X)+spread, fract.Unit(fbounds.Max.Y)+spread),
+	}
+	var width, height int
+	var rectOffset image.Point
+	width, height, self.normOffset, rectOffset = figureOutBounds(bounds, origin)
+	self.flattenOutline(outline)
+
+	// compute the distance field
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	floatSpread := spread.ToFloat64()
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dist := self.signedDistance(float64(x)+0.5, float64(y)+0.5)
+			value := 128 + dist/floatSpread*127
+			if value <= 0 {
+				continue
+			}
+			if value >= 255 {
+				mask.Pix[y*mask.Stride+x] = 255
+			} else {
+				mask.Pix[y*mask.Stride+x] = uint8(value + 0.5)
+			}
+		}
+	}
+
+	mask.Rect = mask.Rect.Add(rectOffset)
+	return mask, nil
+}
+
+func (self *SDFRasterizer) notifyChange() {
+	if self.onChange != nil {
+		self.onChange(self)
+	}
+}
+
+func (self *SDFRasterizer) getSpread() fract.Unit {
+	if self.spread == 0 {
+		return sdfDefaultSpread
+	}
+	return self.spread
+}
+
+func (self *SDFRasterizer) getRefSize() fract.Unit {
+	if self.refSize == 0 {
+		return sdfDefaultRefSize
+	}
+	return self.refSize
+}
+
+// Flattens the outline into line segments, closing contours implicitly.
+func (self *SDFRasterizer) flattenOutline(outline sfnt.Segments) {
+	var x, y, startX, startY float64
+	lineTo := func(fx, fy float64) {
+		if fx != x || fy != y {
+			self.lines = append(self.lines, x, y, fx, fy)
+			x, y = fx, fy
+		}
+	}
+
+	self.lines = self.lines[:0]
+	for _, segment := range outline {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			lineTo(startX, startY)
+			x, y = self.toFloat64s(segment.Args[0].X, segment.Args[0].Y)
+			startX, startY = x, y
+		case sfnt.SegmentOpLineTo:
+			lineTo(self.toFloat64s(segment.Args[0].X, segment.Args[0].Y))
+		case sfnt.SegmentOpQuadTo:
+			cx, cy := self.toFloat64s(segment.Args[0].X, segment.Args[0].Y)
+			fx, fy := self.toFloat64s(segment.Args[1].X, segment.Args[1].Y)
+			self.segmenter.TraceQuad(lineTo, x, y, cx, cy, fx, fy)
+		case sfnt.SegmentOpCubeTo:
+			cx1, cy1 := self.toFloat64s(segment.Args[0].X, segment.Args[0].Y)
+			cx2, cy2 := self.toFloat64s(segment.Args[1].X, segment.Args[1].Y)
+			fx, fy := self.toFloat64s(segment.Args[2].X, segment.Args[2].Y)
+			self.segmenter.TraceCube(lineTo, x, y, cx1, cy1, cx2, cy2, fx, fy)
+		default:
+			panic("unexpected segment.Op case")
+		}
+	}
+	lineTo(startX, startY)
+}
+
+func (self *SDFRasterizer) toFloat64s(x, y fixed.Int26_6) (float64, float64) {
+	point := fract.Point{X: fract.Unit(x), Y: fract.Unit(y)}
+	return point.AddPoint(self.normOffset).ToFloat64s()
+}
+
+// Returns the distance from the given point to the flattened outline,
+// positive inside the glyph (non-zero winding rule) and negative outside.
+func (self *SDFRasterizer) signedDistance(px, py float64) float64 {
+	minDist2 := math.Inf(1)
+	var winding int
+	lines := self.lines
+	for i := 0; i < len(lines); i += 4 {
+		ax, ay, bx, by := lines[i], lines[i+1], lines[i+2], lines[i+3]
+
+		// distance to the segment
+		dx, dy := bx-ax, by-ay
+		t := ((px-ax)*dx + (py-ay)*dy) / (dx*dx + dy*dy)
+		if t < 0 {
+			t = 0
+		} else if t > 1 {
+			t = 1
+		}
+		ox, oy := ax+t*dx-px, ay+t*dy-py
+		dist2 := ox*ox + oy*oy
+		if dist2 < minDist2 {
+			minDist2 = dist2
+		}
+
+		// winding contribution of a ray towards +x
+		if (ay <= py) != (by <= py) {
+			crossX := ax + (py-ay)/(by-ay)*dx
+			if crossX > px {
+				if by > ay {
+					winding += 1
+				} else {
+					winding -= 1
+				}
+			}
+		}
+	}
+
+	dist := math.Sqrt(minDist2)
+	if winding == 0 {
+		return -dist
+	}
+	return dist
+}
+
+// Returns the mask value at the given coordinates in [0, 1], or zero
+// if out of bounds.
+func sdfValueAt(field *image.Alpha, x, y int) float64 {
+	if !(image.Point{x, y}).In(field.Rect) {
+		return 0
+	}
+	return float64(field.Pix[field.PixOffset(x, y)]) / 255
+}
@@ -0,0 +1,81 @@
+package mask
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestSDFRasterizer(t *testing.T) {
+	var rast SDFRasterizer
+	field, err := Rasterize(strokeTestSquare(), &rast, fract.Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.Rect != image.Rect(2, 2, 38, 38) {
+		t.Fatalf("expected bounds expanded by the spread, got %v", field.Rect)
+	}
+
+	tests := []struct {
+		x, y  int
+		alpha uint8
+	}{
+		{20, 20, 255}, // center, beyond the spread
+		{10, 20, 136}, // left edge, half a pixel inside
+		{9, 20, 120},  // left edge, half a pixel outside
+		{2, 20, 9},    // left edge, 7.5 pixels outside
+		{29, 29, 136}, // bottom right corner, inside
+	}
+	for _, test := range tests {
+		alpha := field.AlphaAt(test.x, test.y).A
+		if alpha != test.alpha {
+			t.Fatalf("expected alpha %d at (%d, %d), got %d", test.alpha, test.x, test.y, alpha)
+		}
+	}
+
+	// thresholding at different scales
+	for _, scale := range []float64{0.5, 1, 3} {
+		edge := 10 - 2.0 // left edge, relative to the mask bounds
+		if opacity := rast.OpacityAt(field, edge, 18, scale); opacity < 0.45 || opacity > 0.55 {
+			t.Fatalf("scale %v: expected half opacity on the edge, got %v", scale, opacity)
+		}
+		if opacity := rast.OpacityAt(field, edge+2/scale, 18, scale); opacity != 1 {
+			t.Fatalf("scale %v: expected full opacity inside, got %v", scale, opacity)
+		}
+		if opacity := rast.OpacityAt(field, edge-2/scale, 18, scale); opacity != 0 {
+			t.Fatalf("scale %v: expected zero opacity outside, got %v", scale, opacity)
+		}
+	}
+
+	// outlines
+	rast.SetOutlineWidth(2)
+	if opacity := rast.OpacityAt(field, 8, 18, 1); opacity != 1 {
+		t.Fatalf("expected full opacity on the outline, got %v", opacity)
+	}
+	if opacity := rast.OpacityAt(field, 18, 18, 1); opacity != 0 {
+		t.Fatalf("expected zero opacity inside the outline, got %v", opacity)
+	}
+}
+
+func TestSDFRasterizerSignature(t *testing.T) {
+	var rast SDFRasterizer
+	var changes int
+	rast.SetOnChangeFunc(func(Rasterizer) { changes += 1 })
+
+	signature := rast.Signature()
+	if signature == (&DefaultRasterizer{}).Signature() {
+		t.Fatalf("unexpected signature %016X", signature)
+	}
+	rast.SetSpread(8) // default, redundant
+	rast.SetEdgeSoftness(2)
+	rast.SetOutlineWidth(3)
+	rast.SetReferenceSize(32)
+	if changes != 0 || rast.Signature() != signature {
+		t.Fatalf("draw parameters must not change the signature")
+	}
+	rast.SetSpread(4)
+	if changes != 1 || rast.Signature() == signature {
+		t.Fatalf("expected spread change to modify the signature")
+	}
+}
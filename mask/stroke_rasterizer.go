@@ -0,0 +1,389 @@
+package mask
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+var _ Rasterizer = (*StrokeRasterizer)(nil)
+
+// Join styles for [StrokeRasterizer], used where two segments of
+// a glyph outline meet at an angle.
+type StrokeJoin uint8
+
+const (
+	JoinMiter StrokeJoin = iota // sharp corners, beveled past the miter limit
+	JoinRound                   // rounded corners
+	JoinBevel                   // corners cut flat
+)
+
+// Returns the string representation of the [StrokeJoin]
+// (e.g., "JoinMiter", "JoinRound").
+func (self StrokeJoin) String() string {
+	switch self {
+	case JoinMiter:
+		return "JoinMiter"
+	case JoinRound:
+		return "JoinRound"
+	case JoinBevel:
+		return "JoinBevel"
+	default:
+		return "UnknownStrokeJoin"
+	}
+}
+
+// A rasterizer that strokes the glyph outlines instead of filling
+// them, which can be used to draw outlined text. The stroke is
+// centered on the outline, so half of it falls outside the glyph.
+// Optionally, the glyph can also be filled, which is useful to draw
+// a stroke behind the regular text in a different color or to get
+// heavier glyphs.
+//
+// Glyph contours are always closed, so there are no cap styles, only
+// joins (see [StrokeRasterizer.SetJoin]()).
+//
+// The zero value has a stroke width of zero, so it draws nothing
+// unless fill is enabled. Like [FauxRasterizer], this rasterizer
+// can't modify glyph advances, so wide strokes may need additional
+// spacing (see [sizer.PaddedAdvanceSizer]).
+//
+// [sizer.PaddedAdvanceSizer]: https://pkg.go.dev/github.com/tinne26/etxt/sizer@v0.0.10#PaddedAdvanceSizer
+type StrokeRasterizer struct {
+	rasterizer vector.Rasterizer
+	onChange   func(Rasterizer)
+	normOffset fract.Point
+	segmenter  curveSegmenter
+
+	width      fract.Unit
+	join       StrokeJoin
+	fill       bool
+	miterLimit uint16 // in 1/256ths, zero for the default
+
+	contour []float64 // buffer for contour points (x, y pairs)
+	polygon []float64 // buffer for stroke polygons (x, y pairs)
+}
+
+// Sets the stroke width in pixels. Values outside the [0, 1024] range
+// will be clamped, and the precision is quantized to 1/64ths of a pixel.
+func (self *StrokeRasterizer) SetWidth(width float64) {
+	if width < 0 {
+		width = 0
+	} else if width > 1024 {
+		width = 1024
+	}
+	fractWidth := fract.FromFloat64Down(width)
+	if fractWidth == self.width {
+		return
+	}
+	self.width = fractWidth
+	self.notifyChange()
+}
+
+// Returns the stroke width in pixels.
+func (self *StrokeRasterizer) GetWidth() float64 {
+	return self.width.ToFloat64()
+}
+
+// Sets the join style. The default is [JoinMiter].
+func (self *StrokeRasterizer) SetJoin(join StrokeJoin) {
+	if join > JoinBevel {
+		panic("invalid stroke join")
+	}
+	if join == self.join {
+		return
+	}
+	self.join = join
+	self.notifyChange()
+}
+
+// Returns the join style.
+func (self *StrokeRasterizer) GetJoin() StrokeJoin {
+	return self.join
+}
+
+// Sets the miter limit for [JoinMiter]: the maximum ratio between the
+// miter length and the stroke width before falling back to a bevel.
+// The default is 4. Values outside the [1, 255] range will be clamped,
+// and the precision is quantized to 1/256ths.
+func (self *StrokeRasterizer) SetMiterLimit(limit float64) {
+	if limit < 1 {
+		limit = 1
+	} else if limit > 255 {
+		limit = 255
+	}
+	miterLimit := uint16(limit * 256)
+	if miterLimit == self.miterLimit || (miterLimit == 4*256 && self.miterLimit == 0) {
+		return
+	}
+	self.miterLimit = miterLimit
+	self.notifyChange()
+}
+
+// Returns the miter limit. See [StrokeRasterizer.SetMiterLimit]().
+func (self *StrokeRasterizer) GetMiterLimit() float64 {
+	if self.miterLimit == 0 {
+		return 4
+	}
+	return float64(self.miterLimit) / 256
+}
+
+// Sets whether the glyph is filled in addition to being stroked.
+// Disabled by default.
+func (self *StrokeRasterizer) SetFill(fill bool) {
+	if fill == self.fill {
+		return
+	}
+	self.fill = fill
+	self.notifyChange()
+}
+
+// Returns whether the glyph is filled in addition to being stroked.
+func (self *StrokeRasterizer) GetFill() bool {
+	return self.fill
+}
+
+// Satisfies the [Rasterizer] interface.
+func (self *StrokeRasterizer) SetOnChangeFunc(onChange func(Rasterizer)) {
+	self.onChange = onChange
+}
+
+// Satisfies the [Rasterizer] interface. The signature for the
+// stroke rasterizer has the following shape:
+//   - 0xFF00000000000000 unused bits customizable through type embedding.
+//   - 0x00FF000000000000 bits being 0x57 (self signature byte).
+//   - 0x0000F00000000000 bits encoding the join style.
+//   - 0x00000F0000000000 bits being 0x1 if fill is enabled.
+//   - 0x000000FFFF000000 bits encoding the miter limit in 1/256ths,
+//     or zero for the default limit.
+//   - 0x0000000000FFFFFF bits encoding the stroke width in 64ths of
+//     a pixel.
+func (self *StrokeRasterizer) Signature() uint64 {
+	signature := uint64(0x0057000000000000)
+	signature |= uint64(self.join) << 44
+	if self.fill {
+		signature |= 0x0000010000000000
+	}
+	signature |= uint64(self.miterLimit) << 24
+	return signature | uint64(self.width)
+}
+
+// Satisfies the [Rasterizer] interface.
+func (self *StrokeRasterizer) Rasterize(outline sfnt.Segments, origin fract.Point) (*image.Alpha, error) {
+	if self.segmenter.maxCurveSplits == 0 {
+		self.segmenter.SetThreshold(0.1)
+		self.segmenter.SetMaxSplits(8)
+	}
+
+	// get outline bounds, expanded to fit the stroke
+	fbounds := outline.Bounds()
+	margin := self.width >> 1
+	if self.join == JoinMiter {
+		margin = margin.Mul(fract.FromFloat64Up(self.GetMiterLimit()))
+	}
+	margin += 1 // (some leeway for rounding)
+	bounds := fract.Rect{
+		Min: fract.UnitsToPoint(fract.Unit(fbounds.Min.X)-margin, fract.Unit(fbounds.Min.Y)-margin),
+		Max: fract.UnitsToPoint(fract.Unit(fbounds.Max.X)+margin, fract.Unit(fbounds.Max.Y)+margin),
+	}
+
+	// prepare rasterizer and glyph mask
+	var width, height int
+	var rectOffset image.Point
+	width, height, self.normOffset, rectOffset = figureOutBounds(bounds, origin)
+	self.rasterizer.Reset(width, height)
+	mask := image.NewAlpha(self.rasterizer.Bounds())
+
+	// fill the glyph first if necessary. the stroke must be drawn
+	// in a separate pass, as its winding could cancel the fill
+	if self.fill {
+		self.rasterizer.DrawOp = draw.Src
+		self.traceOutline(outline, true)
+		self.rasterizer.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+		self.rasterizer.Reset(width, height)
+		self.rasterizer.DrawOp = draw.Over
+	} else {
+		self.rasterizer.DrawOp = draw.Src
+	}
+
+	if self.width > 0 {
+		self.traceOutline(outline, false)
+		self.rasterizer.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	}
+
+	mask.Rect = mask.Rect.Add(rectOffset)
+	return mask, nil
+}
+
+func (self *StrokeRasterizer) notifyChange() {
+	if self.onChange != nil {
+		self.onChange(self)
+	}
+}
+
+// Flattens the outline contour by contour, and either fills it
+// directly or strokes each contour.
+func (self *StrokeRasterizer) traceOutline(outline sfnt.Segments, fill bool) {
+	var x, y float64
+	lineTo := func(fx, fy float64) {
+		if fx != x || fy != y {
+			self.contour = append(self.contour, fx, fy)
+			x, y = fx, fy
+		}
+	}
+
+	self.contour = self.contour[:0]
+	for _, segment := range outline {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			self.flushContour(fill)
+			x, y = self.toFloat64s(segment.Args[0].X, segment.Args[0].Y)
+			self.contour = append(self.contour, x, y)
+		case sfnt.SegmentOpLineTo:
+			lineTo(self.toFloat64s(segment.Args[0].X, segment.Args[0].Y))
+		case sfnt.SegmentOpQuadTo:
+			cx, cy := self.toFloat64s(segment.Args[0].X, segment.Args[0].Y)
+			fx, fy := self.toFloat64s(segment.Args[1].X, segment.Args[1].Y)
+			self.segmenter.TraceQuad(lineTo, x, y, cx, cy, fx, fy)
+		case sfnt.SegmentOpCubeTo:
+			cx1, cy1 := self.toFloat64s(segment.Args[0].X, segment.Args[0].Y)
+			cx2, cy2 := self.toFloat64s(segment.Args[1].X, segment.Args[1].Y)
+			fx, fy := self.toFloat64s(segment.Args[2].X, segment.Args[2].Y)
+			self.segmenter.TraceCube(lineTo, x, y, cx1, cy1, cx2, cy2, fx, fy)
+		default:
+			panic("unexpected segment.Op case")
+		}
+	}
+	self.flushContour(fill)
+}
+
+func (self *StrokeRasterizer) toFloat64s(x, y fixed.Int26_6) (float64, float64) {
+	point := fract.Point{X: fract.Unit(x), Y: fract.Unit(y)}
+	return point.AddPoint(self.normOffset).ToFloat64s()
+}
+
+// Fills or strokes the current contour and clears it. Contours
+// are closed implicitly.
+func (self *StrokeRasterizer) flushContour(fill bool) {
+	points := self.contour
+	self.contour = self.contour[:0]
+	n := len(points) / 2
+	if n > 1 && points[0] == points[2*n-2] && points[1] == points[2*n-1] {
+		n -= 1 // (explicitly closed contour)
+	}
+	if n < 2 {
+		return
+	}
+
+	if fill {
+		self.rasterizer.MoveTo(float32(points[0]), float32(points[1]))
+		for i := 1; i < n; i++ {
+			self.rasterizer.LineTo(float32(points[2*i]), float32(points[2*i+1]))
+		}
+		self.rasterizer.ClosePath()
+		return
+	}
+
+	// stroke each segment as a quad, and add the joins
+	halfWidth := self.width.ToFloat64() / 2
+	for i := 0; i < n; i++ {
+		ax, ay := points[2*i], points[2*i+1]
+		j := (i + 1) % n
+		bx, by := points[2*j], points[2*j+1]
+		nx, ny := strokeNormal(bx-ax, by-ay, halfWidth)
+		self.addPolygon(ax+nx, ay+ny, bx+nx, by+ny, bx-nx, by-ny, ax-nx, ay-ny)
+
+		k := (j + 1) % n
+		cx, cy := points[2*k], points[2*k+1]
+		self.addJoin(bx, by, bx-ax, by-ay, cx-bx, cy-by, halfWidth)
+	}
+}
+
+// Adds the join at point (px, py) between a segment with direction
+// (dx0, dy0) and the next one with direction (dx1, dy1).
+func (self *StrokeRasterizer) addJoin(px, py, dx0, dy0, dx1, dy1, halfWidth float64) {
+	cross := dx0*dy1 - dy0*dx1
+	dot := dx0*dx1 + dy0*dy1
+	if cross == 0 && dot > 0 {
+		return // straight continuation
+	}
+
+	// offsets to the outer side of the turn
+	nx0, ny0 := strokeNormal(dx0, dy0, halfWidth)
+	nx1, ny1 := strokeNormal(dx1, dy1, halfWidth)
+	if cross > 0 {
+		nx0, ny0, nx1, ny1 = -nx0, -ny0, -nx1, -ny1
+	}
+
+	switch self.join {
+	case JoinMiter:
+		// the miter length ratio is 1/cos(theta/2), or sqrt(2/(1 + cos(theta)))
+		cos := dot / (math.Hypot(dx0, dy0) * math.Hypot(dx1, dy1))
+		limit := self.GetMiterLimit()
+		if cos > -1 && 2/(1+cos) <= limit*limit {
+			scale := 1 / (1 + cos)
+			mx, my := px+(nx0+nx1)*scale, py+(ny0+ny1)*scale
+			self.addPolygon(px, py, px+nx0, py+ny0, mx, my, px+nx1, py+ny1)
+			return
+		}
+		fallthrough
+	case JoinBevel:
+		self.addPolygon(px, py, px+nx0, py+ny0, px+nx1, py+ny1)
+	case JoinRound:
+		start := math.Atan2(ny0, nx0)
+		delta := math.Atan2(ny1, nx1) - start
+		if delta > math.Pi {
+			delta -= 2 * math.Pi
+		} else if delta < -math.Pi {
+			delta += 2 * math.Pi
+		}
+		steps := int(math.Ceil(math.Abs(delta) / (math.Pi / 16)))
+		self.polygon = append(self.polygon[:0], px, py)
+		for i := 0; i <= steps; i++ {
+			angle := start + delta*float64(i)/float64(steps)
+			self.polygon = append(self.polygon, px+halfWidth*math.Cos(angle), py+halfWidth*math.Sin(angle))
+		}
+		self.addPolygon(self.polygon...)
+	default:
+		panic(self.join)
+	}
+}
+
+// Adds a closed polygon with the given (x, y) pairs to the rasterizer.
+// All polygons are added with the same orientation, as otherwise they
+// could cancel each other where they overlap.
+func (self *StrokeRasterizer) addPolygon(coords ...float64) {
+	n := len(coords) / 2
+	var area float64
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += coords[2*i]*coords[2*j+1] - coords[2*j]*coords[2*i+1]
+	}
+	if area == 0 {
+		return
+	}
+
+	if area > 0 {
+		self.rasterizer.MoveTo(float32(coords[0]), float32(coords[1]))
+		for i := 1; i < n; i++ {
+			self.rasterizer.LineTo(float32(coords[2*i]), float32(coords[2*i+1]))
+		}
+	} else {
+		self.rasterizer.MoveTo(float32(coords[2*n-2]), float32(coords[2*n-1]))
+		for i := n - 2; i >= 0; i-- {
+			self.rasterizer.LineTo(float32(coords[2*i]), float32(coords[2*i+1]))
+		}
+	}
+	self.rasterizer.ClosePath()
+}
+
+// Returns the normal of the given direction, scaled to the given length.
+func strokeNormal(dx, dy, length float64) (float64, float64) {
+	scale := length / math.Hypot(dx, dy)
+	return -dy * scale, dx * scale
+}
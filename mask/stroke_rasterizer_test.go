@@ -0,0 +1,119 @@
+package mask
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
+
+// square from (10, 10) to (30, 30), without explicit closing
+func strokeTestSquare() sfnt.Segments {
+	var segments []sfnt.Segment
+	segments = moveTo(segments, 10*64, 10*64)
+	segments = lineTo(segments, 30*64, 10*64)
+	segments = lineTo(segments, 30*64, 30*64)
+	segments = lineTo(segments, 10*64, 30*64)
+	return sfnt.Segments(segments)
+}
+
+func TestStrokeRasterizer(t *testing.T) {
+	var rast StrokeRasterizer
+	rast.SetWidth(4)
+	mask, err := Rasterize(strokeTestSquare(), &rast, fract.Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		x, y  int
+		alpha uint8
+	}{
+		{20, 20, 0},   // center, not filled
+		{9, 20, 255},  // left edge, outside half
+		{10, 20, 255}, // left edge, inside half
+		{12, 20, 0},   // past the inside half
+		{7, 20, 0},    // past the outside half
+		{20, 31, 255}, // bottom edge, closing segment
+		{8, 8, 255},   // miter corner
+	}
+	for _, test := range tests {
+		alpha := mask.AlphaAt(test.x, test.y).A
+		if alpha != test.alpha {
+			t.Fatalf("expected alpha %d at (%d, %d), got %d", test.alpha, test.x, test.y, alpha)
+		}
+	}
+
+	// joins
+	rast.SetJoin(JoinBevel)
+	mask, _ = Rasterize(strokeTestSquare(), &rast, fract.Point{})
+	if alpha := mask.AlphaAt(8, 8).A; alpha != 0 {
+		t.Fatalf("expected bevel corner, got alpha %d", alpha)
+	}
+	rast.SetJoin(JoinRound)
+	mask, _ = Rasterize(strokeTestSquare(), &rast, fract.Point{})
+	if alpha := mask.AlphaAt(8, 8).A; alpha == 0 || alpha == 255 {
+		t.Fatalf("expected round corner, got alpha %d", alpha)
+	}
+	rast.SetJoin(JoinMiter)
+	rast.SetMiterLimit(1)
+	mask, _ = Rasterize(strokeTestSquare(), &rast, fract.Point{})
+	if alpha := mask.AlphaAt(8, 8).A; alpha != 0 {
+		t.Fatalf("expected miter limit to bevel the corner, got alpha %d", alpha)
+	}
+
+	// fill, with a hole that must not cancel the stroke
+	segments := []sfnt.Segment(strokeTestSquare())
+	segments = moveTo(segments, 15*64, 15*64)
+	segments = lineTo(segments, 15*64, 25*64)
+	segments = lineTo(segments, 25*64, 25*64)
+	segments = lineTo(segments, 25*64, 15*64)
+	segments = lineTo(segments, 15*64, 15*64)
+	rast.SetFill(true)
+	mask, _ = Rasterize(sfnt.Segments(segments), &rast, fract.Point{})
+	for _, point := range []image.Point{{12, 20}, {9, 20}, {14, 20}, {15, 20}} {
+		if alpha := mask.AlphaAt(point.X, point.Y).A; alpha != 255 {
+			t.Fatalf("expected alpha 255 at (%d, %d), got %d", point.X, point.Y, alpha)
+		}
+	}
+	if alpha := mask.AlphaAt(20, 20).A; alpha != 0 {
+		t.Fatalf("expected empty hole, got alpha %d", alpha)
+	}
+}
+
+func TestStrokeRasterizerSignature(t *testing.T) {
+	var rast StrokeRasterizer
+	var changes int
+	rast.SetOnChangeFunc(func(Rasterizer) { changes += 1 })
+
+	seen := map[uint64]bool{(&DefaultRasterizer{}).Signature(): true}
+	check := func(name string) {
+		signature := rast.Signature()
+		if seen[signature] {
+			t.Fatalf("%s: repeated signature %016X", name, signature)
+		}
+		seen[signature] = true
+	}
+	check("zero")
+	rast.SetWidth(2.5)
+	check("width")
+	rast.SetJoin(JoinRound)
+	check("join")
+	rast.SetFill(true)
+	check("fill")
+	rast.SetMiterLimit(2)
+	check("miter limit")
+	if changes != 4 {
+		t.Fatalf("expected 4 changes, got %d", changes)
+	}
+
+	// redundant changes must not be notified
+	rast.SetWidth(2.5)
+	rast.SetJoin(JoinRound)
+	rast.SetFill(true)
+	rast.SetMiterLimit(2)
+	if changes != 4 {
+		t.Fatalf("expected 4 changes, got %d", changes)
+	}
+}
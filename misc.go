@@ -1,9 +1,11 @@
 package etxt
 
 import (
+	"image/color"
 	"strconv"
 
 	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
 	"golang.org/x/image/font/sfnt"
 )
 
@@ -23,7 +25,11 @@ type Font = sfnt.Font
 // [sfnt.GlyphIndex] is used, but they are completely interchangeable.
 type GlyphIndex = sfnt.GlyphIndex
 
-// See [RendererTwine.RegisterFont]() and related functions.
+// Font indices are used to refer to the fonts registered on a
+// renderer through [RendererTwine.RegisterFont](). [TwineStyle]
+// values use them to select the font for each run of text.
+//
+// The index 0 is special, as it's the one used by [Renderer.SetFont]().
 //
 // When using multiple fonts, you are encouraged to define
 // and use your own named constants within the relevant context.
@@ -34,10 +40,7 @@ type GlyphIndex = sfnt.GlyphIndex
 //	    BoldFont
 //	    ItalicFont
 //	)
-type fontIndex uint8
-
-// Special value for [RendererTwine.RegisterFont]().
-//const NextFontIndex FontIndex = 255
+type FontIndex uint8
 
 // Quantization levels are used to control the trade-off between
 // memory usage and glyph positioning precision. Less theoretically:
@@ -73,11 +76,13 @@ const (
 )
 
 // Renderers can have their text direction configured as
-// left-to-right or right-to-left. See [Renderer.SetDirection]()
-// for further context and details.
+// left-to-right or right-to-left, either for the whole text or
+// for paragraphs processed with the Unicode Bidirectional
+// Algorithm, or as vertical top-to-bottom text. See
+// [Renderer.SetDirection]() for further context and details.
 //
-// If necessary, directions can also be casted directly to
-// [unicode/bidi] directions:
+// If necessary, [LeftToRight] and [RightToLeft] can also be
+// casted directly to [unicode/bidi] directions:
 //
 //	bidi.Direction(etxt.LeftToRight).
 //
@@ -85,10 +90,12 @@ const (
 type Direction int8
 
 const (
-	LeftToRight Direction = iota
-	RightToLeft
-	textDirectionUnexportedMixed
-	textDirectionUnexportedNeutral
+	LeftToRight     Direction = iota // all text is laid out left-to-right
+	RightToLeft                      // all text is laid out right-to-left
+	BidiLeftToRight                  // bidi algorithm with left-to-right paragraphs
+	BidiRightToLeft                  // bidi algorithm with right-to-left paragraphs
+	BidiAuto                         // bidi algorithm with paragraph direction based on content
+	TopToBottom                      // vertical text, with columns laid out right-to-left
 )
 
 // Returns the string representation of the [Direction]
@@ -99,15 +106,199 @@ func (self Direction) String() string {
 		return "LeftToRight"
 	case RightToLeft:
 		return "RightToLeft"
-	case textDirectionUnexportedMixed:
-		return "Mixed"
-	case textDirectionUnexportedNeutral:
-		return "Neutral"
+	case BidiLeftToRight:
+		return "BidiLeftToRight"
+	case BidiRightToLeft:
+		return "BidiRightToLeft"
+	case BidiAuto:
+		return "BidiAuto"
+	case TopToBottom:
+		return "TopToBottom"
 	default:
 		return "UnknownTextDirection"
 	}
 }
 
+func (self Direction) isBidi() bool {
+	return self >= BidiLeftToRight && self <= BidiAuto
+}
+
+func (self Direction) isVertical() bool {
+	return self == TopToBottom
+}
+
+// Line breaking strategies used when wrapping text with functions
+// like [Renderer.DrawWithWrap]() and [Renderer.MeasureWithWrap]().
+// See [RendererLayout.SetLineBreaking]() for further details.
+type LineBreaking uint8
+
+const (
+	LineBreakGreedy  LineBreaking = iota // fit as many words as possible on each line
+	LineBreakOptimal                     // minimize raggedness for the whole paragraph
+)
+
+// Returns the string representation of the [LineBreaking]
+// (e.g., "LineBreakGreedy", "LineBreakOptimal").
+func (self LineBreaking) String() string {
+	switch self {
+	case LineBreakGreedy:
+		return "LineBreakGreedy"
+	case LineBreakOptimal:
+		return "LineBreakOptimal"
+	default:
+		return "UnknownLineBreaking"
+	}
+}
+
+// Truncation modes used by functions like [Renderer.DrawWithTruncation]()
+// and [Renderer.MeasureWithTruncation]() to decide which part of the text
+// is replaced by an ellipsis. See [RendererLayout.SetTruncation]().
+type Truncation uint8
+
+const (
+	TruncateEnd    Truncation = iota // keep the start of the text
+	TruncateStart                    // keep the end of the text
+	TruncateMiddle                   // keep both ends of the text (e.g. file paths)
+)
+
+// Returns the string representation of the [Truncation]
+// (e.g., "TruncateEnd", "TruncateMiddle").
+func (self Truncation) String() string {
+	switch self {
+	case TruncateEnd:
+		return "TruncateEnd"
+	case TruncateStart:
+		return "TruncateStart"
+	case TruncateMiddle:
+		return "TruncateMiddle"
+	default:
+		return "UnknownTruncation"
+	}
+}
+
+// Tab stop aligns, used by [TabStop] to decide how the text after a
+// tab character ('\t') is placed relative to the tab stop position.
+// For right-to-left text, stop positions are measured from the right
+// edge of the line, and [TabLeft] and [TabRight] are mirrored.
+type TabAlign uint8
+
+const (
+	TabLeft    TabAlign = iota // the text starts at the tab stop
+	TabRight                   // the text ends at the tab stop
+	TabCenter                  // the text is centered on the tab stop
+	TabDecimal                 // the first '.' of the text is placed at the tab stop (right if none)
+)
+
+// Returns the string representation of the [TabAlign]
+// (e.g., "TabLeft", "TabDecimal").
+func (self TabAlign) String() string {
+	switch self {
+	case TabLeft:
+		return "TabLeft"
+	case TabRight:
+		return "TabRight"
+	case TabCenter:
+		return "TabCenter"
+	case TabDecimal:
+		return "TabDecimal"
+	default:
+		return "UnknownTabAlign"
+	}
+}
+
+// A tab stop for [RendererLayout.SetTabStops](). The position is
+// the distance from the start of the line in logical pixels, so it's
+// multiplied by the renderer's scale. The text after a tab, up to the
+// next tab or the end of the line, is aligned to the stop.
+type TabStop struct {
+	Position float64
+	Align    TabAlign
+}
+
+// Details about the text removed by operations like
+// [Renderer.DrawWithTruncation](). If the text was truncated,
+// the bytes in text[CutStart:CutEnd] were replaced by an ellipsis.
+// Both indices are always at rune boundaries.
+type TruncationDetails struct {
+	Truncated bool
+	CutStart  int
+	CutEnd    int
+}
+
+// The result of a hit test with [Renderer.HitTest]() or
+// [Renderer.HitTestWithWrap]().
+//
+// ByteIndex and RuneIndex refer to the rune closest to the tested
+// point, and Trailing indicates whether the point falls on the
+// trailing half of the rune (the right half for left-to-right
+// runes, the left half for right-to-left ones and the bottom
+// half for [TopToBottom] text). CaretIndex is
+// the byte index where a caret should be placed for the point,
+// which is the index after the rune if Trailing is true.
+//
+// Points on empty lines resolve to the start of the line, with
+// Trailing set to false.
+type TextHit struct {
+	ByteIndex  int
+	RuneIndex  int
+	Trailing   bool
+	CaretIndex int
+}
+
+// Measurement details for a single line of text, as returned by
+// [Renderer.MeasureLines]() and [Renderer.MeasureLinesWithWrap]().
+//
+// Positions are relative to the coordinates passed to the equivalent
+// draw function, taking into account the renderer's align and text
+// direction. The rect spans the line's full height, from the top of
+// its ascent to the bottom of its descent (line gap included), and
+// its width doesn't include spaces elided at line wraps.
+//
+// The line content is text[ByteStart:ByteEnd], which doesn't include
+// the line break or the space elided at the end of the line, if any.
+// Change describes how the line ended, like [RendererGlyph.SetLineChangeFunc]().
+// LineBreak is true if the line ended with an explicit '\n'. If
+// neither LineBreak nor Change.IsWrap are true, the line is the
+// last one of the text.
+//
+// For [TopToBottom] text, lines are columns: the rect spans the
+// column's width and length, and Baseline is the x coordinate of
+// the column's vertical axis.
+type MeasuredLine struct {
+	Rect      fract.Rect
+	Baseline  fract.Unit
+	ByteStart int
+	ByteEnd   int
+	Change    LineChangeDetails
+	LineBreak bool
+}
+
+// A render layer for [RendererLayout.SetLayers](). Nil fields keep
+// the values from the renderer or the twine style, while the blend
+// mode and the offset are always applied. Offsets are given in pixels.
+//
+// Colors set on layers also override highlights, and color glyphs
+// (see [RendererGlyph.SetColorGlyphs]()) are drawn with the layer
+// color instead of their palette colors.
+type RenderLayer struct {
+	Rasterizer mask.Rasterizer // nil to keep the current rasterizer
+	Color      color.Color     // nil to keep the current color
+	BlendMode  BlendMode
+	OffsetX    int
+	OffsetY    int
+}
+
+// Applies the layer overrides to the given style.
+func (self *RenderLayer) applyTo(style *layoutStyle) {
+	if self.Rasterizer != nil {
+		style.rasterizer = self.Rasterizer
+	}
+	if self.Color != nil {
+		style.color = self.Color
+	}
+	style.blendMode = self.BlendMode
+}
+
 // --- misc helpers ---
 
 // can replace with max() when minimum version reaches go1.21
@@ -118,6 +309,13 @@ func maxInt(a, b int) int {
 	return b
 }
 
+func minInt(a, b int) int {
+	if a <= b {
+		return a
+	}
+	return b
+}
+
 func runeToUnicodeCode(r rune) string {
 	return "\\u" + strconv.FormatInt(int64(r), 16)
 }
@@ -137,3 +335,43 @@ func ensureSliceSize[T any](slice []T, size int) []T {
 		return append(slice, make([]T, growth)...)
 	}
 }
+
+// Text decorations, drawn as lines across the text. Decorations are
+// flags and can be combined, like Underline | Strikethrough. See
+// [RendererLayout.SetDecorations]() and [TwineStyle].
+type Decoration uint8
+
+const (
+	Underline     Decoration = 1 << iota // line below the baseline
+	Strikethrough                        // line through the middle of lowercase letters
+	Overline                             // line at the ascent
+)
+
+// Returns the string representation of the [Decoration]
+// (e.g., "Underline", "Underline|Overline", "NoDecoration").
+func (self Decoration) String() string {
+	if self == 0 {
+		return "NoDecoration"
+	}
+	var str string
+	for _, flag := range [...]Decoration{Underline, Strikethrough, Overline} {
+		if self&flag == 0 {
+			continue
+		}
+		if str != "" {
+			str += "|"
+		}
+		switch flag {
+		case Underline:
+			str += "Underline"
+		case Strikethrough:
+			str += "Strikethrough"
+		case Overline:
+			str += "Overline"
+		}
+	}
+	if self&^(Underline|Strikethrough|Overline) != 0 {
+		return "UnknownDecoration"
+	}
+	return str
+}
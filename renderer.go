@@ -4,6 +4,7 @@ import (
 	"image/color"
 
 	"github.com/tinne26/etxt/cache"
+	"github.com/tinne26/etxt/font"
 	"github.com/tinne26/etxt/fract"
 	"github.com/tinne26/etxt/mask"
 	"github.com/tinne26/etxt/sizer"
@@ -31,6 +32,8 @@ import (
 //   - [Renderer.Fract](), to access specialized fractional positioning functionality.
 //   - [Renderer.Glyph](), to access low level functions for glyphs and
 //     glyph masks.
+//   - [Renderer.Twine](), to draw and measure rich text.
+//   - [Renderer.Layout](), to configure advanced text layout options.
 //
 // To create a renderer, using [NewRenderer]() is recommended. Before you
 // can start using it, though, you have to set a font. In most practical
@@ -51,7 +54,18 @@ type Renderer struct {
 	lineChangeFn  func(LineChangeDetails)
 	missHandlerFn func(*sfnt.Font, rune) (sfnt.GlyphIndex, bool)
 	fonts         []*sfnt.Font
+	fallbackFonts []*sfnt.Font
+	vertMetrics   map[*sfnt.Font]*font.VertMetrics
+	colorGlyphs   map[*sfnt.Font]*font.ColorGlyphs
+	positioning   map[*sfnt.Font]*font.GlyphPositioning
+	colorPalette  int
+	colorRast     colorGlyphRasterizer
+	monochrome    bool                                  // draw color glyphs as regular glyphs
+	decorMetrics  map[*sfnt.Font]font.DecorationMetrics // cache
+	sideways      *sidewaysRasterizer
 	buffer        sfnt.Buffer
+	layout        textLayout
+	layoutOptions layoutOptions
 
 	cachedMidHeight   fract.Unit
 	cachedCapHeight   fract.Unit
@@ -78,7 +92,8 @@ func NewRenderer() *Renderer {
 			logicalSize:      16 * fract.One,
 			scaledSize:       16 * fract.One,
 		},
-		fonts: make([]*sfnt.Font, 0, 1),
+		fonts:         make([]*sfnt.Font, 0, 1),
+		layoutOptions: defaultLayoutOptions(),
 	}
 }
 
@@ -146,6 +161,35 @@ func (self *Renderer) GetScale() float64 {
 // direction is typically only changed for right-to-left languages
 // like Arabic, Hebrew or Persian.
 //
+// [LeftToRight] and [RightToLeft] apply to the whole text, so any
+// embedded words or numbers in the opposite direction must be
+// reversed manually. For mixed text, use [BidiLeftToRight],
+// [BidiRightToLeft] or [BidiAuto] instead. These apply the Unicode
+// Bidirectional Algorithm to each paragraph, reordering runs
+// visually on each line after line wrapping. With [BidiAuto],
+// each paragraph's direction is determined by its first strong
+// directional character.
+//
+// [TopToBottom] lays out text vertically, as traditionally done for
+// Chinese, Japanese and Korean, with lines becoming columns that flow
+// from right to left. Ideographs, kana and similar characters are drawn
+// upright, punctuation uses vertical presentation forms when the font
+// has them, and other text (e.g. Latin) is rotated sideways. Vertical
+// advances can be configured with [RendererGlyph.SetVertMetrics]().
+// Some details on vertical text:
+//   - The horizontal align places the whole block of columns, while the
+//     vertical align places the text within each column. Only [Top],
+//     [VertCenter] and [Bottom] are meaningful here; other vertical aligns
+//     behave like [Top]. Justified aligns behave like [Right].
+//   - Width limits for wrapping and truncation apply to column lengths,
+//     and measured rects are as wide as all the columns together.
+//   - Horizontal quantization applies along the columns, and vertical
+//     quantization across them.
+//   - Sideways glyphs are rasterized with a wrapper of the renderer's
+//     rasterizer, which is the one custom draw functions will find set.
+//   - [Feed] doesn't support vertical text, and it will panic if used
+//     with it.
+//
 // Notice that etxt is not really at a point where it can handle
 // complex scripts properly; if that's an important feature for you,
 // consider [ebiten/v2/text/v2] instead.
@@ -155,7 +199,7 @@ func (self *Renderer) SetDirection(dir Direction) {
 	// basically, this can change the text iteration order,
 	// from first \n to next, to next \n to first.
 	switch dir {
-	case LeftToRight, RightToLeft:
+	case LeftToRight, RightToLeft, BidiLeftToRight, BidiRightToLeft, BidiAuto, TopToBottom:
 		self.state.textDirection = dir
 	default:
 		panic("invalid direction")
@@ -197,6 +241,7 @@ func (self *Renderer) SetFont(font *sfnt.Font) {
 }
 
 func (self *Renderer) notifyFontChange(font *sfnt.Font) {
+	self.cachedMetricsSize = -1
 	if self.cacheHandler != nil {
 		self.cacheHandler.NotifyFontChange(font)
 	}
@@ -20,8 +20,8 @@ type Align uint8
 func (self Align) Vert() Align { return alignVertBits & self }
 
 // Returns the horizontal component of the align. If the
-// align is valid, the result can only be [Left], [HorzCenter]
-// or [Right].
+// align is valid, the result can only be [Left], [HorzCenter],
+// [Right], [Justify] or [JustifyAll].
 func (self Align) Horz() Align { return alignHorzBits & self }
 
 // Returns whether the align has a vertical component.
@@ -53,12 +53,12 @@ func (self Align) Adjusted(align Align) Align {
 }
 
 // Returns a value between 'left' and 'right' based on the current horizontal align:
-//   - [Left]: the function returns 'left'.
+//   - [Left], [Justify], [JustifyAll]: the function returns 'left'.
 //   - [Right]: the function returns 'right'.
 //   - Otherwise: the function returns the middle point between 'left' and 'right'.
 func (self Align) GetHorzAnchor(left, right int) int {
 	switch self.Horz() {
-	case Left:
+	case Left, Justify, JustifyAll:
 		return left
 	case Right:
 		return right
@@ -117,6 +117,10 @@ func (self Align) horzString() string {
 		return "HorzCenter"
 	case Right:
 		return "Right"
+	case Justify:
+		return "Justify"
+	case JustifyAll:
+		return "JustifyAll"
 	default:
 		return "HorzUnknown"
 	}
@@ -132,6 +136,8 @@ const (
 	Left       Align = 0b0010_0000
 	HorzCenter Align = 0b0100_0000
 	Right      Align = 0b1000_0000
+	Justify    Align = 0b0011_0000 // see [Renderer.DrawWithWrap]()
+	JustifyAll Align = 0b0001_0000 // like Justify, but also for last lines
 
 	// Vertical aligns
 	Top          Align = 0b0000_0001 // top of font's ascent
@@ -0,0 +1,172 @@
+package etxt
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+
+	"github.com/tinne26/etxt/font"
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Drawing for color glyphs (COLR/CPAL version 0 layers). When the active
+// font has color glyph data (see RendererGlyph.SetColorGlyphs()), the
+// layers of color glyphs are rasterized with the current rasterizer and
+// composed into a single premultiplied RGBA image, which is converted to
+// an RGBA mask and drawn with drawColorMask() (see ebiten_yes.go and
+// ebiten_no.go). Composed masks are cached as RGBA masks (only if the cache
+// handler implements cache.RGBAMaskCacheHandler) with the signature of the
+// colorGlyphRasterizer wrapper, so different palettes don't collide.
+
+// Signature bits flipped by colorGlyphRasterizer.
+const colorGlyphSignatureBits = 0xC000000000000000
+
+// Returns the color glyph data and the color layers for the given glyph
+// of the active font, or nil layers if it must be drawn as a regular glyph.
+func (self *Renderer) colorGlyphLayers(index sfnt.GlyphIndex) (*font.ColorGlyphs, []font.ColorLayer) {
+	colors := self.colorGlyphs[self.state.activeFont]
+	if colors == nil {
+		return nil, nil
+	}
+	return colors, colors.Layers(index)
+}
+
+// Draws the given color glyph. If color glyphs must be drawn in
+// monochrome (e.g. for render layers with their own color), each
+// layer is drawn as a regular glyph instead.
+func (self *Renderer) colorGlyphDraw(target Target, index sfnt.GlyphIndex, origin fract.Point, colors *font.ColorGlyphs, layers []font.ColorLayer) {
+	if self.monochrome {
+		for _, layer := range layers {
+			mask := self.loadGlyphMask(layer.Glyph, origin)
+			self.defaultDrawFunc(target, origin, mask)
+		}
+		return
+	}
+
+	composed := self.colorLoadMask(index, origin, colors, layers)
+	self.drawColorMask(target, origin, composed)
+}
+
+// Loads the composed mask for the given color glyph, from the cache
+// if possible.
+func (self *Renderer) colorLoadMask(index sfnt.GlyphIndex, origin fract.Point, colors *font.ColorGlyphs, layers []font.ColorLayer) rgbaMask {
+	palette := self.colorPalette
+	if palette >= colors.NumPalettes() {
+		palette = 0
+	}
+	rgbaCacheHandler := self.rgbaCacheHandler()
+	if rgbaCacheHandler != nil {
+		self.colorRast.base = self.state.rasterizer
+		self.colorRast.palette = palette
+		self.colorRast.foreground = 0
+		if colors.UsesForeground() {
+			fg := color.RGBAModel.Convert(self.state.fontColor).(color.RGBA)
+			self.colorRast.foreground = uint32(fg.R)<<24 | uint32(fg.G)<<16 | uint32(fg.B)<<8 | uint32(fg.A)
+		}
+		rgbaCacheHandler.NotifyRasterizerChange(&self.colorRast)
+		defer rgbaCacheHandler.NotifyRasterizerChange(self.state.rasterizer)
+		colorMask, found := rgbaCacheHandler.GetRGBAMask(index)
+		if found {
+			return colorMask
+		}
+	}
+
+	// rasterize the layers
+	if sizeAware, ok := self.state.rasterizer.(mask.SizeAwareRasterizer); ok {
+		sizeAware.NotifySizeChange(self.state.scaledSize)
+	}
+	var bounds image.Rectangle
+	layerMasks := make([]*image.Alpha, len(layers))
+	for i, layer := range layers {
+		segments, err := self.glyphLoadSegments(layer.Glyph)
+		if err != nil {
+			panic("font.LoadGlyph(index = " + strconv.Itoa(int(layer.Glyph)) + ") error: " + err.Error())
+		}
+		layerMasks[i], err = mask.Rasterize(segments, self.state.rasterizer, origin)
+		if err != nil {
+			panic("RasterizeGlyphMask failed: " + err.Error())
+		}
+		if layerMasks[i] != nil {
+			bounds = bounds.Union(layerMasks[i].Rect)
+		}
+	}
+
+	// compose the layers in order
+	var colorMask rgbaMask
+	if !bounds.Empty() {
+		composed := image.NewRGBA(bounds)
+		for i, layer := range layers {
+			if layerMasks[i] != nil {
+				layerColor := self.colorLayerColor(colors, palette, layer)
+				composeColorLayer(composed, layerMasks[i], layerColor)
+			}
+		}
+		colorMask = convertRGBAImageToRGBAMask(composed)
+	}
+
+	if rgbaCacheHandler != nil {
+		rgbaCacheHandler.PassRGBAMask(index, colorMask)
+	}
+	return colorMask
+}
+
+// Returns the color for the given layer. Foreground layers and
+// layers with invalid palette entries use the text color.
+func (self *Renderer) colorLayerColor(colors *font.ColorGlyphs, palette int, layer font.ColorLayer) color.RGBA {
+	paletteColor, ok := colors.PaletteColor(palette, layer.PaletteEntry)
+	if !ok {
+		return color.RGBAModel.Convert(self.state.fontColor).(color.RGBA)
+	}
+	return color.RGBAModel.Convert(paletteColor).(color.RGBA)
+}
+
+// Draws the layer mask over the composed image with the given
+// premultiplied color.
+func composeColorLayer(composed *image.RGBA, layerMask *image.Alpha, layerColor color.RGBA) {
+	r, g, b, a := uint32(layerColor.R), uint32(layerColor.G), uint32(layerColor.B), uint32(layerColor.A)
+	rect := layerMask.Rect
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			level := uint32(layerMask.Pix[layerMask.PixOffset(x, y)])
+			if level == 0 {
+				continue
+			}
+			offset := composed.PixOffset(x, y)
+			pixel := composed.Pix[offset : offset+4]
+			inv := 255 - (a*level+127)/255
+			pixel[0] = uint8((r*level+127)/255 + (uint32(pixel[0])*inv+127)/255)
+			pixel[1] = uint8((g*level+127)/255 + (uint32(pixel[1])*inv+127)/255)
+			pixel[2] = uint8((b*level+127)/255 + (uint32(pixel[2])*inv+127)/255)
+			pixel[3] = uint8((a*level+127)/255 + (uint32(pixel[3])*inv+127)/255)
+		}
+	}
+}
+
+// ---- color glyph rasterizer ----
+
+// A rasterizer wrapper only used to give composed color glyph masks
+// a different cache signature, which depends on the palette and, if
+// relevant, the text color. Rasterization is delegated to the base.
+type colorGlyphRasterizer struct {
+	base       mask.Rasterizer
+	palette    int
+	foreground uint32 // packed RGBA text color, zero if not relevant
+}
+
+// Satisfies the [mask.Rasterizer] interface.
+func (self *colorGlyphRasterizer) Rasterize(outline sfnt.Segments, origin fract.Point) (*image.Alpha, error) {
+	return self.base.Rasterize(outline, origin)
+}
+
+// Satisfies the [mask.Rasterizer] interface.
+func (self *colorGlyphRasterizer) Signature() uint64 {
+	bits := colorGlyphSignatureBits | uint64(uint16(self.palette))<<32 | uint64(self.foreground)
+	return self.base.Signature() ^ bits
+}
+
+// Satisfies the [mask.Rasterizer] interface. Changes are never
+// notified, the renderer sets the signature explicitly before
+// loading color glyph masks.
+func (self *colorGlyphRasterizer) SetOnChangeFunc(func(mask.Rasterizer)) {}
@@ -0,0 +1,128 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tinne26/etxt/cache"
+	"github.com/tinne26/etxt/font"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Returns font data with only 'COLR' and 'CPAL' tables, making the given
+// glyph a color glyph with two layers: the first with palette entry 0 (red
+// or green), and the second with the text color.
+func testColorFontData(glyph, layerA, layerB sfnt.GlyphIndex) []byte {
+	colr := make([]byte, 14+6+8)
+	binary.BigEndian.PutUint16(colr[2:], 1)  // num base glyphs
+	binary.BigEndian.PutUint32(colr[4:], 14) // base glyphs offset
+	binary.BigEndian.PutUint32(colr[8:], 20) // layers offset
+	binary.BigEndian.PutUint16(colr[12:], 2) // num layers
+	binary.BigEndian.PutUint16(colr[14:], uint16(glyph))
+	binary.BigEndian.PutUint16(colr[18:], 2) // num layers for the glyph
+	binary.BigEndian.PutUint16(colr[20:], uint16(layerA))
+	binary.BigEndian.PutUint16(colr[24:], uint16(layerB))
+	binary.BigEndian.PutUint16(colr[26:], 0xFFFF)
+	cpal := []byte{
+		0, 0, 0, 1, 0, 2, 0, 2, // version, num entries, num palettes, num colors
+		0, 0, 0, 16, 0, 0, 0, 1, // colors offset, palette indices
+		0, 0, 255, 255, 0, 255, 0, 255, // BGRA red, BGRA green
+	}
+
+	return testFontData([]testTable{{"COLR", colr}, {"CPAL", cpal}})
+}
+
+func TestColorGlyphs(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	glyphCache := cache.NewDefaultCache(1024 * 1024) // (not shared with other tests)
+	renderer.SetCacheHandler(glyphCache.NewHandler())
+	renderer.SetFont(testFontA)
+	renderer.SetSize(32)
+	renderer.SetColor(color.RGBA{0, 0, 255, 255})
+	glyph := renderer.Glyph().GetRuneIndex('A')
+	layerA := renderer.Glyph().GetRuneIndex('O')
+	layerB := renderer.Glyph().GetRuneIndex('I')
+	colors, err := font.ParseColorGlyphs(testColorFontData(glyph, layerA, layerB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer.Glyph().SetColorGlyphs(testFontA, colors)
+
+	// counts the opaque pixels for the given colors
+	countColors := func(target *image.RGBA) map[color.RGBA]int {
+		counts := make(map[color.RGBA]int)
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				if rgba := target.RGBAAt(x, y); rgba.A == 255 {
+					counts[rgba] += 1
+				}
+			}
+		}
+		return counts
+	}
+	red, green := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	target := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	renderer.Draw(target, "A", 32, 32)
+	counts := countColors(target)
+	if counts[red] == 0 || counts[blue] == 0 || counts[green] != 0 {
+		t.Fatalf("unexpected color glyph pixels %v", counts)
+	}
+	if glyphCache.NumEntries() != 1 {
+		t.Fatalf("expected 1 cached mask, got %d", glyphCache.NumEntries())
+	}
+
+	// palette selection
+	renderer.Glyph().SetColorPalette(1)
+	target = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	renderer.Draw(target, "A", 32, 32)
+	counts = countColors(target)
+	if counts[green] == 0 || counts[blue] == 0 || counts[red] != 0 {
+		t.Fatalf("unexpected color glyph pixels %v with palette 1", counts)
+	}
+	if glyphCache.NumEntries() != 2 {
+		t.Fatalf("expected 2 cached masks, got %d", glyphCache.NumEntries())
+	}
+
+	// layers with their own color draw color glyphs in monochrome
+	white := color.RGBA{255, 255, 255, 255}
+	renderer.Layout().SetLayers(RenderLayer{Color: white})
+	target = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	renderer.Draw(target, "A", 32, 32)
+	counts = countColors(target)
+	if len(counts) != 1 || counts[white] == 0 {
+		t.Fatalf("unexpected monochrome glyph pixels %v", counts)
+	}
+	renderer.Layout().SetLayers()
+
+	// handlers without RGBA mask support never get composed masks
+	glyphCache = cache.NewDefaultCache(1024 * 1024)
+	renderer.SetCacheHandler(struct{ cache.GlyphCacheHandler }{glyphCache.NewHandler()})
+	target = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	renderer.Draw(target, "A", 32, 32)
+	counts = countColors(target)
+	if counts[green] == 0 || counts[blue] == 0 {
+		t.Fatalf("unexpected uncached color glyph pixels %v", counts)
+	}
+	if glyphCache.NumEntries() != 0 {
+		t.Fatalf("expected no cached masks, got %d", glyphCache.NumEntries())
+	}
+
+	// regular glyphs and removed color data
+	renderer.Glyph().SetColorGlyphs(testFontA, nil)
+	target = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	renderer.Draw(target, "A", 32, 32)
+	counts = countColors(target)
+	if len(counts) != 1 || counts[blue] == 0 {
+		t.Fatalf("unexpected regular glyph pixels %v", counts)
+	}
+}
@@ -11,6 +11,12 @@ import (
 // Missing glyphs in the current font will cause the renderer to panic.
 // See [RendererGlyph.GetRuneIndex]() for further advice if you need to
 // make your system more robust.
+//
+// Justified aligns only make sense with line wrapping, so here they
+// behave like [Left]. See [Renderer.DrawWithWrap]() instead.
+//
+// Tab characters ('\t') advance to the next tab stop. See
+// [RendererLayout.SetTabStops]() for details.
 func (self *Renderer) Draw(target Target, text string, x, y int) {
 	self.fractDraw(target, text, fract.FromInt(x), fract.FromInt(y))
 }
@@ -36,6 +42,13 @@ func (self *Renderer) fractDraw(target Target, text string, x, y fract.Unit) {
 		return
 	}
 
+	// use the general layout process if necessary
+	if self.layoutRequired(text) {
+		self.layoutString(&self.layout, text, false, 0)
+		self.layoutDraw(target, &self.layout, x, y)
+		return
+	}
+
 	// adjust Y position
 	horzQuant, vertQuant := self.fractGetQuantization()
 	lineHeight := self.getOpLineHeight()
@@ -22,6 +22,13 @@ func (self *Renderer) advanceLine(position fract.Point, lineBreakX fract.Unit, l
 func (self *Renderer) internalGlyphDraw(target Target, glyphIndex sfnt.GlyphIndex, origin fract.Point) {
 	if self.customDrawFn != nil {
 		self.customDrawFn(target, glyphIndex, origin)
+	} else if sdf, isSDF := self.sdfRasterizer(); isSDF {
+		self.sdfGlyphDraw(target, glyphIndex, origin, sdf)
+	} else if self.usingLCDRasterizer() {
+		lcdMask := self.loadLCDMask(glyphIndex, origin)
+		self.drawLCDMask(target, origin, lcdMask)
+	} else if colors, layers := self.colorGlyphLayers(glyphIndex); layers != nil {
+		self.colorGlyphDraw(target, glyphIndex, origin, colors, layers)
 	} else {
 		mask := self.loadGlyphMask(glyphIndex, origin)
 		self.defaultDrawFunc(target, origin, mask)
@@ -43,24 +50,6 @@ func (self *drawInternalValues) increaseLineBreakNth() {
 	self.lineBreakNth = maxInt(1, self.lineBreakNth+1)
 }
 
-func (self *drawInternalValues) updateLineChangeFromWrapMeasure(runeCount int, lastRune rune) {
-	self.lineChangeDetails.ElidedSpace = false
-	self.lineChangeDetails.IsWrap = true
-	if lastRune == ' ' {
-		self.lineChangeDetails.ElidedSpace = (runeCount > 1)
-	}
-	if lastRune == '\n' || lastRune == -1 {
-		self.lineChangeDetails.IsWrap = false
-	}
-}
-
-func (self *drawInternalValues) numElisions() int {
-	if self.lineChangeDetails.ElidedSpace {
-		return 1
-	}
-	return 0
-}
-
 func (self *Renderer) drawRuneLTR(target Target, position fract.Point, codePoint rune, iv drawInternalValues) (fract.Point, drawInternalValues) {
 	glyph, skip := self.getGlyphIndex(self.state.activeFont, codePoint)
 	if skip {
@@ -0,0 +1,58 @@
+package etxt
+
+import (
+	"github.com/tinne26/etxt/fract"
+)
+
+// Same as [Renderer.DrawWithWrap](), but limiting the text to the given
+// number of lines. If the text doesn't fit, part of it is replaced by an
+// ellipsis so that the result fits in maxLines. By default, the end of
+// the text is cut, but this can be changed through [RendererLayout.SetTruncation]().
+//
+// The ellipsis is drawn with the font's '…' glyph if available, or
+// as "..." otherwise. Spaces next to the ellipsis are also removed.
+// The returned [TruncationDetails] indicate which part of the text
+// was cut, if any.
+//
+// Like widthLimit, maxLines refers to the lines of the wrapped text,
+// so line breaks in the text count too. maxLines must be at least 1.
+func (self *Renderer) DrawWithTruncation(target Target, text string, x, y, widthLimit, maxLines int) TruncationDetails {
+	if widthLimit > fract.MaxInt {
+		panic("widthLimit too big, must be <= fract.MaxInt")
+	}
+	return self.fractDrawWithTruncation(target, text, fract.FromInt(x), fract.FromInt(y), fract.FromInt(widthLimit), maxLines)
+}
+
+// x and y are assumed to be unquantized
+func (self *Renderer) fractDrawWithTruncation(target Target, text string, x, y fract.Unit, widthLimit fract.Unit, maxLines int) TruncationDetails {
+	// preconditions
+	if target == nil {
+		panic("can't draw on nil Target")
+	}
+	if self.state.activeFont == nil {
+		panic("can't draw text with nil font (tip: Renderer.SetFont())")
+	}
+	if self.state.fontSizer == nil {
+		panic("can't draw with a nil sizer (tip: NewRenderer())")
+	}
+	if self.state.rasterizer == nil {
+		panic("can't draw with a nil rasterizer (tip: NewRenderer())")
+	}
+	if widthLimit < 0 {
+		panic("can't use a negative widthLimit")
+	}
+	if maxLines < 1 {
+		panic("maxLines must be at least 1")
+	}
+
+	// return directly on superfluous invocations
+	if text == "" {
+		return TruncationDetails{}
+	}
+
+	details := self.layoutStringTruncated(&self.layout, text, widthLimit, maxLines)
+	if !target.Bounds().Empty() {
+		self.layoutDraw(target, &self.layout, x, y)
+	}
+	return details
+}
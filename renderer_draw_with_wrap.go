@@ -5,8 +5,24 @@ import (
 )
 
 // Same as [Renderer.Draw](), but using a width limit for line wrapping.
-// The algorithm is a trivial greedy algorithm that only considers spaces
-// as line wrapping candidates.
+// Line wrapping candidates are determined with the Unicode Line Breaking
+// Algorithm (UAX #14): lines can be wrapped at spaces, after hyphens and
+// slashes, at zero width spaces and between most ideographs, but never
+// at non-breaking spaces. Soft hyphens (U+00AD) are invisible unless a
+// line is wrapped right after them, in which case a hyphen is shown.
+// Words that don't fit on a single line are split between characters,
+// unless a hyphenator is set with [RendererLayout.SetHyphenator](), in
+// which case words can also be split at hyphenation points.
+//
+// By default, the algorithm fits as many words as possible on each
+// line. See [RendererLayout.SetLineBreaking]() for alternatives.
+//
+// When the horizontal align is [Justify], the x coordinate is the left
+// edge of a box of widthLimit width, and wrapped lines are stretched to
+// fill it by distributing the extra width across inter-word spaces. Lines
+// ending a paragraph are aligned to the start of the box instead (left for
+// left-to-right text, right for right-to-left text), unless [JustifyAll]
+// is used. See also [RendererLayout.SetJustifyLetterSpacing]().
 //
 // The widthLimit must be given in real pixels, not logical units.
 // This means that unlike text sizes, the widthLimit won't be internally
@@ -43,296 +59,8 @@ func (self *Renderer) fractDrawWithWrap(target Target, text string, x, y fract.U
 		return
 	}
 
-	// adjust Y position
-	horzQuant, vertQuant := self.fractGetQuantization()
-	lineHeight := self.getOpLineHeight()
-	vertAlign := self.state.align.Vert()
-	switch vertAlign {
-	case VertCenter:
-		height := self.fractMeasureWithWrap(text, widthLimit).Height()
-		y = (y + self.getOpAscent() - (height >> 1)).QuantizeUp(vertQuant)
-	case LastBaseline:
-		height := self.fractMeasureWithWrap(text, widthLimit).Height()
-		qtLineHeight := lineHeight.QuantizeUp(vertQuant)
-		if height >= qtLineHeight {
-			height -= qtLineHeight
-		}
-		y = (y - height).QuantizeUp(vertQuant)
-	case Bottom:
-		height := self.fractMeasureWithWrap(text, widthLimit).Height()
-		y = (y + self.getOpAscent() - height).QuantizeUp(vertQuant)
-	default:
-		y = (y + self.getBaselineOffset(vertAlign)).QuantizeUp(vertQuant)
-	}
-
-	// Note: skipping text portions based on visibility can be a
-	// problem when using custom draw and line break functions,
-	// so I'm temporarily suspending the optimization
-
-	// skip non-visible portions of the text in the target
-	// minBaselineY := fract.FromInt(bounds.Min.Y) - lineHeight
-	// maxBaselineY := fract.FromInt(bounds.Max.Y) + lineHeight
-	// var lineBreakNth int
-	// text, lineBreakNth = self.trimNonVisibleWithWrap(text, widthLimit, y, minBaselineY)
-	// if text == "" {
-	// 	return
-	// }
-
-	// subdelegate to the relevant function
-	x = x.QuantizeUp(horzQuant)
-	switch self.state.align.Horz() {
-	case Left:
-		if self.state.textDirection == LeftToRight {
-			self.fractDrawWithWrapLeftLTR(target, text, x, y, widthLimit)
-		} else {
-			self.fractDrawWithWrapLeftRTL(target, text, x, y, widthLimit)
-		}
-	case Right:
-		if self.state.textDirection == LeftToRight {
-			self.fractDrawWithWrapRightLTR(target, text, x, y, widthLimit)
-		} else {
-			self.fractDrawWithWrapRightRTL(target, text, x, y, widthLimit)
-		}
-	case HorzCenter:
-		if self.state.textDirection == LeftToRight {
-			self.fractDrawWithWrapCenterLTR(target, text, x, y, widthLimit)
-		} else {
-			self.fractDrawWithWrapCenterRTL(target, text, x, y, widthLimit)
-		}
-	default:
-		panic(self.state.align.Horz())
-	}
-}
-
-// Precondition: x and y are quantized.
-func (self *Renderer) fractDrawWithWrapLeftLTR(target Target, text string, x, y, widthLimit fract.Unit) {
-	// set up traversal variables
-	var iterator ltrStringIterator
-	var position fract.Point = fract.UnitsToPoint(x, y)
-	startX := position.X
-	if self.cacheHandler != nil {
-		self.cacheHandler.NotifyFractChange(position)
-	}
-
-	var iv drawInternalValues
-	iv.prevFractX = x.FractShift()
-	iv.lineBreakNth = -1
-	for { // for each wrap line
-		// Notice: this approach is not optimal. One could write a helperDrawWrapLineLTR
-		//         directly. Same goes for the other 3 horz align + text dir variants.
-		_, _, runeCount, lastRune := self.helperMeasureWrapLineLTR(iterator, text, widthLimit)
-		iv.updateLineChangeFromWrapMeasure(runeCount, lastRune)
-		position, iv, iterator = self.helperDrawLineLTR(target, position, iv, iterator, text, runeCount-iv.numElisions())
-		if iv.lineChangeDetails.ElidedSpace {
-			if iterator.Next(text) != ' ' {
-				panic("broken code")
-			}
-		}
-
-		// stop here or advance line
-		if lastRune == -1 {
-			break
-		}
-		if lastRune == '\n' {
-			_ = iterator.Next(text)
-		}
-		iv.increaseLineBreakNth()
-		position = self.advanceLine(position, startX, iv.lineBreakNth)
-		if self.lineChangeFn != nil {
-			self.lineChangeFn(iv.lineChangeDetails)
-		}
-	}
-}
-
-// Precondition: x and y are quantized.
-func (self *Renderer) fractDrawWithWrapLeftRTL(target Target, text string, x, y, widthLimit fract.Unit) {
-	// set up traversal variables
-	var iterator ltrStringIterator
-	var position fract.Point = fract.UnitsToPoint(x, y)
-	startX := position.X
-	if self.cacheHandler != nil {
-		self.cacheHandler.NotifyFractChange(position)
-	}
-
-	var iv drawInternalValues
-	iv.prevFractX = x.FractShift()
-	iv.lineBreakNth = -1
-	for { // for each wrap line
-		_, lineWidth, runeCount, lastRune := self.helperMeasureWrapLineReverseLTR(iterator, text, widthLimit)
-		iv.updateLineChangeFromWrapMeasure(runeCount, lastRune)
-		position.X = x + lineWidth
-		position, iv, iterator = self.helperDrawLineReverseLTR(target, position, iv, iterator, text, runeCount-iv.numElisions())
-		if iv.lineChangeDetails.ElidedSpace {
-			if iterator.Next(text) != ' ' {
-				panic("broken code")
-			}
-		}
-
-		// stop here or advance line
-		if lastRune == -1 {
-			break
-		}
-		if lastRune == '\n' {
-			_ = iterator.Next(text)
-		}
-		iv.increaseLineBreakNth()
-		position = self.advanceLine(position, startX, iv.lineBreakNth)
-		if self.lineChangeFn != nil {
-			self.lineChangeFn(iv.lineChangeDetails)
-		}
-	}
-}
-
-// Precondition: x and y are already quantized.
-func (self *Renderer) fractDrawWithWrapRightLTR(target Target, text string, x, y, widthLimit fract.Unit) {
-	// set up traversal variables
-	var iterator ltrStringIterator
-	var position fract.Point = fract.UnitsToPoint(x, y)
-	startX := position.X
-	if self.cacheHandler != nil {
-		self.cacheHandler.NotifyFractChange(position)
-	}
-
-	var iv drawInternalValues
-	iv.prevFractX = x.FractShift()
-	iv.lineBreakNth = -1
-	for { // for each wrap line
-		_, lineWidth, runeCount, lastRune := self.helperMeasureWrapLineLTR(iterator, text, widthLimit)
-		iv.updateLineChangeFromWrapMeasure(runeCount, lastRune)
-		position.X = startX - lineWidth
-		position, iv, iterator = self.helperDrawLineLTR(target, position, iv, iterator, text, runeCount-iv.numElisions())
-		if iv.lineChangeDetails.ElidedSpace {
-			if iterator.Next(text) != ' ' {
-				panic("broken code")
-			}
-		}
-
-		// stop here or advance line
-		if lastRune == -1 {
-			break
-		}
-		if lastRune == '\n' {
-			_ = iterator.Next(text)
-		}
-		iv.increaseLineBreakNth()
-		position = self.advanceLine(position, startX, iv.lineBreakNth)
-		if self.lineChangeFn != nil {
-			self.lineChangeFn(iv.lineChangeDetails)
-		}
-	}
-}
-
-// Precondition: x and y are already quantized.
-func (self *Renderer) fractDrawWithWrapRightRTL(target Target, text string, x, y, widthLimit fract.Unit) {
-	// set up traversal variables
-	var iterator ltrStringIterator
-	var position fract.Point = fract.UnitsToPoint(x, y)
-	startX := position.X
-	if self.cacheHandler != nil {
-		self.cacheHandler.NotifyFractChange(position)
-	}
-
-	var iv drawInternalValues
-	iv.prevFractX = x.FractShift()
-	iv.lineBreakNth = -1
-	for { // for each wrap line
-		_, _, runeCount, lastRune := self.helperMeasureWrapLineReverseLTR(iterator, text, widthLimit)
-		iv.updateLineChangeFromWrapMeasure(runeCount, lastRune)
-		position, iv, iterator = self.helperDrawLineReverseLTR(target, position, iv, iterator, text, runeCount-iv.numElisions())
-		if iv.lineChangeDetails.ElidedSpace {
-			if iterator.Next(text) != ' ' {
-				panic("broken code")
-			}
-		}
-
-		// stop here or advance line
-		if lastRune == -1 {
-			break
-		}
-		if lastRune == '\n' {
-			_ = iterator.Next(text)
-		}
-		iv.increaseLineBreakNth()
-		position = self.advanceLine(position, startX, iv.lineBreakNth)
-		if self.lineChangeFn != nil {
-			self.lineChangeFn(iv.lineChangeDetails)
-		}
-	}
-}
-
-// Precondition: y is already quantized, x is not (for better precision).
-func (self *Renderer) fractDrawWithWrapCenterLTR(target Target, text string, x, y, widthLimit fract.Unit) {
-	// set up traversal variables
-	var iterator ltrStringIterator
-	var position fract.Point = fract.UnitsToPoint(x, y)
-	if self.cacheHandler != nil {
-		self.cacheHandler.NotifyFractChange(position)
-	}
-
-	var iv drawInternalValues
-	iv.prevFractX = x.FractShift()
-	iv.lineBreakNth = -1
-	for { // for each wrap line
-		_, lineWidth, runeCount, lastRune := self.helperMeasureWrapLineLTR(iterator, text, widthLimit)
-		iv.updateLineChangeFromWrapMeasure(runeCount, lastRune)
-		position.X = x - (lineWidth >> 1)
-		_, iv, iterator = self.helperDrawLineLTR(target, position, iv, iterator, text, runeCount-iv.numElisions())
-		if iv.lineChangeDetails.ElidedSpace {
-			if iterator.Next(text) != ' ' {
-				panic("broken code")
-			}
-		}
-
-		// stop here or advance line
-		if lastRune == -1 {
-			break
-		}
-		if lastRune == '\n' {
-			_ = iterator.Next(text)
-		}
-
-		iv.increaseLineBreakNth()
-		position = self.advanceLine(position, 0, iv.lineBreakNth)
-		if self.lineChangeFn != nil {
-			self.lineChangeFn(iv.lineChangeDetails)
-		}
-	}
-}
-
-// Precondition: y is already quantized, x is not (for better precision).
-func (self *Renderer) fractDrawWithWrapCenterRTL(target Target, text string, x, y, widthLimit fract.Unit) {
-	// set up traversal variables
-	var iterator ltrStringIterator
-	var position fract.Point = fract.UnitsToPoint(x, y)
-	if self.cacheHandler != nil {
-		self.cacheHandler.NotifyFractChange(position)
-	}
-
-	var iv drawInternalValues
-	iv.prevFractX = x.FractShift()
-	iv.lineBreakNth = -1
-	for { // for each wrap line
-		_, lineWidth, runeCount, lastRune := self.helperMeasureWrapLineReverseLTR(iterator, text, widthLimit)
-		iv.updateLineChangeFromWrapMeasure(runeCount, lastRune)
-		position.X = x + (lineWidth >> 1)
-		position, iv, iterator = self.helperDrawLineReverseLTR(target, position, iv, iterator, text, runeCount-iv.numElisions())
-		if iv.lineChangeDetails.ElidedSpace {
-			if iterator.Next(text) != ' ' {
-				panic("broken code")
-			}
-		}
-
-		// stop here or advance line
-		if lastRune == -1 {
-			break
-		}
-		if lastRune == '\n' {
-			_ = iterator.Next(text)
-		}
-		iv.increaseLineBreakNth()
-		position = self.advanceLine(position, 0, iv.lineBreakNth)
-		if self.lineChangeFn != nil {
-			self.lineChangeFn(iv.lineChangeDetails)
-		}
-	}
+	// wrapping always goes through the general layout process,
+	// as line break opportunities can't be found in a single pass
+	self.layoutString(&self.layout, text, true, widthLimit)
+	self.layoutDraw(target, &self.layout, x, y)
 }
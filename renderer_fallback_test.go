@@ -0,0 +1,78 @@
+package etxt
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestFallbackFonts(t *testing.T) {
+	if testFontA == nil || testFontB == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Utils().SetCache8MiB()
+
+	// fallbacks must not affect text already covered by the main font
+	texts := []string{"hello world", "\n\nhey\n", "hello world hello world\ngoodbye", "AV.AV"}
+	for _, qt := range []fract.Unit{QtFull, Qt4th, QtNone} {
+		for _, dir := range []Direction{LeftToRight, RightToLeft} {
+			renderer.Fract().SetHorzQuantization(qt)
+			renderer.SetDirection(dir)
+			for _, text := range texts {
+				renderer.Glyph().SetFallbackFonts()
+				r1, w1 := renderer.Measure(text), renderer.MeasureWithWrap(text, 60)
+				renderer.Glyph().SetFallbackFonts(testFontB)
+				r2, w2 := renderer.Measure(text), renderer.MeasureWithWrap(text, 60)
+				if r1 != r2 || w1 != w2 {
+					t.Fatalf("text %q: expected %v and %v, got %v and %v", text, r1, w1, r2, w2)
+				}
+			}
+		}
+	}
+
+	// code points not covered by any font must go through the miss handler
+	renderer.Glyph().SetMissHandler(OnMissSkip)
+	if renderer.Measure("a\U0010FFFDb") != renderer.Measure("ab") {
+		t.Fatal("expected missing code point to be skipped")
+	}
+	renderer.Glyph().SetMissHandler(nil)
+
+	// use a main font that only covers 'a', so 'b' must come from the fallback
+	mainFont, err := sfnt.Parse(testMinimalFontData('a', 500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer.SetFont(testFontA)
+	renderer.SetSize(16)
+	renderer.Fract().SetHorzQuantization(QtNone)
+	renderer.SetDirection(LeftToRight)
+	fallbackIndex := renderer.Glyph().GetRuneIndex('b')
+	fallbackWidth := renderer.Measure("b").Width()
+	renderer.SetFont(mainFont)
+	renderer.Glyph().SetFallbackFonts(testFontA)
+	rect := renderer.Measure("ab")
+
+	// glyph index and advance from the fallback font
+	fallbackRune := renderer.layout.runes[1]
+	if renderer.layout.styles[fallbackRune.style].font != testFontA {
+		t.Fatal("expected fallback font for 'b'")
+	}
+	if fallbackRune.glyph != fallbackIndex {
+		t.Fatalf("expected fallback glyph index %d, got %d", fallbackIndex, fallbackRune.glyph)
+	}
+	if rect.Width() != 8*64+fallbackWidth {
+		t.Fatalf("expected width %d, got %d", 8*64+fallbackWidth, rect.Width())
+	}
+
+	// line metrics from the main font
+	if rect.Height() != 16*64 {
+		t.Fatalf("expected main font line height %d, got %d", 16*64, rect.Height())
+	}
+	if renderer.GetFont() != mainFont {
+		t.Fatal("renderer font modified by fallback")
+	}
+}
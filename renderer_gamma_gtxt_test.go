@@ -0,0 +1,44 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+)
+
+func TestStemDarkeningSize(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.Utils().SetCache8MiB()
+	renderer.SetFont(testFontA)
+	index := renderer.Glyph().GetRuneIndex('l')
+
+	// returns the total coverage of the glyph mask at the given size
+	coverage := func(size float64) int {
+		renderer.SetSize(size)
+		var total int
+		for _, alpha := range renderer.Glyph().LoadMask(index, fract.Point{}).Pix {
+			total += int(alpha)
+		}
+		return total
+	}
+
+	refSmall, refBig := coverage(12), coverage(32)
+	var gamma mask.GammaRasterizer
+	gamma.SetStemDarkening(1)
+	gamma.SetStemDarkeningRange(14, 24)
+	renderer.Glyph().SetRasterizer(&gamma)
+	small, big := coverage(12), coverage(32)
+	if small <= refSmall {
+		t.Fatalf("expected stem darkening at small sizes (%d vs %d)", small, refSmall)
+	}
+	if big != refBig {
+		t.Fatalf("expected no stem darkening at big sizes (%d vs %d)", big, refBig)
+	}
+}
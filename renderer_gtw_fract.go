@@ -109,6 +109,16 @@ func (self *RendererFract) DrawWithWrap(target Target, text string, x, y fract.U
 	(*Renderer)(self).fractDrawWithWrap(target, text, x, y, fract.FromInt(widthLimit))
 }
 
+// Fractional and lower level version of [Renderer.DrawWithTruncation]().
+func (self *RendererFract) DrawWithTruncation(target Target, text string, x, y fract.Unit, widthLimit, maxLines int) TruncationDetails {
+	return (*Renderer)(self).fractDrawWithTruncation(target, text, x, y, fract.FromInt(widthLimit), maxLines)
+}
+
+// Fractional and lower level version of [Renderer.DrawTextBlock]().
+func (self *RendererFract) DrawTextBlock(target Target, block *TextBlock, x, y fract.Unit) {
+	(*Renderer)(self).fractDrawTextBlock(target, block, x, y)
+}
+
 // ---- underlying implementations ----
 
 func (self *Renderer) fractSetSize(size fract.Unit) {
@@ -1,6 +1,7 @@
 package etxt
 
 import (
+	"github.com/tinne26/etxt/font"
 	"github.com/tinne26/etxt/fract"
 	"github.com/tinne26/etxt/mask"
 	"golang.org/x/image/font/sfnt"
@@ -44,6 +45,10 @@ func (self *RendererGlyph) DrawMask(target Target, mask GlyphMask, origin fract.
 // Loads a glyph mask. This is a very low level function, almost only
 // relevant if you are trying to implement custom draw functions for
 // [RendererGlyph.SetDrawFunc]().
+//
+// Subpixel masks can't be represented as glyph masks, so if the
+// rasterizer is a [mask.LCDRasterizer], the mask is rasterized with
+// its wrapped rasterizer instead.
 func (self *RendererGlyph) LoadMask(index sfnt.GlyphIndex, origin fract.Point) GlyphMask {
 	return (*Renderer)(self).glyphLoadMask(index, origin)
 }
@@ -81,9 +86,14 @@ func (self *RendererGlyph) SetDrawFunc(drawFn func(Target, sfnt.GlyphIndex, frac
 }
 
 // Helper type for [RendererGlyph.SetLineChangeFunc]().
+//
+// InsertedHyphen indicates that a hyphen glyph that's not part of the
+// original text was drawn at the end of the line, as a result of word
+// hyphenation (see [RendererLayout.SetHyphenator]()).
 type LineChangeDetails struct {
-	IsWrap      bool
-	ElidedSpace bool
+	IsWrap         bool
+	ElidedSpace    bool
+	InsertedHyphen bool
 }
 
 // Sets a function to be called when processing a line break during draw
@@ -112,6 +122,109 @@ func (self *RendererGlyph) SetMissHandler(missHandler func(*sfnt.Font, rune) (sf
 	self.missHandlerFn = missHandler
 }
 
+// Sets an ordered list of fallback fonts to be used when the current
+// font doesn't have a glyph for a given code point. Each code point
+// is drawn and measured with the first font that covers it. If none
+// of them do, the miss handler is used as usual. See also
+// [RendererUtils.SetFallbackFontsFromLibrary]().
+//
+// Line metrics (ascent, line height, etc.) always come from the
+// main font, while glyph advances and kerning come from the font
+// actually used for each glyph. Fallback fonts are not applied to
+// [Feed] operations, which work at the glyph index level.
+//
+// Passing no fonts clears the fallback list.
+func (self *RendererGlyph) SetFallbackFonts(fonts ...*sfnt.Font) {
+	(*Renderer)(self).glyphSetFallbackFonts(fonts)
+}
+
+// Returns the fallback fonts set with [RendererGlyph.SetFallbackFonts]().
+// The returned slice must not be modified.
+func (self *RendererGlyph) GetFallbackFonts() []*sfnt.Font {
+	return self.fallbackFonts
+}
+
+// Sets the vertical metrics to be used for the given font with
+// [TopToBottom] text. [sfnt.Font] doesn't expose the 'vhea' and 'vmtx'
+// tables, so vertical metrics have to be parsed from the raw font data
+// with [font.ParseVertMetrics](). Without them, upright glyphs advance
+// by the font's ascent + descent and are placed with their ascent at
+// the top of the advance, which works well for most CJK fonts anyway.
+//
+// Passing nil metrics removes the vertical metrics for the given font.
+//
+// [font.ParseVertMetrics]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10/font#ParseVertMetrics
+func (self *RendererGlyph) SetVertMetrics(font *sfnt.Font, metrics *font.VertMetrics) {
+	(*Renderer)(self).glyphSetVertMetrics(font, metrics)
+}
+
+// Returns the vertical metrics set for the given font with
+// [RendererGlyph.SetVertMetrics](), or nil if none.
+func (self *RendererGlyph) GetVertMetrics(font *sfnt.Font) *font.VertMetrics {
+	return (*Renderer)(self).vertMetrics[font]
+}
+
+// Sets the color glyph data to be used for the given font. [sfnt.Font]
+// doesn't expose the 'COLR' and 'CPAL' tables, so color glyph data has
+// to be parsed from the raw font data with [font.ParseColorGlyphs]().
+// Without it, color glyphs are drawn as regular glyphs, which in most
+// color fonts means a monochrome silhouette or an empty glyph.
+//
+// Color glyphs are rasterized layer by layer with the current
+// rasterizer, composed with the palette colors and cached as a single
+// mask. See also [RendererGlyph.SetColorPalette]().
+//
+// Passing nil removes the color glyph data for the given font.
+//
+// [font.ParseColorGlyphs]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10/font#ParseColorGlyphs
+func (self *RendererGlyph) SetColorGlyphs(font *sfnt.Font, colors *font.ColorGlyphs) {
+	(*Renderer)(self).glyphSetColorGlyphs(font, colors)
+}
+
+// Returns the color glyph data set for the given font with
+// [RendererGlyph.SetColorGlyphs](), or nil if none.
+func (self *RendererGlyph) GetColorGlyphs(font *sfnt.Font) *font.ColorGlyphs {
+	return (*Renderer)(self).colorGlyphs[font]
+}
+
+// Sets the glyph positioning data to be used for the given font.
+// [sfnt.Font] doesn't expose the 'GPOS' table, so positioning data has
+// to be parsed from the raw font data with [font.ParseGlyphPositioning]().
+//
+// The renderer uses the pair adjustments for kerning, taking precedence
+// over the sizer's kerning for the glyph pairs they cover, and the mark
+// attachments to place combining marks (e.g. diacritics) on top of the
+// preceding base glyphs or marks, instead of drawing them after the base.
+// Attached marks don't advance. Mark attachment only works with horizontal
+// text.
+//
+// Passing nil removes the positioning data for the given font.
+//
+// [font.ParseGlyphPositioning]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10/font#ParseGlyphPositioning
+func (self *RendererGlyph) SetPositioning(font *sfnt.Font, positioning *font.GlyphPositioning) {
+	(*Renderer)(self).glyphSetPositioning(font, positioning)
+}
+
+// Returns the glyph positioning data set for the given font with
+// [RendererGlyph.SetPositioning](), or nil if none.
+func (self *RendererGlyph) GetPositioning(font *sfnt.Font) *font.GlyphPositioning {
+	return (*Renderer)(self).positioning[font]
+}
+
+// Sets the index of the palette used for color glyphs. Most color
+// fonts only have one palette, but some include alternative palettes
+// (e.g. for dark backgrounds). If the index is out of range for a
+// font, the first palette is used. The default is 0.
+func (self *RendererGlyph) SetColorPalette(index int) {
+	(*Renderer)(self).glyphSetColorPalette(index)
+}
+
+// Returns the index of the palette used for color glyphs.
+// See [RendererGlyph.SetColorPalette]().
+func (self *RendererGlyph) GetColorPalette() int {
+	return (*Renderer)(self).colorPalette
+}
+
 // Obtains the glyph index for the given rune in the current renderer's
 // font. This method returns 0 if the glyph mapping doesn't exist. The
 // [RendererGlyph.SetMissHandler]() configuration is not considered here.
@@ -145,6 +258,12 @@ func (self *RendererGlyph) CacheIndex(index sfnt.GlyphIndex) {
 }
 
 // Sets the glyph mask rasterizer to be used on subsequent operations.
+//
+// A [mask.SDFRasterizer] enables a special draw path, where cached
+// distance fields are scaled to the current size while drawing, and
+// a [mask.LCDRasterizer] enables subpixel antialiasing, where masks
+// are blended per channel. See the rasterizers' documentation for
+// more details.
 func (self *RendererGlyph) SetRasterizer(rasterizer mask.Rasterizer) {
 	(*Renderer)(self).glyphSetRasterizer(rasterizer)
 }
@@ -213,6 +332,83 @@ func (self *Renderer) glyphCacheIndex(index sfnt.GlyphIndex) {
 	}
 }
 
+func (self *Renderer) glyphSetFallbackFonts(fonts []*sfnt.Font) {
+	if len(fonts) == 0 {
+		self.fallbackFonts = nil
+		return
+	}
+	for _, font := range fonts {
+		if font == nil {
+			panic("nil fallback font")
+		}
+	}
+	self.fallbackFonts = append(self.fallbackFonts[:0], fonts...)
+}
+
+func (self *Renderer) glyphSetVertMetrics(sfntFont *sfnt.Font, metrics *font.VertMetrics) {
+	if sfntFont == nil {
+		panic("can't set vertical metrics for a nil font")
+	}
+	if metrics == nil {
+		delete(self.vertMetrics, sfntFont)
+		return
+	}
+	if self.vertMetrics == nil {
+		self.vertMetrics = make(map[*sfnt.Font]*font.VertMetrics)
+	}
+	self.vertMetrics[sfntFont] = metrics
+}
+
+func (self *Renderer) glyphSetColorGlyphs(sfntFont *sfnt.Font, colors *font.ColorGlyphs) {
+	if sfntFont == nil {
+		panic("can't set color glyphs for a nil font")
+	}
+	if colors == nil {
+		delete(self.colorGlyphs, sfntFont)
+		return
+	}
+	if self.colorGlyphs == nil {
+		self.colorGlyphs = make(map[*sfnt.Font]*font.ColorGlyphs)
+	}
+	self.colorGlyphs[sfntFont] = colors
+}
+
+func (self *Renderer) glyphSetPositioning(sfntFont *sfnt.Font, positioning *font.GlyphPositioning) {
+	if sfntFont == nil {
+		panic("can't set positioning data for a nil font")
+	}
+	if positioning == nil {
+		delete(self.positioning, sfntFont)
+		return
+	}
+	if self.positioning == nil {
+		self.positioning = make(map[*sfnt.Font]*font.GlyphPositioning)
+	}
+	self.positioning[sfntFont] = positioning
+}
+
+func (self *Renderer) glyphSetColorPalette(index int) {
+	if index < 0 {
+		panic("negative color palette index")
+	}
+	self.colorPalette = index
+}
+
+// Returns the first fallback font containing a glyph for the given
+// code point, or nil if none.
+func (self *Renderer) glyphFindFallbackFont(codePoint rune) *sfnt.Font {
+	for _, font := range self.fallbackFonts {
+		index, err := font.GlyphIndex(&self.buffer, codePoint)
+		if err != nil {
+			panic("font.GlyphIndex error: " + err.Error())
+		}
+		if index != 0 {
+			return font
+		}
+	}
+	return nil
+}
+
 func (self *Renderer) glyphGetRasterizer() mask.Rasterizer {
 	return self.state.rasterizer
 }
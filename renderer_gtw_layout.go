@@ -0,0 +1,342 @@
+package etxt
+
+import (
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/hyphen"
+)
+
+// This type exists only for documentation and structuring purposes,
+// acting as a [gateway] to configure advanced text layout options.
+//
+// In general, this type is used through method chaining:
+//
+//	renderer.Layout().SetJustifyLetterSpacing(2)
+//
+// Layout options are not part of the renderer's restorable state,
+// so they are not affected by [RendererUtils.StoreState]() and
+// [RendererUtils.RestoreState]().
+//
+// [gateway]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10#Renderer
+type RendererLayout Renderer
+
+// [Gateway] to [RendererLayout] functionality.
+//
+// [Gateway]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10#Renderer
+func (self *Renderer) Layout() *RendererLayout {
+	return (*RendererLayout)(self)
+}
+
+// Sets the maximum spacing that can be added between letters when
+// justifying a line that has no inter-word spaces, with [Justify] or
+// [JustifyAll] aligns. The spacing is given in logical pixels, so it's
+// multiplied by the renderer's scale, and it's rounded down to the
+// horizontal quantization. The default value is zero, which disables
+// letter spacing and leaves single word lines unjustified.
+func (self *RendererLayout) SetJustifyLetterSpacing(maxSpacing float64) {
+	if maxSpacing < 0 {
+		panic("negative letter spacing")
+	}
+	self.layoutOptions.justifyLetterSpacing = fract.FromFloat64Up(maxSpacing)
+}
+
+// Returns the maximum justification letter spacing. See
+// [RendererLayout.SetJustifyLetterSpacing]() for more details.
+func (self *RendererLayout) GetJustifyLetterSpacing() float64 {
+	return self.layoutOptions.justifyLetterSpacing.ToFloat64()
+}
+
+// Sets the line breaking strategy to be used by [Renderer.DrawWithWrap](),
+// [Renderer.MeasureWithWrap]() and their twine and fract variants.
+//
+// The default is [LineBreakGreedy], which fills each line with as many
+// words as possible before moving on to the next one. This is fast and
+// predictable, but can leave some lines much shorter than others.
+// [LineBreakOptimal] instead considers the whole paragraph at once and
+// picks the line breaks that minimize the total raggedness, like the
+// Knuth-Plass algorithm used in TeX. This is slower, but often leads to
+// much more even paragraphs, especially with [Justify] aligns. See also
+// [RendererLayout.SetLineBreakingParams]().
+func (self *RendererLayout) SetLineBreaking(lineBreaking LineBreaking) {
+	if lineBreaking > LineBreakOptimal {
+		panic("invalid line breaking strategy")
+	}
+	self.layoutOptions.lineBreaking = lineBreaking
+}
+
+// Returns the current line breaking strategy. See
+// [RendererLayout.SetLineBreaking]() for more details.
+func (self *RendererLayout) GetLineBreaking() LineBreaking {
+	return self.layoutOptions.lineBreaking
+}
+
+// Sets the parameters used to evaluate line breaks with [LineBreakOptimal]:
+//   - The linePenalty is added to the badness of each line. Higher
+//     values favor using fewer lines, even if they are more uneven.
+//     The default value is 10.
+//   - The tolerance is the fraction of the width limit that a line
+//     can leave unused while still being considered acceptable. The
+//     badness of a line is 100 when its unused width matches the
+//     tolerance, and it grows cubically from there. The default value
+//     is 0.1.
+//
+// Last lines in a paragraph are never penalized for being short.
+func (self *RendererLayout) SetLineBreakingParams(linePenalty, tolerance float64) {
+	if linePenalty < 0 {
+		panic("negative line penalty")
+	}
+	if tolerance <= 0 {
+		panic("line breaking tolerance must be strictly positive")
+	}
+	self.layoutOptions.linePenalty = linePenalty
+	self.layoutOptions.tolerance = tolerance
+}
+
+// Returns the line penalty and tolerance parameters. See
+// [RendererLayout.SetLineBreakingParams]() for more details.
+func (self *RendererLayout) GetLineBreakingParams() (linePenalty, tolerance float64) {
+	return self.layoutOptions.linePenalty, self.layoutOptions.tolerance
+}
+
+// Sets the hyphenator to be used by [Renderer.DrawWithWrap](),
+// [Renderer.MeasureWithWrap]() and their twine and fract variants
+// in order to allow wrapping lines within words. When a line is
+// wrapped at a hyphenation point, the font's hyphen glyph ('-') is
+// drawn at the end of the line, and the line change is reported
+// with [LineChangeDetails].InsertedHyphen.
+//
+// Hyphenation is disabled by default (nil hyphenator). Since
+// hyphenation is language specific, you will typically use a
+// different [hyphen.Patterns] for each language.
+func (self *RendererLayout) SetHyphenator(hyphenator hyphen.Hyphenator) {
+	self.layoutOptions.hyphenator = hyphenator
+}
+
+// Returns the current hyphenator, which may be nil. See
+// [RendererLayout.SetHyphenator]() for more details.
+func (self *RendererLayout) GetHyphenator() hyphen.Hyphenator {
+	return self.layoutOptions.hyphenator
+}
+
+// Sets the part of the text to be replaced by an ellipsis when it
+// doesn't fit in the space given to [Renderer.DrawWithTruncation]()
+// or [Renderer.MeasureWithTruncation](). The default is [TruncateEnd].
+func (self *RendererLayout) SetTruncation(truncation Truncation) {
+	if truncation > TruncateMiddle {
+		panic("invalid truncation mode")
+	}
+	self.layoutOptions.truncation = truncation
+}
+
+// Returns the current truncation mode. See
+// [RendererLayout.SetTruncation]() for more details.
+func (self *RendererLayout) GetTruncation() Truncation {
+	return self.layoutOptions.truncation
+}
+
+// Sets a byte range [start, end) of the text to be drawn with the given
+// color instead of the renderer's color. This can be used to draw text
+// selections and highlights in a single pass. The range applies to all
+// draw operations, including twines, until it's changed again. Passing
+// a nil color disables highlighting, which is the default.
+//
+// Highlighting only changes the text color. For background rects, see
+// [Renderer.RangeRects]().
+func (self *RendererLayout) SetHighlight(start, end int, textColor color.Color) {
+	if textColor != nil && start > end {
+		panic("highlight start must be <= end")
+	}
+	self.layoutOptions.highlight = layoutHighlight{start: start, end: end, color: textColor}
+}
+
+// Returns the current highlight range and color. See
+// [RendererLayout.SetHighlight]() for more details.
+func (self *RendererLayout) GetHighlight() (start, end int, textColor color.Color) {
+	highlight := &self.layoutOptions.highlight
+	return highlight.start, highlight.end, highlight.color
+}
+
+// Sets explicit tab stops for tab characters ('\t'). Each tab advances
+// to the first stop after the current position, and the text after it
+// is aligned to the stop as indicated by [TabStop].Align. Past the last
+// explicit stop, tabs advance to the next multiple of the tab interval
+// (see [RendererLayout.SetTabInterval]()) with [TabLeft] align.
+//
+// Tab stops are honored by all the draw and measure functions, including
+// twines and wrapped text (wrapping is possible after tabs), but [Feed]
+// can't look ahead, so tabs always behave as [TabLeft] there. Passing
+// no stops clears them, which is the default.
+func (self *RendererLayout) SetTabStops(stops ...TabStop) {
+	if len(stops) == 0 {
+		self.layoutOptions.tabStops = nil
+		return
+	}
+	for _, stop := range stops {
+		if stop.Position < 0 || math.IsNaN(stop.Position) || math.IsInf(stop.Position, 1) {
+			panic("invalid tab stop position")
+		}
+		if stop.Align > TabDecimal {
+			panic("invalid tab stop align")
+		}
+	}
+
+	// (a new slice is always allocated, so options remain comparable)
+	sorted := make([]TabStop, len(stops))
+	copy(sorted, stops)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+	self.layoutOptions.tabStops = &sorted
+}
+
+// Returns the explicit tab stops, sorted by position. The returned
+// slice must not be modified. See [RendererLayout.SetTabStops]().
+func (self *RendererLayout) GetTabStops() []TabStop {
+	if self.layoutOptions.tabStops == nil {
+		return nil
+	}
+	return *self.layoutOptions.tabStops
+}
+
+// Sets the distance between regular tab stops in logical pixels,
+// so it's multiplied by the renderer's scale. See also
+// [RendererLayout.SetTabIntervalEms]() and [RendererLayout.SetTabStops]().
+func (self *RendererLayout) SetTabInterval(interval float64) {
+	if interval <= 0 || math.IsInf(interval, 1) {
+		panic("tab interval must be strictly positive")
+	}
+	self.layoutOptions.tabInterval = fract.FromFloat64Up(interval)
+	self.layoutOptions.tabIntervalInEms = false
+}
+
+// Sets the distance between regular tab stops in ems, so it's
+// multiplied by the current text size. The default is 4 ems.
+func (self *RendererLayout) SetTabIntervalEms(ems float64) {
+	if ems <= 0 || math.IsInf(ems, 1) {
+		panic("tab interval must be strictly positive")
+	}
+	self.layoutOptions.tabInterval = fract.FromFloat64Up(ems)
+	self.layoutOptions.tabIntervalInEms = true
+}
+
+// Returns the distance between regular tab stops, and whether
+// it's given in ems or in logical pixels. See [RendererLayout.SetTabInterval]()
+// and [RendererLayout.SetTabIntervalEms]().
+func (self *RendererLayout) GetTabInterval() (interval float64, ems bool) {
+	return self.layoutOptions.tabInterval.ToFloat64(), self.layoutOptions.tabIntervalInEms
+}
+
+// Sets the decorations to draw across the text: any combination of
+// [Underline], [Strikethrough] and [Overline]. Positions and thicknesses
+// are taken from the font's 'post' and 'OS/2' tables when available (see
+// [font.GetDecorationMetrics]()) and scaled with the text size, and the
+// overline is placed at the font's ascent. Decorations are drawn with
+// the text color and blend mode, continuously across wrapped lines and
+// twine runs, but never across elided spaces at line ends. Passing zero
+// disables decorations, which is the default.
+//
+// Decorations are not drawn for [TopToBottom] text nor by [Feed].
+//
+// [font.GetDecorationMetrics]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10/font#GetDecorationMetrics
+func (self *RendererLayout) SetDecorations(decorations Decoration) {
+	if decorations&^(Underline|Strikethrough|Overline) != 0 {
+		panic("invalid decoration flags")
+	}
+	self.layoutOptions.decorations = decorations
+}
+
+// Returns the current decorations. See [RendererLayout.SetDecorations]().
+func (self *RendererLayout) GetDecorations() Decoration {
+	return self.layoutOptions.decorations
+}
+
+// Sets whether underlines and overlines leave gaps around the glyph
+// parts crossing them, like descenders on 'g' or 'y'. Strikethroughs
+// never skip ink. Enabled by default.
+//
+// Skipping ink requires loading the outlines of the decorated glyphs
+// on every draw, so disabling it can be noticeably faster.
+func (self *RendererLayout) SetDecorationSkipInk(skipInk bool) {
+	self.layoutOptions.skipInk = skipInk
+}
+
+// Returns whether decorations skip ink. See
+// [RendererLayout.SetDecorationSkipInk]().
+func (self *RendererLayout) GetDecorationSkipInk() bool {
+	return self.layoutOptions.skipInk
+}
+
+// Sets the render layers used to draw text. When layers are set, each
+// draw operation lays out and measures the text only once, but draws it
+// once per layer, in order, with the layer's rasterizer, color, blend mode
+// and pixel offset. This makes it possible to draw a shadow, an outline
+// and the regular fill in a single draw call:
+//
+//	renderer.Layout().SetLayers(
+//		etxt.RenderLayer{Rasterizer: &blur, Color: shadowColor, OffsetX: 2, OffsetY: 2},
+//		etxt.RenderLayer{Rasterizer: &stroke, Color: outlineColor},
+//		etxt.RenderLayer{}, // regular text
+//	)
+//
+// Layers are drawn one after another instead of glyph by glyph, so the
+// layers below never overlap the ones above. Layers apply to all the
+// draw functions, including twines and text blocks, but not to [Feed].
+// Passing no layers clears them, which is the default.
+func (self *RendererLayout) SetLayers(layers ...RenderLayer) {
+	if len(layers) == 0 {
+		self.layoutOptions.layers = nil
+		return
+	}
+
+	// (a new slice is always allocated, so options remain comparable)
+	copied := make([]RenderLayer, len(layers))
+	copy(copied, layers)
+	self.layoutOptions.layers = &copied
+}
+
+// Returns the render layers. The returned slice must not be
+// modified. See [RendererLayout.SetLayers]().
+func (self *RendererLayout) GetLayers() []RenderLayer {
+	if self.layoutOptions.layers == nil {
+		return nil
+	}
+	return *self.layoutOptions.layers
+}
+
+// ---- underlying implementations ----
+
+// Layout options that can be configured through [RendererLayout].
+type layoutOptions struct {
+	justifyLetterSpacing fract.Unit // logical units
+	lineBreaking         LineBreaking
+	linePenalty          float64
+	tolerance            float64
+	hyphenator           hyphen.Hyphenator
+	truncation           Truncation
+	highlight            layoutHighlight
+	tabStops             *[]TabStop // sorted, replaced instead of modified
+	tabInterval          fract.Unit // logical units or ems
+	tabIntervalInEms     bool
+	decorations          Decoration
+	skipInk              bool
+	layers               *[]RenderLayer // replaced instead of modified
+}
+
+type layoutHighlight struct {
+	start int
+	end   int
+	color color.Color // nil if disabled
+}
+
+func defaultLayoutOptions() layoutOptions {
+	return layoutOptions{
+		linePenalty:      10,
+		tolerance:        0.1,
+		tabInterval:      fract.FromInt(4),
+		tabIntervalInEms: true,
+		skipInk:          true,
+	}
+}
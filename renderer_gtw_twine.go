@@ -0,0 +1,176 @@
+package etxt
+
+import (
+	"strconv"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
+
+// This type exists only for documentation and structuring purposes,
+// acting as a [gateway] to draw and measure rich text with [Twine].
+//
+// In general, this type is used through method chaining:
+//
+//	renderer.Twine().Draw(canvas, twine, x, y)
+//
+// Twines can use multiple fonts, which must be registered on the
+// renderer with [RendererTwine.RegisterFont]() before drawing.
+//
+// [gateway]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10#Renderer
+type RendererTwine Renderer
+
+// [Gateway] to [RendererTwine] functionality.
+//
+// [Gateway]: https://pkg.go.dev/github.com/tinne26/etxt@v0.0.10#Renderer
+func (self *Renderer) Twine() *RendererTwine {
+	return (*RendererTwine)(self)
+}
+
+// Registers a font at the given index, so it can be used in twines
+// through [TwineStyle].FontIndex. Registering a font at the renderer's
+// current font index is equivalent to [Renderer.SetFont]().
+func (self *RendererTwine) RegisterFont(index FontIndex, font *sfnt.Font) {
+	(*Renderer)(self).twineRegisterFont(index, font)
+}
+
+// Returns the font registered at the given index, or nil if none.
+func (self *RendererTwine) GetFont(index FontIndex) *sfnt.Font {
+	return (*Renderer)(self).twineGetFont(index)
+}
+
+// Same as [Renderer.Draw](), but for twines. Vertical aligns are
+// resolved with the twine's line metrics when relevant: [Top] uses
+// the ascent of the first line, and [VertCenter], [Bottom] and
+// [LastBaseline] use the twine's measured height.
+//
+// The renderer state is left untouched after drawing, even if the
+// twine uses different fonts, sizes, colors or rasterizers.
+func (self *RendererTwine) Draw(target Target, twine Twine, x, y int) {
+	(*Renderer)(self).twineDraw(target, &twine, fract.FromInt(x), fract.FromInt(y), false, 0)
+}
+
+// Same as [Renderer.DrawWithWrap](), but for twines. Lines can be
+// wrapped at any space, even within a run. See also [RendererTwine.Draw]().
+func (self *RendererTwine) DrawWithWrap(target Target, twine Twine, x, y, widthLimit int) {
+	if widthLimit > fract.MaxInt {
+		panic("widthLimit too big, must be <= fract.MaxInt")
+	}
+	(*Renderer)(self).twineDraw(target, &twine, fract.FromInt(x), fract.FromInt(y), true, fract.FromInt(widthLimit))
+}
+
+// Same as [Renderer.Measure](), but for twines. Line heights and
+// advances are computed from the tallest styles on each line.
+func (self *RendererTwine) Measure(twine Twine) fract.Rect {
+	return (*Renderer)(self).twineMeasure(&twine, false, 0)
+}
+
+// Same as [Renderer.MeasureWithWrap](), but for twines.
+func (self *RendererTwine) MeasureWithWrap(twine Twine, widthLimit int) fract.Rect {
+	if widthLimit > fract.MaxInt {
+		panic("widthLimit too big, must be <= fract.MaxInt")
+	}
+	return (*Renderer)(self).twineMeasure(&twine, true, fract.FromInt(widthLimit))
+}
+
+// ---- underlying implementations ----
+
+func (self *Renderer) twineRegisterFont(index FontIndex, font *sfnt.Font) {
+	if index == self.state.fontIndex {
+		self.SetFont(font)
+	} else {
+		self.fonts = ensureSliceSize(self.fonts, int(index)+1)
+		self.fonts[index] = font
+	}
+}
+
+func (self *Renderer) twineGetFont(index FontIndex) *sfnt.Font {
+	if index == self.state.fontIndex {
+		return self.state.activeFont
+	}
+	if int(index) >= len(self.fonts) {
+		return nil
+	}
+	return self.fonts[index]
+}
+
+func (self *Renderer) twineDraw(target Target, twine *Twine, x, y fract.Unit, wrap bool, widthLimit fract.Unit) {
+	// preconditions
+	if target == nil {
+		panic("can't draw on nil Target")
+	}
+	if self.state.fontSizer == nil {
+		panic("can't draw with a nil sizer (tip: NewRenderer())")
+	}
+
+	// return directly on superfluous invocations
+	if len(twine.Runs) == 0 {
+		return
+	}
+	if target.Bounds().Empty() {
+		return
+	}
+
+	self.twineLayout(&self.layout, twine, wrap, widthLimit)
+	self.layoutDraw(target, &self.layout, x, y)
+}
+
+func (self *Renderer) twineMeasure(twine *Twine, wrap bool, widthLimit fract.Unit) fract.Rect {
+	// preconditions
+	if self.state.fontSizer == nil {
+		panic("can't measure with a nil sizer (tip: NewRenderer())")
+	}
+
+	// return directly on superfluous invocations
+	if len(twine.Runs) == 0 {
+		return fract.Rect{}
+	}
+
+	self.twineLayout(&self.layout, twine, wrap, widthLimit)
+	return self.layoutMeasure(&self.layout)
+}
+
+func (self *Renderer) twineLayout(layout *textLayout, twine *Twine, wrap bool, widthLimit fract.Unit) {
+	layout.reset(self.state.textDirection, wrap)
+
+	// resolve run styles first, as applying them changes the renderer state
+	initStyle := self.layoutCurrentStyle()
+	layout.runStyles = ensureSliceSize(layout.runStyles, len(twine.Runs))
+	styles := layout.runStyles
+	for i := range twine.Runs {
+		styles[i] = layout.addStyle(self.twineResolveStyle(&twine.Runs[i].Style))
+	}
+
+	// add runes and process the layout
+	var byteOffset int
+	for i := range twine.Runs {
+		self.layoutApplyStyle(&layout.styles[styles[i]])
+		layout.addRunes(self, twine.Runs[i].Text, byteOffset, styles[i])
+		byteOffset += len(twine.Runs[i].Text)
+	}
+	self.layoutProcess(layout, widthLimit)
+	self.layoutApplyStyle(&initStyle)
+}
+
+func (self *Renderer) twineResolveStyle(style *TwineStyle) layoutStyle {
+	resolved := self.layoutCurrentStyle()
+	resolved.font = self.twineGetFont(style.FontIndex)
+	if resolved.font == nil {
+		panic("twine font index " + strconv.Itoa(int(style.FontIndex)) + " has no font (tip: RendererTwine.RegisterFont())")
+	}
+	resolved.fontIndex = style.FontIndex
+	if style.Size != 0 {
+		resolved.logicalSize = fract.FromFloat64Up(style.Size)
+	}
+	if style.Color != nil {
+		resolved.color = style.Color
+	}
+	if style.Rasterizer != nil {
+		resolved.rasterizer = style.Rasterizer
+	} else if resolved.rasterizer == nil {
+		panic("can't use twines with a nil rasterizer (tip: NewRenderer())")
+	}
+	resolved.blendMode = style.BlendMode
+	resolved.decorations |= style.Decoration
+	return resolved
+}
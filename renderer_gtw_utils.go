@@ -1,10 +1,13 @@
 package etxt
 
 import (
+	"errors"
 	"image/color"
 	"strconv"
+	"strings"
 
 	"github.com/tinne26/etxt/cache"
+	"github.com/tinne26/etxt/font"
 	"github.com/tinne26/etxt/fract"
 	"github.com/tinne26/etxt/mask"
 	"github.com/tinne26/etxt/sizer"
@@ -75,6 +78,16 @@ func (self *RendererUtils) SetFontBytes(data []byte) error {
 	return (*Renderer)(self).utilsSetFontBytes(data)
 }
 
+// Utility method to set the renderer's fallback fonts from a font
+// library, using the given font names as the preference order. If
+// any of the fonts can't be found, an error is returned and the
+// fallback fonts are left unchanged.
+//
+// See [RendererGlyph.SetFallbackFonts]() for further details.
+func (self *RendererUtils) SetFallbackFontsFromLibrary(library *font.Library, names ...string) error {
+	return (*Renderer)(self).utilsSetFallbackFontsFromLibrary(library, names)
+}
+
 // Makes a copy of the current renderer state and pushes it
 // into an internal stack. Stored states can be recovered with
 // [RendererUtils.RestoreState]() in last-in first-out order.
@@ -119,6 +132,37 @@ func (self *RendererUtils) AssertMaxStoredStates(n int) {
 	}
 }
 
+// Returns the largest logical size between minSize and maxSize for which
+// the given text fits within the box's width and height, without changing
+// the renderer's current size. If wrap is true, the text is measured like
+// with [Renderer.MeasureWithWrap](), using the box's width as the width
+// limit. Otherwise, it's measured like with [Renderer.Measure]().
+//
+// Since measurements are in real pixels, the renderer's scale and
+// quantization are taken into account, but the returned size is a
+// logical size, ready to be passed to [Renderer.SetSize](). If the
+// text doesn't fit even at minSize, minSize is returned along with
+// false. Sizes are found with a binary search, with a precision
+// of 1/64th of a pixel.
+func (self *RendererUtils) FitSize(text string, box fract.Rect, minSize, maxSize float64, wrap bool) (float64, bool) {
+	width, height := box.Size()
+	size, fits := (*Renderer)(self).utilsFitSize(text, width, height, fract.FromFloat64Up(minSize), fract.FromFloat64Up(maxSize), wrap)
+	return size.ToFloat64(), fits
+}
+
+// Creates a twine where each line of the given text has the largest
+// logical size between minSize and maxSize that fits the box's width,
+// as determined by [RendererUtils.FitSize](). This is typically used
+// for headlines and titles, where each line is expected to fill the
+// available width. The box's height is not taken into account.
+//
+// The twine's runs have the computed sizes set, while the other style
+// fields are left to their zero values. Empty lines use the renderer's
+// size. The result can be drawn with [RendererTwine.Draw]().
+func (self *RendererUtils) FitLinesToWidth(text string, box fract.Rect, minSize, maxSize float64) Twine {
+	return (*Renderer)(self).utilsFitLinesToWidth(text, box.Width(), fract.FromFloat64Up(minSize), fract.FromFloat64Up(maxSize))
+}
+
 // ---- underlying implementations ----
 
 func (self *Renderer) utilsSetCache8MiB() {
@@ -141,6 +185,19 @@ func (self *Renderer) utilsSetFontBytes(data []byte) error {
 	return nil
 }
 
+func (self *Renderer) utilsSetFallbackFontsFromLibrary(library *font.Library, names []string) error {
+	fonts := make([]*sfnt.Font, 0, len(names))
+	for _, name := range names {
+		fallback := library.GetFont(name)
+		if fallback == nil {
+			return errors.New("font \"" + name + "\" not found in library")
+		}
+		fonts = append(fonts, fallback)
+	}
+	self.glyphSetFallbackFonts(fonts)
+	return nil
+}
+
 func (self *Renderer) utilsFillMissingProperties() {
 	if self.state.rasterizer == nil {
 		self.glyphSetRasterizer(&mask.DefaultRasterizer{})
@@ -212,6 +269,7 @@ func (self *Renderer) setState(state restorableState) {
 	}
 	if initFont != self.state.activeFont {
 		refreshSizer = true
+		self.cachedMetricsSize = -1
 		if self.cacheHandler != nil {
 			self.cacheHandler.NotifyFontChange(self.state.activeFont)
 		}
@@ -236,3 +294,64 @@ func (self *Renderer) setState(state restorableState) {
 		}
 	}
 }
+
+func (self *Renderer) utilsFitSize(text string, width, height fract.Unit, minSize, maxSize fract.Unit, wrap bool) (fract.Unit, bool) {
+	// preconditions
+	if self.state.activeFont == nil {
+		panic("can't fit text with nil font (tip: Renderer.SetFont())")
+	}
+	if minSize <= 0 || maxSize < minSize {
+		panic("invalid size range, must be 0 < minSize <= maxSize")
+	}
+
+	initSize := self.state.logicalSize
+	fits := func(size fract.Unit) bool {
+		self.fractSetSize(size)
+		var rect fract.Rect
+		if wrap {
+			rect = self.fractMeasureWithWrap(text, width)
+		} else {
+			rect = self.fractMeasure(text)
+		}
+		return rect.Width() <= width && rect.Height() <= height
+	}
+
+	// binary search the largest fitting size
+	size, ok := minSize, fits(minSize)
+	if ok {
+		if fits(maxSize) {
+			size = maxSize
+		} else {
+			low, high := minSize, maxSize // low fits, high doesn't
+			for high-low > 1 {
+				mid := low + (high-low)/2
+				if fits(mid) {
+					low = mid
+				} else {
+					high = mid
+				}
+			}
+			size = low
+		}
+	}
+	self.fractSetSize(initSize)
+	return size, ok
+}
+
+func (self *Renderer) utilsFitLinesToWidth(text string, width fract.Unit, minSize, maxSize fract.Unit) Twine {
+	var twine Twine
+	for len(text) > 0 {
+		line, rest, found := strings.Cut(text, "\n")
+		var style TwineStyle
+		if line != "" {
+			size, _ := self.utilsFitSize(line, width, fract.MaxUnit, minSize, maxSize, false)
+			style.Size = size.ToFloat64()
+		}
+		if found {
+			line = text[:len(line)+1]
+		}
+		twine.Add(line, style)
+		text = rest
+	}
+	return twine
+}
@@ -0,0 +1,74 @@
+package etxt
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestFitSize(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(20)
+	text := "hello world"
+	box := renderer.Measure(text)
+
+	for _, scale := range []float64{1, 2} {
+		for _, wrap := range []bool{false, true} {
+			renderer.SetScale(scale)
+			renderer.SetSize(16)
+			size, fits := renderer.Utils().FitSize(text, box, 4, 100, wrap)
+			if !fits || renderer.GetSize() != 16 {
+				t.Fatalf("scale %v, wrap %v: unexpected fit (%v, %v)", scale, wrap, size, fits)
+			}
+			if size < 20/scale-0.1 || size > 100 {
+				t.Fatalf("scale %v, wrap %v: unexpected size %v", scale, wrap, size)
+			}
+
+			// size must fit, but not the next one
+			measure := func(size fract.Unit) fract.Rect {
+				renderer.Fract().SetSize(size)
+				if wrap {
+					return renderer.fractMeasureWithWrap(text, box.Width())
+				}
+				return renderer.Measure(text)
+			}
+			rect := measure(fract.FromFloat64Up(size))
+			if rect.Width() > box.Width() || rect.Height() > box.Height() {
+				t.Fatalf("scale %v, wrap %v: size %v doesn't fit", scale, wrap, size)
+			}
+			rect = measure(fract.FromFloat64Up(size) + 1)
+			if rect.Width() <= box.Width() && rect.Height() <= box.Height() {
+				t.Fatalf("scale %v, wrap %v: size %v is not the largest fitting size", scale, wrap, size)
+			}
+		}
+	}
+
+	size, fits := renderer.Utils().FitSize(text, fract.IntsToRect(0, 0, 1, 1), 4, 100, false)
+	if fits || size != 4 {
+		t.Fatalf("expected text not to fit, got (%v, %v)", size, fits)
+	}
+}
+
+func TestFitLinesToWidth(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	twine := renderer.Utils().FitLinesToWidth("BIG\n\nsmaller line", fract.IntsToRect(0, 0, 200, 10), 4, 200)
+	if len(twine.Runs) != 3 {
+		t.Fatalf("expected 3 runs, got %d", len(twine.Runs))
+	}
+	if twine.Runs[0].Text != "BIG\n" || twine.Runs[1].Text != "\n" || twine.Runs[2].Text != "smaller line" {
+		t.Fatalf("unexpected runs %v", twine.Runs)
+	}
+	if twine.Runs[0].Style.Size <= twine.Runs[2].Style.Size || twine.Runs[1].Style.Size != 0 {
+		t.Fatalf("unexpected sizes %v", twine.Runs)
+	}
+}
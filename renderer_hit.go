@@ -0,0 +1,136 @@
+package etxt
+
+import (
+	"image"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+// Returns the rune of the text closest to the given point, assuming
+// the text is drawn at (x, y) with [Renderer.Draw]() and the current
+// renderer configuration. This can be used to make text clickable,
+// place carets in text fields and so on. See [TextHit] for details on
+// the result and [Renderer.CaretRect]() for the opposite operation.
+//
+// Points outside the text resolve to the closest line and the closest
+// rune within that line.
+func (self *Renderer) HitTest(text string, x, y int, point image.Point) TextHit {
+	return self.fractHitTest(text, fract.FromInt(x), fract.FromInt(y), false, 0, fract.IntsToPoint(point.X, point.Y))
+}
+
+// Same as [Renderer.HitTest](), but for text drawn with [Renderer.DrawWithWrap]().
+func (self *Renderer) HitTestWithWrap(text string, x, y, widthLimit int, point image.Point) TextHit {
+	if widthLimit > fract.MaxInt {
+		panic("widthLimit too big, must be <= fract.MaxInt")
+	}
+	return self.fractHitTest(text, fract.FromInt(x), fract.FromInt(y), true, fract.FromInt(widthLimit), fract.IntsToPoint(point.X, point.Y))
+}
+
+// Returns the caret rect for the given byte index of the text, assuming
+// the text is drawn at (x, y) with [Renderer.Draw]() and the current
+// renderer configuration. The rect has zero width and spans the full
+// height of the line (or zero height and the full column width for
+// [TopToBottom] text). Byte indices in the middle of a rune are moved
+// to the next rune, and len(text) can be used to get the caret at the
+// end of the text.
+//
+// At line wraps, the caret for the index of the first rune of a line
+// is placed at the start of that line, not at the end of the previous
+// one. With mixed directions, the caret is placed at the leading edge
+// of the rune at the given index.
+func (self *Renderer) CaretRect(text string, x, y int, byteIndex int) fract.Rect {
+	return self.fractCaretRect(text, fract.FromInt(x), fract.FromInt(y), false, 0, byteIndex)
+}
+
+// Same as [Renderer.CaretRect](), but for text drawn with [Renderer.DrawWithWrap]().
+func (self *Renderer) CaretRectWithWrap(text string, x, y, widthLimit int, byteIndex int) fract.Rect {
+	if widthLimit > fract.MaxInt {
+		panic("widthLimit too big, must be <= fract.MaxInt")
+	}
+	return self.fractCaretRect(text, fract.FromInt(x), fract.FromInt(y), true, fract.FromInt(widthLimit), byteIndex)
+}
+
+// Returns the rects covering the glyphs in the byte range [start, end)
+// of the text, assuming the text is drawn at (x, y) with [Renderer.Draw]()
+// and the current renderer configuration. This can be used to draw
+// selection backgrounds, search highlights, link hover boxes and so on.
+//
+// Rects span the full height of their lines, and glyphs that are next
+// to each other are merged into a single rect, so there's typically one
+// rect per line, but mixed directions can lead to more. Line breaks and
+// spaces elided at line wraps are not covered by any rect.
+//
+// To draw the range with a different color, see [RendererLayout.SetHighlight]().
+func (self *Renderer) RangeRects(text string, x, y int, start, end int) []fract.Rect {
+	return self.fractRangeRects(text, fract.FromInt(x), fract.FromInt(y), false, 0, start, end)
+}
+
+// Same as [Renderer.RangeRects](), but for text drawn with [Renderer.DrawWithWrap]().
+func (self *Renderer) RangeRectsWithWrap(text string, x, y, widthLimit int, start, end int) []fract.Rect {
+	if widthLimit > fract.MaxInt {
+		panic("widthLimit too big, must be <= fract.MaxInt")
+	}
+	return self.fractRangeRects(text, fract.FromInt(x), fract.FromInt(y), true, fract.FromInt(widthLimit), start, end)
+}
+
+// ---- underlying implementations ----
+
+func (self *Renderer) fractHitTest(text string, x, y fract.Unit, wrap bool, widthLimit fract.Unit, point fract.Point) TextHit {
+	// preconditions
+	if self.state.activeFont == nil {
+		panic("can't hit test text with nil font (tip: Renderer.SetFont())")
+	}
+	if self.state.fontSizer == nil {
+		panic("can't hit test text with a nil sizer (tip: NewRenderer())")
+	}
+	if widthLimit < 0 {
+		panic("can't use a negative widthLimit")
+	}
+
+	// main processing
+	self.layoutString(&self.layout, text, wrap, widthLimit)
+	return self.layoutHitTest(&self.layout, len(text), x, y, point)
+}
+
+func (self *Renderer) fractCaretRect(text string, x, y fract.Unit, wrap bool, widthLimit fract.Unit, byteIndex int) fract.Rect {
+	// preconditions
+	if self.state.activeFont == nil {
+		panic("can't get caret with nil font (tip: Renderer.SetFont())")
+	}
+	if self.state.fontSizer == nil {
+		panic("can't get caret with a nil sizer (tip: NewRenderer())")
+	}
+	if widthLimit < 0 {
+		panic("can't use a negative widthLimit")
+	}
+	if byteIndex < 0 || byteIndex > len(text) {
+		panic("byteIndex out of range")
+	}
+
+	// main processing
+	self.layoutString(&self.layout, text, wrap, widthLimit)
+	return self.layoutCaretRect(&self.layout, byteIndex, x, y)
+}
+
+func (self *Renderer) fractRangeRects(text string, x, y fract.Unit, wrap bool, widthLimit fract.Unit, start, end int) []fract.Rect {
+	// preconditions
+	if self.state.activeFont == nil {
+		panic("can't get range rects with nil font (tip: Renderer.SetFont())")
+	}
+	if self.state.fontSizer == nil {
+		panic("can't get range rects with a nil sizer (tip: NewRenderer())")
+	}
+	if widthLimit < 0 {
+		panic("can't use a negative widthLimit")
+	}
+	if start > end {
+		panic("range start must be <= end")
+	}
+
+	// main processing
+	if text == "" || start == end {
+		return nil
+	}
+	self.layoutString(&self.layout, text, wrap, widthLimit)
+	return self.layoutRangeRects(&self.layout, start, end, x, y, nil)
+}
@@ -0,0 +1,124 @@
+package etxt
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestHitTestCaretRoundTrip(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(16)
+	renderer.Fract().SetHorzQuantization(QtFull)
+	renderer.Fract().SetVertQuantization(QtFull)
+
+	text := "hello world\nhow are you?"
+	widthLimit := renderer.Measure("hello wor").IntWidth()
+	for _, dir := range []Direction{LeftToRight, RightToLeft} {
+		for _, align := range []Align{Left, HorzCenter, Right} {
+			renderer.SetDirection(dir)
+			renderer.SetAlign(align)
+			for index := 0; index <= len(text); index++ {
+				caret := renderer.CaretRectWithWrap(text, 100, 100, widthLimit, index)
+				if caret.Width() != 0 || caret.Height() <= 0 {
+					t.Fatalf("dir %s, align %s, index %d: invalid caret %v", dir, align, index, caret)
+				}
+
+				// points right next to the caret, on the side of
+				// the rune at the caret index, must resolve to it
+				cx, cy := caret.Min.X.ToIntFloor(), (caret.Min.Y + caret.Height()/2).ToIntFloor()
+				point := image.Pt(cx+1, cy)
+				if dir == RightToLeft {
+					point.X = cx - 1
+				}
+				if index == len(text) || text[index] == '\n' {
+					point.X = cx // end of line
+				}
+				hit := renderer.HitTestWithWrap(text, 100, 100, widthLimit, point)
+				if hit.CaretIndex != index {
+					t.Fatalf("dir %s, align %s, index %d: caret at %v, but hit test resolved to %+v", dir, align, index, caret, hit)
+				}
+			}
+		}
+	}
+}
+
+func TestHitTestOutside(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.SetAlign(Top | Left)
+
+	text := "abc\ndef"
+	hit := renderer.HitTest(text, 0, 0, image.Pt(-50, -50))
+	if hit != (TextHit{ByteIndex: 0, RuneIndex: 0, Trailing: false, CaretIndex: 0}) {
+		t.Fatalf("unexpected hit %+v", hit)
+	}
+	hit = renderer.HitTest(text, 0, 0, image.Pt(9999, -50))
+	if hit != (TextHit{ByteIndex: 2, RuneIndex: 2, Trailing: true, CaretIndex: 3}) {
+		t.Fatalf("unexpected hit %+v", hit)
+	}
+	hit = renderer.HitTest(text, 0, 0, image.Pt(9999, 9999))
+	if hit != (TextHit{ByteIndex: 6, RuneIndex: 6, Trailing: true, CaretIndex: 7}) {
+		t.Fatalf("unexpected hit %+v", hit)
+	}
+
+	renderer.SetDirection(RightToLeft)
+	renderer.SetAlign(Right)
+	hit = renderer.HitTest(text, 0, 0, image.Pt(-9999, 9999))
+	if hit.CaretIndex != 7 || !hit.Trailing {
+		t.Fatalf("unexpected right-to-left hit %+v", hit)
+	}
+
+	// empty text
+	caret := renderer.CaretRect("", 10, 10, 0)
+	if caret.Min.X.ToIntFloor() != 10 || caret.Height() <= 0 {
+		t.Fatalf("unexpected caret %v for empty text", caret)
+	}
+}
+
+func TestRangeRects(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+
+	text := "hello world"
+	widthLimit := renderer.Measure("hello wor").IntWidth()
+	for _, dir := range []Direction{LeftToRight, RightToLeft} {
+		renderer.SetDirection(dir)
+		rects := renderer.RangeRectsWithWrap(text, 10, 10, widthLimit, 3, 8)
+		if len(rects) != 2 {
+			t.Fatalf("dir %s: expected 2 rects, got %v", dir, rects)
+		}
+		for i, span := range [][2]int{{3, 5}, {6, 8}} {
+			a := renderer.CaretRectWithWrap(text, 10, 10, widthLimit, span[0])
+			b := renderer.CaretRectWithWrap(text, 10, 10, widthLimit, span[1])
+			if dir == RightToLeft {
+				a, b = b, a
+			}
+			// (carets may differ in kerning and quantization from glyph edges)
+			expected := fract.UnitsToRect(a.Min.X, a.Min.Y, b.Min.X, b.Max.Y)
+			rect := rects[i]
+			if rect.Min.Y != expected.Min.Y || rect.Max.Y != expected.Max.Y ||
+				(rect.Min.X-expected.Min.X).Abs() >= fract.One || (rect.Max.X-expected.Max.X).Abs() >= fract.One {
+				t.Fatalf("dir %s: expected rect #%d to be close to %v, got %v", dir, i, expected, rect)
+			}
+		}
+	}
+
+	if len(renderer.RangeRects(text, 0, 0, 2, 2)) != 0 {
+		t.Fatalf("expected no rects for empty range")
+	}
+}
@@ -1,8 +1,10 @@
 package etxt
 
 import (
+	"image"
 	"strconv"
 
+	"github.com/tinne26/etxt/cache"
 	"github.com/tinne26/etxt/fract"
 	"github.com/tinne26/etxt/mask"
 	"golang.org/x/image/font/sfnt"
@@ -58,8 +60,17 @@ func (self *Renderer) loadGlyphMask(index sfnt.GlyphIndex, origin fract.Point) G
 		panic("font.LoadGlyph(index = " + strconv.Itoa(int(index)) + ") error: " + err.Error())
 	}
 
-	// rasterize the glyph mask
-	alphaMask, err := mask.Rasterize(segments, self.state.rasterizer, origin)
+	// rasterize the glyph mask. subpixel masks can't be represented
+	// as glyph masks, so LCD rasterizers are replaced by their wrapped
+	// ones (subpixel masks are loaded with loadLCDMask() instead)
+	rasterizer := self.state.rasterizer
+	if lcd, isLCD := rasterizer.(*mask.LCDRasterizer); isLCD {
+		rasterizer = lcd.GetRasterizer()
+	}
+	if sizeAware, ok := rasterizer.(mask.SizeAwareRasterizer); ok {
+		sizeAware.NotifySizeChange(self.state.scaledSize)
+	}
+	alphaMask, err := mask.Rasterize(segments, rasterizer, origin)
 	if err != nil {
 		panic("RasterizeGlyphMask failed: " + err.Error())
 	}
@@ -72,6 +83,82 @@ func (self *Renderer) loadGlyphMask(index sfnt.GlyphIndex, origin fract.Point) G
 	return glyphMask
 }
 
+// Like loadGlyphMask(), but for the subpixel masks of a [mask.LCDRasterizer],
+// which are converted to RGBA masks. RGBA masks are only cached if the cache
+// handler implements [cache.RGBAMaskCacheHandler].
+// Precondition: same as loadGlyphMask(), and the current rasterizer must
+// be a mask.LCDRasterizer.
+func (self *Renderer) loadLCDMask(index sfnt.GlyphIndex, origin fract.Point) rgbaMask {
+	rgbaCacheHandler := self.rgbaCacheHandler()
+	if rgbaCacheHandler != nil {
+		lcdMask, found := rgbaCacheHandler.GetRGBAMask(index)
+		if found {
+			return lcdMask
+		}
+	}
+
+	segments, err := self.glyphLoadSegments(index)
+	if err != nil {
+		panic("font.LoadGlyph(index = " + strconv.Itoa(int(index)) + ") error: " + err.Error())
+	}
+	if sizeAware, ok := self.state.rasterizer.(mask.SizeAwareRasterizer); ok {
+		sizeAware.NotifySizeChange(self.state.scaledSize)
+	}
+	subpixelMask, err := mask.Rasterize(segments, self.state.rasterizer, origin)
+	if err != nil {
+		panic("RasterizeGlyphMask failed: " + err.Error())
+	}
+
+	lcdMask := convertLCDImageToRGBAMask(subpixelMask)
+	if rgbaCacheHandler != nil {
+		rgbaCacheHandler.PassRGBAMask(index, lcdMask)
+	}
+	return lcdMask
+}
+
+// Returns the cache handler if it can store RGBA masks, or nil otherwise.
+func (self *Renderer) rgbaCacheHandler() cache.RGBAMaskCacheHandler {
+	rgbaCacheHandler, _ := self.cacheHandler.(cache.RGBAMaskCacheHandler)
+	return rgbaCacheHandler
+}
+
+// Returns whether the current rasterizer is a [mask.LCDRasterizer],
+// whose subpixel masks need special conversion and drawing.
+func (self *Renderer) usingLCDRasterizer() bool {
+	_, isLCD := self.state.rasterizer.(*mask.LCDRasterizer)
+	return isLCD
+}
+
+// Converts a subpixel mask generated by mask.LCDRasterizer to an RGBA
+// image with pixel bounds. The subpixel coverage values are stored in
+// the RGB channels, with the alpha being the max coverage.
+func lcdMaskToRGBA(lcd *image.Alpha) *image.RGBA {
+	if lcd == nil {
+		return nil
+	}
+
+	rect := lcd.Rect
+	rect.Min.X, rect.Max.X = rect.Min.X/3, rect.Max.X/3
+	rgba := image.NewRGBA(rect)
+	width := rect.Dx()
+	for y := 0; y < rect.Dy(); y++ {
+		row := lcd.Pix[y*lcd.Stride : y*lcd.Stride+width*3]
+		pixels := rgba.Pix[y*rgba.Stride : y*rgba.Stride+width*4]
+		for x := 0; x < width; x++ {
+			r, g, b := row[x*3+0], row[x*3+1], row[x*3+2]
+			a := r
+			if g > a {
+				a = g
+			}
+			if b > a {
+				a = b
+			}
+			pixels[x*4+0], pixels[x*4+1], pixels[x*4+2], pixels[x*4+3] = r, g, b, a
+		}
+	}
+	return rgba
+}
+
 // --- internal functions for draw and renderer ---
 // Precondition: sizer and font have been validated to be initialized.
 
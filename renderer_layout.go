@@ -0,0 +1,1001 @@
+package etxt
+
+import (
+	"image/color"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+	"golang.org/x/image/font/sfnt"
+)
+
+// This file contains the general text layout process used for twines
+// and other operations that can't be performed in a single pass over
+// a string like the ones in renderer_draw*.go and renderer_measure*.go.
+//
+// The process is split in two steps:
+//   - Layout: runes are mapped to glyphs, measured and broken into
+//     lines, and line metrics and baselines are computed.
+//   - Traversal: lines are walked glyph by glyph from a specific
+//     position in order to draw them or find glyph positions.
+//
+// Positioning and quantization rules are the same ones used by the
+// regular draw and measure functions, so results must be identical
+// for the cases that both can handle.
+//
+// All layout functions leave the renderer state as they found it.
+
+// A style used within a text layout. Fields are always resolved,
+// there are no "inherit from renderer" values like on TwineStyle.
+type layoutStyle struct {
+	font        *sfnt.Font
+	fontIndex   FontIndex
+	logicalSize fract.Unit
+	color       color.Color
+	blendMode   BlendMode
+	rasterizer  mask.Rasterizer
+	metricsFont *sfnt.Font // font for line metrics, if different from font (fallbacks)
+	sideways    bool       // rotated glyphs for vertical text
+	decorations Decoration
+}
+
+type layoutRune struct {
+	codePoint rune
+	byteIndex int
+	glyph     sfnt.GlyphIndex
+	advance   fract.Unit
+	spacing   fract.Unit // extra advance after the glyph (justification)
+	kern      fract.Unit // kern with the left glyph in visual order, within the line
+	style     uint16
+	level     uint8 // bidi embedding level
+	skip      bool
+	offset    fract.Point // origin offset from the column axis and glyph position, for vertical text and attached marks
+	attach    fract.Point // origin offset from the base glyph origin, for attached marks
+	base      int         // index of the base rune, for attached marks
+	attached  bool        // whether the rune is a mark attached to a base glyph
+}
+
+type layoutLine struct {
+	runeStart  int        // first rune of the line
+	runeEnd    int        // end of line content (excluding elided spaces and line breaks)
+	orderStart int        // start of the line glyphs in textLayout.order (visual order)
+	orderEnd   int        // end of the line glyphs in textLayout.order
+	width      fract.Unit // computed in the text direction, unquantized
+	ascent     fract.Unit
+	descent    fract.Unit        // line height - ascent, so it includes the line gap
+	baseline   fract.Unit        // relative to the first baseline, quantized
+	change     LineChangeDetails // details of the line change after this line
+	hyphenRune int               // index of the inserted hyphen rune, if change.InsertedHyphen
+	rtl        bool              // paragraph direction
+}
+
+type textLayout struct {
+	runes  []layoutRune
+	styles []layoutStyle
+	order  []int // glyph rune indices in visual order, line by line
+	lines  []layoutLine
+
+	bidi       bidiResolver
+	codePoints []rune         // buffer for bidi resolution
+	levels     []uint8        // buffer for bidi reordering
+	breaks     []layoutBreak  // buffer for optimal line wrapping
+	positions  []fract.Unit   // buffer for hit testing
+	inkGaps    []layoutInkGap // buffer for decorations
+	runStyles  []uint16       // buffer for twine run styles
+	seenStyles []bool         // buffer for line vertical metrics
+
+	lineBreaker  lineBreaker
+	breakable    []bool // whether lines can be wrapped before each rune
+	hyphenPoints []bool // whether lines can be hyphenated before each rune
+	wordPoints   []bool // buffer for hyphenation
+	numTextRunes int    // inserted hyphen runes come after the text runes
+
+	wrap           bool
+	direction      Direction
+	widthLimit     fract.Unit // only relevant if wrap is true
+	width          fract.Unit // max line width, unquantized
+	height         fract.Unit // quantized
+	lineBreaksOnly bool
+}
+
+func (self *textLayout) reset(direction Direction, wrap bool) {
+	self.runes = self.runes[:0]
+	self.styles = self.styles[:0]
+	self.order = self.order[:0]
+	self.lines = self.lines[:0]
+	self.wrap = wrap
+	self.direction = direction
+	self.widthLimit = 0
+	self.width = 0
+	self.height = 0
+	self.lineBreaksOnly = true
+}
+
+// Returns the index of the given style, adding it to the layout if necessary.
+func (self *textLayout) addStyle(style layoutStyle) uint16 {
+	for i := range self.styles {
+		if self.styles[i] == style {
+			return uint16(i)
+		}
+	}
+	if len(self.styles) >= 0xFFFF {
+		panic("too many different styles in a single layout")
+	}
+	self.styles = append(self.styles, style)
+	return uint16(len(self.styles) - 1)
+}
+
+// Precondition: the given style must be applied on the renderer.
+func (self *textLayout) addRunes(renderer *Renderer, text string, byteOffset int, style uint16) {
+	for i, codePoint := range text {
+		lrune := layoutRune{codePoint: codePoint, byteIndex: byteOffset + i, style: style}
+		if lineBreakIsInvisible(codePoint) {
+			lrune.skip = true
+		} else if codePoint != '\n' && codePoint != '\t' { // (tab advances are set later)
+			if renderer.fallbackFonts != nil {
+				lrune.style = self.fallbackStyle(renderer, codePoint, style)
+				renderer.layoutApplyStyle(&self.styles[lrune.style])
+			}
+			lrune.glyph, lrune.skip = renderer.getGlyphIndex(self.styles[lrune.style].font, codePoint)
+			if !lrune.skip {
+				lrune.advance = renderer.getOpAdvance(lrune.glyph)
+			}
+		}
+		self.runes = append(self.runes, lrune)
+	}
+}
+
+// Returns the style to be used for the given code point, which will be
+// a variant of the given style with a different font if the code point
+// isn't covered by the style's font but it's covered by a fallback font.
+func (self *textLayout) fallbackStyle(renderer *Renderer, codePoint rune, style uint16) uint16 {
+	font := self.styles[style].font
+	index, err := font.GlyphIndex(&renderer.buffer, codePoint)
+	if err != nil {
+		panic("font.GlyphIndex error: " + err.Error())
+	}
+	if index != 0 {
+		return style
+	}
+	fallback := renderer.glyphFindFallbackFont(codePoint)
+	if fallback == nil {
+		return style
+	}
+	fallbackStyle := self.styles[style]
+	fallbackStyle.font = fallback
+	fallbackStyle.metricsFont = font
+	return self.addStyle(fallbackStyle)
+}
+
+// Returns the style to be used for line metrics. For most styles
+// this is the style itself, but fallback styles use the metrics
+// of the original font.
+func (self *textLayout) metricsStyle(style uint16) layoutStyle {
+	metricsStyle := self.styles[style]
+	if metricsStyle.metricsFont != nil {
+		metricsStyle.font = metricsStyle.metricsFont
+		metricsStyle.metricsFont = nil
+	}
+	return metricsStyle
+}
+
+// Returns the style of the given line, for lines without any glyph.
+func (self *textLayout) emptyLineStyle(line *layoutLine) uint16 {
+	if line.runeStart < self.numTextRunes {
+		return self.runes[line.runeStart].style
+	}
+	if self.numTextRunes > 0 {
+		return self.runes[self.numTextRunes-1].style
+	}
+	return 0
+}
+
+// Returns the distance from the first baseline to the last.
+func (self *textLayout) lastBaseline() fract.Unit {
+	if len(self.lines) == 0 {
+		return 0
+	}
+	return self.lines[len(self.lines)-1].baseline
+}
+
+// Returns the ascent of the first line.
+func (self *textLayout) firstAscent() fract.Unit {
+	if len(self.lines) == 0 {
+		return 0
+	}
+	return self.lines[0].ascent
+}
+
+// Returns whether glyphs are traversed from the right edge of the
+// line instead of the left one. This mimics the behavior of the
+// regular draw functions.
+func (self *textLayout) fromRight(line *layoutLine, horzAlign Align) bool {
+	if self.wrap || horzAlign == HorzCenter || self.direction.isBidi() {
+		return line.rtl
+	}
+	return horzAlign == Right
+}
+
+// ---- layout process ----
+
+// Sets the renderer state to the given style, notifying the cache
+// handler and sizer when relevant.
+func (self *Renderer) layoutApplyStyle(style *layoutStyle) {
+	if self.state.activeFont != style.font {
+		self.state.fontIndex = style.fontIndex
+		self.state.activeFont = style.font
+		self.notifyFontChange(style.font)
+	}
+	if self.state.logicalSize != style.logicalSize {
+		self.fractSetSize(style.logicalSize)
+	}
+	rasterizer := style.rasterizer
+	if style.sideways {
+		rasterizer = self.layoutSidewaysRasterizer(rasterizer)
+	}
+	if self.state.rasterizer != rasterizer {
+		self.glyphSetRasterizer(rasterizer)
+	}
+	self.state.fontColor = style.color
+	self.state.blendMode = style.blendMode
+}
+
+// Returns the current renderer state as a layout style.
+func (self *Renderer) layoutCurrentStyle() layoutStyle {
+	return layoutStyle{
+		font:        self.state.activeFont,
+		fontIndex:   self.state.fontIndex,
+		logicalSize: self.state.logicalSize,
+		color:       self.state.fontColor,
+		blendMode:   self.state.blendMode,
+		rasterizer:  self.state.rasterizer,
+		decorations: self.layoutOptions.decorations,
+	}
+}
+
+// Kern between two glyphs that will be drawn next to each other, left
+// and right in visual order. Glyphs with different fonts or sizes are
+// never kerned, and in vertical text only sideways glyphs are kerned.
+// Pair adjustments from the font's positioning data take precedence
+// over the sizer's kerning.
+func (self *Renderer) layoutKern(layout *textLayout, left, right *layoutRune) fract.Unit {
+	if left.codePoint == '\t' || right.codePoint == '\t' {
+		return 0
+	}
+	leftStyle, rightStyle := &layout.styles[left.style], &layout.styles[right.style]
+	if left.style != right.style {
+		if leftStyle.font != rightStyle.font || leftStyle.logicalSize != rightStyle.logicalSize {
+			return 0
+		}
+	}
+	if layout.direction.isVertical() && !(leftStyle.sideways && rightStyle.sideways) {
+		return 0
+	}
+	self.layoutApplyStyle(&layout.styles[right.style])
+	if positioning := self.positioning[rightStyle.font]; positioning != nil {
+		if kern, found := positioning.Kern(left.glyph, right.glyph); found {
+			unitsPerEm := int64(self.state.activeFont.UnitsPerEm())
+			return fract.Unit(int64(kern) * int64(self.state.scaledSize) / unitsPerEm)
+		}
+	}
+	return self.getOpKernBetween(left.glyph, right.glyph)
+}
+
+// Kern between two consecutive runes in logical order. Runes with
+// different bidi levels are never kerned, and attached marks are
+// skipped (see renderer_layout_marks.go).
+func (self *Renderer) layoutLogicalKern(layout *textLayout, prev, curr *layoutRune) fract.Unit {
+	if curr.attached {
+		return 0
+	}
+	if prev.attached {
+		prev = &layout.runes[prev.base]
+	}
+	if prev.level != curr.level {
+		return 0
+	}
+	if prev.level&1 == 1 {
+		return self.layoutKern(layout, curr, prev)
+	}
+	return self.layoutKern(layout, prev, curr)
+}
+
+// Lays out the runes already added to the layout, breaking them into
+// lines and computing all the relevant metrics.
+func (self *Renderer) layoutProcess(layout *textLayout, widthLimit fract.Unit) {
+	layout.widthLimit = widthLimit
+	layout.numTextRunes = len(layout.runes)
+	if layout.direction.isVertical() {
+		self.layoutVertRunes(layout)
+	} else {
+		self.layoutAttachMarks(layout)
+	}
+
+	// break paragraphs into lines
+	start := 0
+	for i := 0; i <= len(layout.runes); i++ {
+		if i < len(layout.runes) && layout.runes[i].codePoint != '\n' {
+			continue
+		}
+		rtl := self.layoutResolveParagraph(layout, start, i)
+		firstLine := len(layout.lines)
+		if layout.wrap {
+			self.layoutResolveLineBreaks(layout, start, i)
+			self.layoutWrapParagraph(layout, start, i, rtl, widthLimit)
+		} else {
+			layout.lines = append(layout.lines, layoutLine{runeStart: start, runeEnd: i})
+		}
+		for j := firstLine; j < len(layout.lines); j++ {
+			layout.lines[j].rtl = rtl
+		}
+		start = i + 1
+	}
+
+	// set visual order, kerning and widths for each line
+	for i := range layout.lines {
+		line := &layout.lines[i]
+		self.layoutShowHyphen(layout, line)
+		self.layoutOrderLine(layout, line)
+		self.layoutKernLine(layout, line)
+		self.layoutTabLine(layout, line)
+		line.width = self.layoutLineWidth(layout, line)
+		if layout.wrap && !layout.direction.isVertical() && self.layoutShouldJustify(line) {
+			self.layoutJustifyLine(layout, line)
+		}
+		if !layout.direction.isVertical() {
+			self.layoutPlaceMarksLine(layout, line)
+		}
+		if line.width > 0 {
+			layout.lineBreaksOnly = false
+			if line.width > layout.width {
+				layout.width = line.width
+			}
+		}
+	}
+
+	self.layoutComputeVertMetrics(layout)
+}
+
+// Sets the bidi levels for the runes in [start, end), which shouldn't
+// contain any line breaks, and returns whether the paragraph direction
+// is right-to-left. Bidi mirroring is also applied here.
+func (self *Renderer) layoutResolveParagraph(layout *textLayout, start, end int) bool {
+	switch layout.direction {
+	case LeftToRight, TopToBottom:
+		return false
+	case RightToLeft:
+		for i := start; i < end; i++ {
+			layout.runes[i].level = 1
+		}
+		return true
+	}
+
+	// resolve bidi levels
+	layout.codePoints = layout.codePoints[:0]
+	for i := start; i < end; i++ {
+		layout.codePoints = append(layout.codePoints, layout.runes[i].codePoint)
+	}
+	var paraLevel int8 = -1
+	if layout.direction == BidiLeftToRight {
+		paraLevel = 0
+	} else if layout.direction == BidiRightToLeft {
+		paraLevel = 1
+	}
+	level := layout.bidi.resolve(layout.codePoints, paraLevel)
+
+	// set levels and mirror glyphs on right-to-left runs
+	for i := start; i < end; i++ {
+		lrune := &layout.runes[i]
+		lrune.level = layout.bidi.levels[i-start]
+		if lrune.level&1 == 0 || lrune.skip {
+			continue
+		}
+		mirror := bidiMirror(lrune.codePoint)
+		if mirror == lrune.codePoint {
+			continue
+		}
+		style := &layout.styles[lrune.style]
+		index, err := style.font.GlyphIndex(&self.buffer, mirror)
+		if err != nil {
+			panic("font.GlyphIndex error: " + err.Error())
+		}
+		if index != 0 {
+			self.layoutApplyStyle(style)
+			lrune.glyph = index
+			lrune.advance = self.getOpAdvance(index)
+		}
+	}
+	return level == 1
+}
+
+// Line wrapping for the runes in [start, end), which shouldn't
+// contain any line breaks. Line break opportunities must already
+// be resolved. See also RendererLayout.SetLineBreaking().
+func (self *Renderer) layoutWrapParagraph(layout *textLayout, start, end int, rtl bool, widthLimit fract.Unit) {
+	if self.layoutOptions.lineBreaking == LineBreakOptimal {
+		self.layoutWrapParagraphOptimal(layout, start, end, rtl, widthLimit)
+		return
+	}
+
+	lineStart := start
+	for {
+		line, next := self.layoutWrapLine(layout, lineStart, end, rtl, widthLimit)
+		layout.lines = append(layout.lines, line)
+		if !line.change.IsWrap {
+			return
+		}
+		lineStart = next
+	}
+}
+
+// Greedy wrapping of a single line starting at lineStart. Returns the
+// line and the index of the rune where the next line would start. If
+// the line is the last one in the paragraph, line.change.IsWrap will
+// be false.
+func (self *Renderer) layoutWrapLine(layout *textLayout, lineStart, end int, rtl bool, widthLimit fract.Unit) (layoutLine, int) {
+	horzQuant := fract.Unit(self.state.horzQuantization)
+	var x fract.Unit
+	var safeEnd int = -1 // rune index after the last wrapping opportunity
+	var prev int = -1
+	var i int
+	for i = lineStart; i < end; i++ {
+		lrune := &layout.runes[i]
+		if i > lineStart && layout.canWrapBefore(i) && layout.runes[i-1].codePoint != ' ' {
+			if self.layoutWrapFits(layout, i, x, widthLimit, rtl) {
+				safeEnd = i
+			}
+		}
+		if lrune.skip {
+			continue
+		}
+
+		x = self.layoutAdvanceWrapX(layout, x, prev, i, rtl)
+		if lrune.codePoint == ' ' && layout.canWrapAfterSpace(i, end) {
+			safeEnd = i + 1
+		}
+		if layoutWrapExceeds(x, widthLimit, horzQuant) {
+			break
+		}
+		prev = i
+	}
+
+	// natural paragraph end
+	if i >= end {
+		return layoutLine{runeStart: lineStart, runeEnd: end}, end
+	}
+
+	// wrap line
+	if safeEnd != -1 {
+		return layout.wrappedLine(lineStart, safeEnd), safeEnd
+	}
+	line := layoutLine{runeStart: lineStart, change: LineChangeDetails{IsWrap: true}}
+	if i == lineStart { // single glyph exceeding the limit
+		line.runeEnd = i + 1
+		if i+1 == end { // nothing else left in the paragraph
+			line.change.IsWrap = false
+		}
+		return line, i + 1
+	} else { // show as much of the first word as possible
+		line.runeEnd = i
+		return line, i
+	}
+}
+
+// Returns the line starting at lineStart and wrapped before the rune
+// at the given index, which is where the next line starts. Used by all
+// line breaking strategies, so they report the same line changes.
+func (self *textLayout) wrappedLine(lineStart, next int) layoutLine {
+	line := layoutLine{runeStart: lineStart, runeEnd: next}
+	line.change = LineChangeDetails{IsWrap: true, InsertedHyphen: self.hyphenPoints[next]}
+	if self.runes[next-1].codePoint == ' ' {
+		// (lines with a single space are reported as non-elided
+		// for consistency with the regular wrap functions, but
+		// the space is still not taken into account)
+		line.runeEnd = next - 1
+		line.change.ElidedSpace = (next-lineStart > 1)
+	}
+	return line
+}
+
+// Advances the x position used during line wrapping with the given
+// rune. For right-to-left paragraphs, x goes into negative values.
+func (self *Renderer) layoutAdvanceWrapX(layout *textLayout, x fract.Unit, prev, curr int, rtl bool) fract.Unit {
+	horzQuant := fract.Unit(self.state.horzQuantization)
+	lrune := &layout.runes[curr]
+	if lrune.codePoint == '\t' {
+		lrune.advance = self.layoutWrapTabAdvance(x.Abs())
+	}
+	if rtl {
+		x -= lrune.advance
+		if prev != -1 {
+			x -= self.layoutLogicalKern(layout, &layout.runes[prev], lrune)
+		}
+		return x.QuantizeUp(horzQuant)
+	}
+	if prev != -1 {
+		x = (x + self.layoutLogicalKern(layout, &layout.runes[prev], lrune)).QuantizeUp(horzQuant)
+	}
+	return x + lrune.advance
+}
+
+// Returns whether the given wrapping x position exceeds the width limit.
+func layoutWrapExceeds(x, widthLimit, horzQuant fract.Unit) bool {
+	return x.Abs() > widthLimit && x.QuantizeUp(horzQuant).Abs() > widthLimit
+}
+
+// Sets the visual order of the line glyphs.
+func (self *Renderer) layoutOrderLine(layout *textLayout, line *layoutLine) {
+	line.orderStart = len(layout.order)
+	if layout.direction.isBidi() {
+		// apply rules L1 and L2
+		layout.levels = layout.levels[:0]
+		layout.codePoints = layout.codePoints[:0]
+		for i := line.runeStart; i < line.runeEnd; i++ {
+			layout.order = append(layout.order, i)
+			layout.levels = append(layout.levels, layout.runes[i].level)
+			layout.codePoints = append(layout.codePoints, layout.runes[i].codePoint)
+		}
+		if line.change.InsertedHyphen {
+			hyphen := &layout.runes[line.hyphenRune]
+			layout.order = append(layout.order, line.hyphenRune)
+			layout.levels = append(layout.levels, hyphen.level)
+			layout.codePoints = append(layout.codePoints, hyphen.codePoint)
+		}
+		var paraLevel uint8
+		if line.rtl {
+			paraLevel = 1
+		}
+		bidiResetWhitespaceLevels(layout.levels, layout.codePoints, paraLevel)
+		for i, level := range layout.levels {
+			layout.runes[layout.order[line.orderStart+i]].level = level
+		}
+		bidiReorderLine(layout.order[line.orderStart:], layout.levels)
+
+		// remove skipped runes
+		n := line.orderStart
+		for _, index := range layout.order[line.orderStart:] {
+			if !layout.runes[index].skip {
+				layout.order[n] = index
+				n += 1
+			}
+		}
+		layout.order = layout.order[:n]
+	} else if line.rtl {
+		if line.change.InsertedHyphen {
+			layout.order = append(layout.order, line.hyphenRune)
+		}
+		for i := line.runeEnd - 1; i >= line.runeStart; i-- {
+			if !layout.runes[i].skip {
+				layout.order = append(layout.order, i)
+			}
+		}
+	} else {
+		for i := line.runeStart; i < line.runeEnd; i++ {
+			if !layout.runes[i].skip {
+				layout.order = append(layout.order, i)
+			}
+		}
+		if line.change.InsertedHyphen {
+			layout.order = append(layout.order, line.hyphenRune)
+		}
+	}
+	line.orderEnd = len(layout.order)
+}
+
+// Sets the kerning values for the line glyphs, which must already be
+// in visual order. Attached marks are not kerned, and they are skipped
+// when kerning the glyphs around them.
+func (self *Renderer) layoutKernLine(layout *textLayout, line *layoutLine) {
+	var left *layoutRune
+	for _, index := range layout.order[line.orderStart:line.orderEnd] {
+		right := &layout.runes[index]
+		if right.attached {
+			right.kern = 0
+			continue
+		}
+		if left == nil || left.level != right.level {
+			right.kern = 0
+		} else {
+			right.kern = self.layoutKern(layout, left, right)
+		}
+		left = right
+	}
+}
+
+func (self *Renderer) layoutLineWidth(layout *textLayout, line *layoutLine) fract.Unit {
+	if line.rtl {
+		return -self.layoutTraverseLine(layout, line, 0, true, nil)
+	}
+	return self.layoutTraverseLine(layout, line, 0, false, nil)
+}
+
+// Computes line ascents, descents and baselines, as well as the
+// total layout height.
+func (self *Renderer) layoutComputeVertMetrics(layout *textLayout) {
+	vertQuant := fract.Unit(self.state.vertQuantization)
+	initStyle := self.layoutCurrentStyle()
+	layout.seenStyles = ensureSliceSize(layout.seenStyles, len(layout.styles))
+	seen := layout.seenStyles[:len(layout.styles)]
+	var lineBreakNth int = -1
+	var prevLine *layoutLine
+	for i := range layout.lines {
+		// compute ascent and descent
+		line := &layout.lines[i]
+		for j := range seen {
+			seen[j] = false
+		}
+		if line.orderStart == line.orderEnd {
+			self.layoutLineStyleMetrics(layout, line, layout.emptyLineStyle(line))
+		} else {
+			for _, index := range layout.order[line.orderStart:line.orderEnd] {
+				style := layout.runes[index].style
+				if !seen[style] {
+					seen[style] = true
+					self.layoutLineStyleMetrics(layout, line, style)
+				}
+			}
+		}
+
+		// compute baseline position relative to the previous line
+		if prevLine != nil {
+			if prevLine.width > 0 {
+				lineBreakNth = 0
+			}
+			lineBreakNth = maxInt(1, lineBreakNth+1)
+			advance := self.layoutLineAdvance(layout, prevLine, lineBreakNth) + line.ascent
+			line.baseline = (prevLine.baseline + advance).QuantizeUp(vertQuant)
+		}
+		prevLine = line
+	}
+
+	// compute total height
+	if len(layout.lines) > 0 && !layout.lineBreaksOnly {
+		height := layout.lines[0].ascent + prevLine.baseline + prevLine.descent
+		layout.height = height.QuantizeUp(vertQuant)
+	} else {
+		layout.height = layout.lastBaseline()
+	}
+	self.layoutApplyStyle(&initStyle)
+}
+
+func (self *Renderer) layoutLineStyleMetrics(layout *textLayout, line *layoutLine, style uint16) {
+	metricsStyle := layout.metricsStyle(style)
+	self.layoutApplyStyle(&metricsStyle)
+	ascent := self.getOpAscent()
+	if ascent > line.ascent {
+		line.ascent = ascent
+	}
+	descent := self.getOpLineHeight() - ascent
+	if descent > line.descent {
+		line.descent = descent
+	}
+}
+
+// Returns the line advance minus the line ascent, the part of
+// the line advance that depends on the line before the break.
+func (self *Renderer) layoutLineAdvance(layout *textLayout, line *layoutLine, lineBreakNth int) fract.Unit {
+	if len(layout.styles) == 1 {
+		self.layoutApplyStyle(&layout.styles[0])
+		return self.getOpLineAdvance(lineBreakNth) - line.ascent
+	}
+
+	var maxAdvance fract.Unit
+	apply := func(style uint16) {
+		metricsStyle := layout.metricsStyle(style)
+		self.layoutApplyStyle(&metricsStyle)
+		advance := self.getOpLineAdvance(lineBreakNth) - self.getOpAscent()
+		if advance > maxAdvance {
+			maxAdvance = advance
+		}
+	}
+	if line.orderStart == line.orderEnd {
+		apply(layout.emptyLineStyle(line))
+	} else {
+		for _, index := range layout.order[line.orderStart:line.orderEnd] {
+			apply(layout.runes[index].style)
+		}
+	}
+	return maxAdvance
+}
+
+// ---- traversal ----
+
+// Traverses the glyphs of a line in visual order. If fromRight is false,
+// x is the left edge of the line and glyphs are traversed from left to
+// right, otherwise x is the right edge and glyphs are traversed from right
+// to left. The given function (which can be nil) is called with the glyph
+// rune and its quantized origin x. The final x position is returned.
+func (self *Renderer) layoutTraverseLine(layout *textLayout, line *layoutLine, x fract.Unit, fromRight bool, fn func(*layoutRune, fract.Unit)) fract.Unit {
+	horzQuant := fract.Unit(self.state.horzQuantization)
+	order := layout.order[line.orderStart:line.orderEnd]
+	if fromRight {
+		var rightKern fract.Unit
+		for i := len(order) - 1; i >= 0; i-- {
+			lrune := &layout.runes[order[i]]
+			x = (x - lrune.advance - lrune.spacing - rightKern).QuantizeUp(horzQuant)
+			if fn != nil {
+				fn(lrune, x)
+			}
+			rightKern = lrune.kern
+		}
+	} else {
+		for _, index := range order {
+			lrune := &layout.runes[index]
+			x = (x + lrune.kern).QuantizeUp(horzQuant)
+			if fn != nil {
+				fn(lrune, x)
+			}
+			x += lrune.advance + lrune.spacing
+		}
+	}
+	return x
+}
+
+// Returns the baseline for the first line of the layout, based on
+// the renderer's vertical align. The result is quantized.
+func (self *Renderer) layoutFirstBaseline(layout *textLayout, y fract.Unit) fract.Unit {
+	vertQuant := fract.Unit(self.state.vertQuantization)
+	switch vertAlign := self.state.align.Vert(); vertAlign {
+	case Top:
+		return (y + layout.firstAscent()).QuantizeUp(vertQuant)
+	case VertCenter:
+		return (y + layout.firstAscent() - (layout.height >> 1)).QuantizeUp(vertQuant)
+	case LastBaseline:
+		return (y - layout.lastBaseline()).QuantizeUp(vertQuant)
+	case Bottom:
+		return (y + layout.firstAscent() - layout.height).QuantizeUp(vertQuant)
+	default:
+		return (y + self.getBaselineOffset(vertAlign)).QuantizeUp(vertQuant)
+	}
+}
+
+// Returns the x coordinate where the line traversal has to start.
+// See layoutTraverseLine() for further details.
+func (self *Renderer) layoutLineStartX(layout *textLayout, line *layoutLine, x fract.Unit, fromRight bool) fract.Unit {
+	switch self.state.align.Horz() {
+	case Left:
+		if fromRight {
+			return x + line.width
+		}
+		return x
+	case Right:
+		if fromRight {
+			return x
+		}
+		return x - line.width
+	case HorzCenter:
+		width := line.width
+		if !layout.wrap {
+			width = width.QuantizeUp(fract.Unit(self.state.horzQuantization))
+		}
+		if fromRight {
+			return x + (width >> 1)
+		}
+		return x - (width >> 1)
+	case Justify, JustifyAll:
+		if !fromRight {
+			return x
+		}
+		if layout.wrap {
+			return x + layout.widthLimit
+		}
+		return x + line.width
+	default:
+		panic(self.state.align.Horz())
+	}
+}
+
+// Returns the adjusted x position and the first baseline y position
+// used to draw the given layout at the given coordinates.
+func (self *Renderer) layoutDrawOrigin(layout *textLayout, x, y fract.Unit) (fract.Unit, fract.Unit) {
+	if layout.wrap || self.state.align.Horz() != HorzCenter {
+		x = x.QuantizeUp(fract.Unit(self.state.horzQuantization))
+	}
+	return x, self.layoutFirstBaseline(layout, y)
+}
+
+// Draws the given layout, once for each render layer if any. The
+// renderer state must be the same one used to create the layout.
+func (self *Renderer) layoutDraw(target Target, layout *textLayout, x, y fract.Unit) {
+	if len(layout.lines) == 0 {
+		return
+	}
+	if self.layoutOptions.layers == nil {
+		self.layoutDrawLayer(target, layout, x, y, nil)
+		return
+	}
+	for i := range *self.layoutOptions.layers {
+		layer := &(*self.layoutOptions.layers)[i]
+		offsetX, offsetY := fract.FromInt(layer.OffsetX), fract.FromInt(layer.OffsetY)
+		self.layoutDrawLayer(target, layout, x+offsetX, y+offsetY, layer)
+	}
+}
+
+// Draws the given layout with the given render layer overrides.
+// The layer can be nil.
+func (self *Renderer) layoutDrawLayer(target Target, layout *textLayout, x, y fract.Unit, layer *RenderLayer) {
+	if layer != nil && layer.Color != nil {
+		self.monochrome = true
+		defer func() { self.monochrome = false }()
+	}
+
+	// adjust the starting position
+	vertical := layout.direction.isVertical()
+	if vertical {
+		x, y = self.layoutVertDrawOrigin(layout, x, y)
+	} else {
+		x, y = self.layoutDrawOrigin(layout, x, y)
+	}
+
+	// draw each line
+	horzQuant, vertQuant := self.fractGetQuantization()
+	var axis fract.Unit // column axis for vertical text
+	initStyle := self.layoutCurrentStyle()
+	var activeStyle int = -1
+	var layerStyle layoutStyle // active style with the layer overrides
+	var highlighted bool
+	var origin fract.Point
+	var baseline fract.Unit
+	var notifiedFract fract.Point = fract.Point{X: -1, Y: -1}
+	var decorator layoutDecorator
+	var decorationMetrics layoutDecorationMetrics
+	decorator.gaps = layout.inkGaps[:0]
+	highlight := &self.layoutOptions.highlight
+	drawFn := func(lrune *layoutRune, glyphX fract.Unit) {
+		if int(lrune.style) != activeStyle {
+			activeStyle = int(lrune.style)
+			layerStyle = layout.styles[activeStyle]
+			if layer != nil {
+				layer.applyTo(&layerStyle)
+			}
+			self.layoutApplyStyle(&layerStyle)
+			notifiedFract = fract.Point{X: -1, Y: -1}
+			highlighted = false
+			if !vertical && layerStyle.decorations != 0 {
+				decorationMetrics = self.layoutDecorationMetrics(&layerStyle)
+			}
+		}
+		if highlight.color != nil && (layer == nil || layer.Color == nil) {
+			inRange := lrune.inByteRange(highlight.start, highlight.end)
+			if inRange != highlighted {
+				highlighted = inRange
+				if highlighted {
+					self.state.fontColor = highlight.color
+				} else {
+					self.state.fontColor = layerStyle.color
+				}
+			}
+		}
+		if !vertical {
+			self.layoutDecorateRune(target, &decorator, lrune, layerStyle.decorations, decorationMetrics, glyphX)
+		}
+		if lrune.codePoint == '\t' {
+			return
+		}
+		if vertical { // (glyphX is the position along the column)
+			origin.X = (axis + lrune.offset.X).QuantizeUp(vertQuant)
+			origin.Y = (glyphX + lrune.offset.Y).QuantizeUp(horzQuant)
+		} else {
+			origin.X, origin.Y = glyphX, baseline
+			if lrune.attached {
+				origin.X = (glyphX + lrune.offset.X).QuantizeUp(horzQuant)
+				origin.Y = (baseline + lrune.offset.Y).QuantizeUp(vertQuant)
+			}
+		}
+		if self.cacheHandler != nil {
+			if origin.X.FractShift() != notifiedFract.X || origin.Y.FractShift() != notifiedFract.Y {
+				notifiedFract = fract.Point{X: origin.X.FractShift(), Y: origin.Y.FractShift()}
+				self.cacheHandler.NotifyFractChange(origin)
+			}
+		}
+		self.internalGlyphDraw(target, lrune.glyph, origin)
+	}
+	for i := range layout.lines {
+		line := &layout.lines[i]
+		if i > 0 && self.lineChangeFn != nil {
+			self.lineChangeFn(layout.lines[i-1].change)
+		}
+		if vertical {
+			axis = x - line.baseline
+			self.layoutTraverseLine(layout, line, self.layoutColumnStart(line, y), false, drawFn)
+			continue
+		}
+		baseline = y + line.baseline
+		decorator.baseline, decorator.ascent = baseline, line.ascent
+		fromRight := layout.fromRight(line, self.state.align.Horz())
+		startX := self.layoutLineStartX(layout, line, x, fromRight)
+		self.layoutTraverseLine(layout, line, startX, fromRight, drawFn)
+		self.layoutDrawDecorations(target, &decorator)
+	}
+	layout.inkGaps = decorator.gaps
+	self.layoutApplyStyle(&initStyle)
+}
+
+// Returns the layout dimensions as a quantized rect with zero origin.
+// For vertical text, line widths and heights are swapped.
+func (self *Renderer) layoutMeasure(layout *textLayout) fract.Rect {
+	width := layout.width.QuantizeUp(fract.Unit(self.state.horzQuantization))
+	if layout.direction.isVertical() {
+		return fract.Rect{Max: fract.UnitsToPoint(layout.height, width)}
+	}
+	return fract.Rect{Max: fract.UnitsToPoint(width, layout.height)}
+}
+
+// Returns the measurements of each line of the layout, relative to
+// the coordinates where the layout would be drawn. The horizontal
+// edges are taken from the same traversal used to draw the glyphs,
+// so they also account for the quantization of their positions.
+func (self *Renderer) layoutMeasureLines(layout *textLayout, textLen int) []MeasuredLine {
+	if layout.direction.isVertical() {
+		return self.layoutVertMeasureLines(layout, textLen)
+	}
+	x, y := self.layoutDrawOrigin(layout, 0, 0)
+	lines := make([]MeasuredLine, 0, len(layout.lines))
+	for i := range layout.lines {
+		line := &layout.lines[i]
+		fromRight := layout.fromRight(line, self.state.align.Horz())
+		start := self.layoutLineStartX(layout, line, x, fromRight)
+		left, right := start, start
+		first := true
+		end := self.layoutTraverseLine(layout, line, start, fromRight, func(lrune *layoutRune, x fract.Unit) {
+			if first && fromRight {
+				right = x + lrune.advance + lrune.spacing
+			} else if first {
+				left = x
+			}
+			first = false
+		})
+		if fromRight {
+			left = end
+		} else {
+			right = end
+		}
+		baseline := y + line.baseline
+		lines = append(lines, MeasuredLine{
+			Rect:      fract.UnitsToRect(left, baseline-line.ascent, right, baseline+line.descent),
+			Baseline:  baseline,
+			ByteStart: layout.runeByteIndex(line.runeStart, textLen),
+			ByteEnd:   layout.runeByteIndex(line.runeEnd, textLen),
+			Change:    line.change,
+			LineBreak: !line.change.IsWrap && line.runeEnd < layout.numTextRunes,
+		})
+	}
+	return lines
+}
+
+// ---- plain strings ----
+
+// Returns whether draw and measure operations for the given plain
+// string must go through the layout process instead of the regular
+// single pass functions.
+func (self *Renderer) layoutRequired(text string) bool {
+	if self.fallbackFonts != nil || self.state.textDirection.isBidi() || self.state.textDirection.isVertical() {
+		return true
+	}
+	if self.layoutOptions.lineBreaking != LineBreakGreedy {
+		return true
+	}
+	if self.layoutOptions.highlight.color != nil || self.layoutOptions.decorations != 0 {
+		return true
+	}
+	if self.layoutOptions.layers != nil {
+		return true
+	}
+	if self.positioning[self.state.activeFont] != nil {
+		return true
+	}
+	horzAlign := self.state.align.Horz()
+	if horzAlign == Justify || horzAlign == JustifyAll {
+		return true
+	}
+	return layoutHasTabs(text)
+}
+
+// Lays out a plain string with the current renderer configuration.
+func (self *Renderer) layoutString(layout *textLayout, text string, wrap bool, widthLimit fract.Unit) {
+	layout.reset(self.state.textDirection, wrap)
+	initStyle := self.layoutCurrentStyle()
+	style := layout.addStyle(initStyle)
+	layout.addRunes(self, text, 0, style)
+	self.layoutProcess(layout, widthLimit)
+	self.layoutApplyStyle(&initStyle)
+}
@@ -0,0 +1,294 @@
+package etxt
+
+import (
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/tinne26/etxt/font"
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Text decorations for the layout process. See RendererLayout.SetDecorations().
+//
+// Decorations are drawn while traversing the lines in layoutDraw().
+// Consecutive glyphs with the same decorations, color, blend mode and
+// decoration metrics are merged into spans, which are drawn as single
+// rects (minus skip-ink gaps) when the span ends or the line ends.
+
+// Decoration metrics scaled to the current size. Positions indicate
+// the top of the strokes relative to the baseline, with y growing
+// downwards like everywhere else.
+type layoutDecorationMetrics struct {
+	underline          fract.Unit
+	underlineThickness fract.Unit
+	strikeout          fract.Unit
+	strikeoutThickness fract.Unit
+}
+
+// A horizontal interval where a decoration must not be drawn because
+// it would cross the ink of a glyph.
+type layoutInkGap struct {
+	decoration Decoration
+	start      fract.Unit
+	end        fract.Unit
+}
+
+// State for drawing the decorations of a layout line by line.
+type layoutDecorator struct {
+	active      bool // whether there's a span in progress
+	left        fract.Unit
+	right       fract.Unit
+	baseline    fract.Unit
+	ascent      fract.Unit // line ascent, for overlines
+	decorations Decoration
+	color       color.Color
+	blendMode   BlendMode
+	metrics     layoutDecorationMetrics
+	gaps        []layoutInkGap
+}
+
+// Returns the decoration metrics for the given style, which must be
+// applied on the renderer. Fallback styles use the metrics of the
+// original font, so decorations don't jump around within a run.
+func (self *Renderer) layoutDecorationMetrics(style *layoutStyle) layoutDecorationMetrics {
+	sfntFont := style.font
+	if style.metricsFont != nil {
+		sfntFont = style.metricsFont
+	}
+	metrics, found := self.decorMetrics[sfntFont]
+	if !found {
+		var err error
+		metrics, err = font.GetDecorationMetrics(sfntFont)
+		if err != nil {
+			panic("font.GetDecorationMetrics error: " + err.Error())
+		}
+		if self.decorMetrics == nil {
+			self.decorMetrics = make(map[*sfnt.Font]font.DecorationMetrics)
+		}
+		self.decorMetrics[sfntFont] = metrics
+	}
+
+	unitsPerEm := fract.Unit(metrics.UnitsPerEm)
+	scale := func(value int) fract.Unit {
+		return fract.Unit(value).Rescale(unitsPerEm, self.state.scaledSize)
+	}
+	return layoutDecorationMetrics{
+		underline:          -scale(metrics.UnderlinePosition),
+		underlineThickness: scale(metrics.UnderlineThickness),
+		strikeout:          -scale(metrics.StrikeoutPosition),
+		strikeoutThickness: scale(metrics.StrikeoutThickness),
+	}
+}
+
+// Adds the given glyph to the decorations, drawing the previous span
+// if it can't be extended. The glyph style must be applied on the
+// renderer, with the color already adjusted for highlights.
+func (self *Renderer) layoutDecorateRune(target Target, decorator *layoutDecorator, lrune *layoutRune, decorations Decoration, metrics layoutDecorationMetrics, x fract.Unit) {
+	if decorator.active {
+		if decorations != decorator.decorations || metrics != decorator.metrics ||
+			self.state.fontColor != decorator.color || self.state.blendMode != decorator.blendMode {
+			self.layoutDrawDecorations(target, decorator)
+		}
+	}
+	if decorations == 0 {
+		return
+	}
+
+	right := x + lrune.advance + lrune.spacing
+	if !decorator.active {
+		decorator.active = true
+		decorator.left, decorator.right = x, right
+		decorator.decorations = decorations
+		decorator.metrics = metrics
+		decorator.color = self.state.fontColor
+		decorator.blendMode = self.state.blendMode
+	} else {
+		if x < decorator.left {
+			decorator.left = x
+		}
+		if right > decorator.right {
+			decorator.right = right
+		}
+	}
+
+	// add skip-ink gaps
+	if !self.layoutOptions.skipInk || lrune.codePoint == '\t' {
+		return
+	}
+	if decorations&Underline != 0 {
+		top := metrics.underline
+		self.layoutAddInkGap(decorator, Underline, lrune.glyph, x, top, top+metrics.underlineThickness)
+	}
+	if decorations&Overline != 0 {
+		top := -decorator.ascent
+		self.layoutAddInkGap(decorator, Overline, lrune.glyph, x, top, top+metrics.underlineThickness)
+	}
+}
+
+// Adds a gap for the parts of the given glyph crossing the given band,
+// relative to the glyph origin. The gap is padded by the band height.
+func (self *Renderer) layoutAddInkGap(decorator *layoutDecorator, decoration Decoration, glyph sfnt.GlyphIndex, x, top, bottom fract.Unit) {
+	pad := bottom - top
+	if pad < fract.One {
+		pad = fract.One
+	}
+	minX, maxX, crossed := self.layoutInkInterval(glyph, top-pad, bottom+pad)
+	if crossed {
+		decorator.gaps = append(decorator.gaps, layoutInkGap{decoration, x + minX - pad, x + maxX + pad})
+	}
+}
+
+// Returns the horizontal interval where the outline of the given glyph
+// is within [top, bottom], relative to the glyph origin. Curves are
+// flattened, so the result is approximate.
+func (self *Renderer) layoutInkInterval(glyph sfnt.GlyphIndex, top, bottom fract.Unit) (fract.Unit, fract.Unit, bool) {
+	segments, err := self.glyphLoadSegments(glyph)
+	if err != nil || len(segments) == 0 {
+		return 0, 0, false
+	}
+	bounds := segments.Bounds()
+	if fract.Unit(bounds.Max.Y) < top || fract.Unit(bounds.Min.Y) > bottom {
+		return 0, 0, false
+	}
+
+	const curveSteps = 8
+	var minX, maxX fract.Unit
+	var crossed bool
+	var prev fixed.Point26_6
+	addPiece := func(a, b fixed.Point26_6) {
+		x0, y0 := fract.Unit(a.X), fract.Unit(a.Y)
+		x1, y1 := fract.Unit(b.X), fract.Unit(b.Y)
+		if y0 > y1 {
+			x0, y0, x1, y1 = x1, y1, x0, y0
+		}
+		if y1 < top || y0 > bottom {
+			return
+		}
+		// clip the piece to the band
+		if y0 < top {
+			x0 = x0 + (x1-x0).Rescale(y1-y0, top-y0)
+			y0 = top
+		}
+		if y1 > bottom {
+			x1 = x0 + (x1-x0).Rescale(y1-y0, bottom-y0)
+		}
+		if x0 > x1 {
+			x0, x1 = x1, x0
+		}
+		if !crossed {
+			minX, maxX, crossed = x0, x1, true
+			return
+		}
+		if x0 < minX {
+			minX = x0
+		}
+		if x1 > maxX {
+			maxX = x1
+		}
+	}
+
+	for _, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			prev = segment.Args[0]
+		case sfnt.SegmentOpLineTo:
+			addPiece(prev, segment.Args[0])
+			prev = segment.Args[0]
+		case sfnt.SegmentOpQuadTo:
+			ctrl, end := segment.Args[0], segment.Args[1]
+			from := prev
+			for i := fixed.Int26_6(1); i <= curveSteps; i++ {
+				t := (i << 6) / curveSteps
+				point := lerpPoint(lerpPoint(prev, ctrl, t), lerpPoint(ctrl, end, t), t)
+				addPiece(from, point)
+				from = point
+			}
+			prev = end
+		case sfnt.SegmentOpCubeTo:
+			c1, c2, end := segment.Args[0], segment.Args[1], segment.Args[2]
+			from := prev
+			for i := fixed.Int26_6(1); i <= curveSteps; i++ {
+				t := (i << 6) / curveSteps
+				a, b, c := lerpPoint(prev, c1, t), lerpPoint(c1, c2, t), lerpPoint(c2, end, t)
+				point := lerpPoint(lerpPoint(a, b, t), lerpPoint(b, c, t), t)
+				addPiece(from, point)
+				from = point
+			}
+			prev = end
+		}
+	}
+	return minX, maxX, crossed
+}
+
+// Draws the decorations of the current span and resets it.
+func (self *Renderer) layoutDrawDecorations(target Target, decorator *layoutDecorator) {
+	if !decorator.active {
+		return
+	}
+	decorator.active = false
+
+	color, blendMode := self.state.fontColor, self.state.blendMode
+	self.state.fontColor, self.state.blendMode = decorator.color, decorator.blendMode
+	sort.Slice(decorator.gaps, func(i, j int) bool {
+		return decorator.gaps[i].start < decorator.gaps[j].start
+	})
+	metrics := &decorator.metrics
+	baseline := decorator.baseline
+	if decorator.decorations&Underline != 0 {
+		top := baseline + metrics.underline
+		self.layoutDrawDecoration(target, decorator, Underline, top, metrics.underlineThickness)
+	}
+	if decorator.decorations&Strikethrough != 0 {
+		top := baseline + metrics.strikeout
+		self.layoutDrawDecoration(target, decorator, Strikethrough, top, metrics.strikeoutThickness)
+	}
+	if decorator.decorations&Overline != 0 {
+		top := baseline - decorator.ascent
+		self.layoutDrawDecoration(target, decorator, Overline, top, metrics.underlineThickness)
+	}
+	self.state.fontColor, self.state.blendMode = color, blendMode
+	decorator.gaps = decorator.gaps[:0]
+}
+
+// Draws a single decoration of the current span, skipping its gaps.
+// Decorations are always snapped to the pixel grid and at least one
+// pixel thick.
+func (self *Renderer) layoutDrawDecoration(target Target, decorator *layoutDecorator, decoration Decoration, top, thickness fract.Unit) {
+	minY := top.ToIntHalfUp()
+	maxY := (top + thickness).ToIntHalfUp()
+	if maxY <= minY {
+		maxY = minY + 1
+	}
+
+	left := decorator.left
+	for _, gap := range decorator.gaps {
+		if gap.decoration != decoration || gap.end <= left {
+			continue
+		}
+		if gap.start >= decorator.right {
+			break
+		}
+		self.layoutDrawDecorationRect(target, left, gap.start, minY, maxY)
+		left = gap.end
+	}
+	self.layoutDrawDecorationRect(target, left, decorator.right, minY, maxY)
+}
+
+func (self *Renderer) layoutDrawDecorationRect(target Target, left, right fract.Unit, minY, maxY int) {
+	minX, maxX := left.ToIntHalfUp(), right.ToIntHalfUp()
+	if maxX <= minX {
+		return
+	}
+	self.drawDecorationRect(target, image.Rect(minX, minY, maxX, maxY))
+}
+
+// Linear interpolation between two points, with t in [0, 64].
+func lerpPoint(a, b fixed.Point26_6, t fixed.Int26_6) fixed.Point26_6 {
+	return fixed.Point26_6{
+		X: a.X + (b.X-a.X)*t/64,
+		Y: a.Y + (b.Y-a.Y)*t/64,
+	}
+}
@@ -0,0 +1,92 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestDecorations(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.Utils().SetCache8MiB()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(32)
+	renderer.SetAlign(Left | Baseline)
+	renderer.SetColor(color.RGBA{255, 255, 255, 255})
+	style := renderer.layoutCurrentStyle()
+	metrics := renderer.layoutDecorationMetrics(&style)
+	underlineY := (fract.FromInt(50) + metrics.underline).ToIntHalfUp()
+	strikeoutY := (fract.FromInt(50) + metrics.strikeout).ToIntHalfUp()
+	if underlineY <= 50 || strikeoutY >= 50 {
+		t.Fatalf("unexpected decoration rows %d and %d", underlineY, strikeoutY)
+	}
+
+	// count the opaque pixels in the given row, within [minX, maxX)
+	countRow := func(target *image.RGBA, y, minX, maxX int) int {
+		var count int
+		for x := minX; x < maxX; x++ {
+			if target.RGBAAt(x, y).A != 0 {
+				count++
+			}
+		}
+		return count
+	}
+
+	// decorations must cover the whole text, including spaces
+	renderer.Layout().SetDecorations(Underline | Strikethrough)
+	target := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	renderer.Draw(target, "l  l", 10, 50)
+	width := renderer.Measure("l  l").Width().ToIntFloor()
+	for _, y := range []int{underlineY, strikeoutY} {
+		if count := countRow(target, y, 11, 10+width-1); count != width-2 {
+			t.Fatalf("expected row %d to be fully drawn, got %d/%d pixels", y, count, width-2)
+		}
+	}
+	if countRow(target, underlineY, 10+width+1, 300) != 0 {
+		t.Fatalf("unexpected underline after the text")
+	}
+
+	// underlines skip descenders unless disabled
+	target = image.NewRGBA(image.Rect(0, 0, 300, 100))
+	renderer.Layout().SetDecorations(Underline)
+	renderer.Draw(target, "gypq", 10, 50)
+	width = renderer.Measure("gypq").Width().ToIntFloor()
+	withGaps := countRow(target, underlineY, 10, 10+width)
+	target = image.NewRGBA(image.Rect(0, 0, 300, 100))
+	renderer.Layout().SetDecorationSkipInk(false)
+	renderer.Draw(target, "gypq", 10, 50)
+	withoutGaps := countRow(target, underlineY, 10, 10+width)
+	if withGaps >= withoutGaps || withoutGaps < width-1 {
+		t.Fatalf("expected skip-ink gaps, got %d vs %d pixels", withGaps, withoutGaps)
+	}
+
+	// twine runs can add decorations
+	renderer.Layout().SetDecorations(0)
+	var twine Twine
+	twine.Add("lll", TwineStyle{}).Add("lll", TwineStyle{Decoration: Overline})
+	target = image.NewRGBA(image.Rect(0, 0, 300, 100))
+	renderer.Twine().Draw(target, twine, 10, 50)
+	split := 10 + renderer.Measure("lll").Width().ToIntHalfUp()
+	width = renderer.Twine().Measure(twine).Width().ToIntFloor()
+	overlineY := (fract.FromInt(50) - renderer.Metrics().Ascent()).ToIntHalfUp()
+	if countRow(target, overlineY, 10, split-1) != 0 {
+		t.Fatalf("unexpected overline on the first run")
+	}
+	if count := countRow(target, overlineY, split+1, 10+width-1); count != 10+width-split-2 {
+		t.Fatalf("expected overline on the second run, got %d pixels", count)
+	}
+	if renderer.Layout().GetDecorations() != 0 {
+		t.Fatalf("twine decorations leaked into the renderer")
+	}
+	if (Underline | Overline).String() != "Underline|Overline" {
+		t.Fatalf("unexpected decoration string %q", (Underline | Overline).String())
+	}
+}
@@ -0,0 +1,174 @@
+package etxt
+
+import (
+	"github.com/tinne26/etxt/fract"
+)
+
+// Hit testing and caret positioning for the layout process. Glyph
+// positions are obtained with the same traversal used by layoutDraw(),
+// so results always match what's drawn.
+
+// Returns the glyph origins for the given line, quantized and in the
+// same order as layout.order[line.orderStart:line.orderEnd]. The x must
+// be the adjusted one returned by layoutDrawOrigin().
+func (self *Renderer) layoutLineGlyphPositions(layout *textLayout, line *layoutLine, x fract.Unit) []fract.Unit {
+	layout.positions = ensureSliceSize(layout.positions, line.orderEnd-line.orderStart)
+	positions := layout.positions[:line.orderEnd-line.orderStart]
+	fromRight := layout.fromRight(line, self.state.align.Horz())
+	startX := self.layoutLineStartX(layout, line, x, fromRight)
+	var k int
+	if fromRight {
+		k = len(positions) - 1
+	}
+	self.layoutTraverseLine(layout, line, startX, fromRight, func(_ *layoutRune, glyphX fract.Unit) {
+		positions[k] = glyphX
+		if fromRight {
+			k -= 1
+		} else {
+			k += 1
+		}
+	})
+	return positions
+}
+
+// Returns the byte index of the rune with the given index, or the
+// text length if the index is past the last text rune.
+func (self *textLayout) runeByteIndex(index int, textLen int) int {
+	if index >= self.numTextRunes {
+		return textLen
+	}
+	return self.runes[index].byteIndex
+}
+
+// Returns the text hit for the given point, for a layout drawn at (x, y).
+func (self *Renderer) layoutHitTest(layout *textLayout, textLen int, x, y fract.Unit, point fract.Point) TextHit {
+	if len(layout.lines) == 0 {
+		return TextHit{}
+	}
+	if layout.direction.isVertical() {
+		return self.layoutVertHitTest(layout, textLen, x, y, point)
+	}
+
+	// find the line
+	x, y = self.layoutDrawOrigin(layout, x, y)
+	line := &layout.lines[len(layout.lines)-1]
+	for i := range layout.lines {
+		if point.Y < y+layout.lines[i].baseline+layout.lines[i].descent {
+			line = &layout.lines[i]
+			break
+		}
+	}
+	if line.orderStart == line.orderEnd {
+		return layout.emptyLineHit(line, textLen)
+	}
+
+	// find the glyph (the last one starting before the point)
+	positions := self.layoutLineGlyphPositions(layout, line, x)
+	var k int
+	for k < len(positions)-1 && positions[k+1] <= point.X {
+		k += 1
+	}
+	index := layout.order[line.orderStart+k]
+	lrune := &layout.runes[index]
+	center := positions[k] + ((lrune.advance + lrune.spacing) >> 1)
+	trailing := (point.X >= center)
+	if lrune.level&1 == 1 {
+		trailing = !trailing
+	}
+	return layout.runeHit(line, index, trailing, textLen)
+}
+
+// Returns the text hit for a point on the given empty line.
+func (self *textLayout) emptyLineHit(line *layoutLine, textLen int) TextHit {
+	index := self.runeByteIndex(line.runeStart, textLen)
+	return TextHit{ByteIndex: index, RuneIndex: line.runeStart, CaretIndex: index}
+}
+
+// Returns the text hit for the rune with the given index in the given line.
+func (self *textLayout) runeHit(line *layoutLine, index int, trailing bool, textLen int) TextHit {
+	// inserted hyphens are mapped to the end of the line
+	if index >= self.numTextRunes {
+		index, trailing = line.runeEnd-1, true
+	}
+
+	hit := TextHit{
+		ByteIndex: self.runes[index].byteIndex,
+		RuneIndex: index,
+		Trailing:  trailing,
+	}
+	hit.CaretIndex = hit.ByteIndex
+	if trailing {
+		hit.CaretIndex = self.runeByteIndex(index+1, textLen)
+	}
+	return hit
+}
+
+// Returns the caret rect for the given byte index, for a layout drawn
+// at (x, y). The rect has zero width and spans the line's height.
+func (self *Renderer) layoutCaretRect(layout *textLayout, byteIndex int, x, y fract.Unit) fract.Rect {
+	if len(layout.lines) == 0 {
+		return fract.Rect{}
+	}
+	if layout.direction.isVertical() {
+		return self.layoutVertCaretRect(layout, byteIndex, x, y)
+	}
+
+	// find the caret position: the leading edge of the first glyph at or
+	// after the rune, or the trailing edge of the last glyph before it
+	index, line := layout.caretLine(byteIndex)
+	x, y = self.layoutDrawOrigin(layout, x, y)
+	var caretX fract.Unit
+	positions := self.layoutLineGlyphPositions(layout, line, x)
+	next, prev := -1, -1
+	for k, runeIndex := range layout.order[line.orderStart:line.orderEnd] {
+		if runeIndex >= line.runeEnd {
+			continue // inserted hyphen
+		}
+		if runeIndex >= index && (next == -1 || runeIndex < layout.order[line.orderStart+next]) {
+			next = k
+		} else if runeIndex < index && (prev == -1 || runeIndex > layout.order[line.orderStart+prev]) {
+			prev = k
+		}
+	}
+	if next != -1 {
+		lrune := &layout.runes[layout.order[line.orderStart+next]]
+		caretX = positions[next]
+		if lrune.level&1 == 1 {
+			caretX += lrune.advance + lrune.spacing
+		}
+	} else if prev != -1 {
+		lrune := &layout.runes[layout.order[line.orderStart+prev]]
+		caretX = positions[prev]
+		if lrune.level&1 == 0 {
+			caretX += lrune.advance + lrune.spacing
+		}
+	} else {
+		fromRight := layout.fromRight(line, self.state.align.Horz())
+		caretX = self.layoutLineStartX(layout, line, x, fromRight)
+	}
+
+	baseline := y + line.baseline
+	return fract.UnitsToRect(caretX, baseline-line.ascent, caretX, baseline+line.descent)
+}
+
+// Returns the index of the rune at or after the given byte index and
+// the line where the caret for it must be placed. At wrap boundaries,
+// carets are placed at the start of the next line.
+// Precondition: len(self.lines) > 0.
+func (self *textLayout) caretLine(byteIndex int) (int, *layoutLine) {
+	index := self.numTextRunes
+	for i := 0; i < self.numTextRunes; i++ {
+		if self.runes[i].byteIndex >= byteIndex {
+			index = i
+			break
+		}
+	}
+	line := &self.lines[0]
+	for i := range self.lines {
+		if self.lines[i].runeStart > index {
+			break
+		}
+		line = &self.lines[i]
+	}
+	return index, line
+}
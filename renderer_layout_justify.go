@@ -0,0 +1,96 @@
+package etxt
+
+import "github.com/tinne26/etxt/fract"
+
+// Justification for the layout process. See renderer_layout.go.
+
+// Returns whether the given line has to be justified based on the
+// current horizontal align.
+func (self *Renderer) layoutShouldJustify(line *layoutLine) bool {
+	switch self.state.align.Horz() {
+	case Justify:
+		return line.change.IsWrap
+	case JustifyAll:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stretches the line to the layout's width limit by distributing the
+// remaining width across inter-word spaces. If the line has no spaces,
+// letter spacing is used instead, up to the configured maximum (see
+// RendererLayout.SetJustifyLetterSpacing()).
+//
+// Glyph positions are quantized during traversal, so the extra width is
+// distributed in steps of the horizontal quantization, with the remainder
+// going to the first gaps. Otherwise, the rounding would make lines
+// overflow the width limit.
+//
+// Precondition: line order, kerning and width already computed.
+func (self *Renderer) layoutJustifyLine(layout *textLayout, line *layoutLine) {
+	extra := layout.widthLimit - line.width
+	if extra <= 0 {
+		return
+	}
+
+	// ignore leading and trailing spaces in visual order
+	order := layout.order[line.orderStart:line.orderEnd]
+	first, last := 0, len(order)-1
+	for first <= last && layout.runes[order[first]].codePoint == ' ' {
+		first += 1
+	}
+	for last >= first && layout.runes[order[last]].codePoint == ' ' {
+		last -= 1
+	}
+	if first >= last {
+		return // no gaps to stretch
+	}
+	order = order[first : last+1]
+
+	// distribute extra width across spaces
+	horzQuant := fract.Unit(self.state.horzQuantization)
+	steps := extra / horzQuant
+	var spaces int
+	for _, index := range order {
+		if layout.runes[index].codePoint == ' ' {
+			spaces += 1
+		}
+	}
+	if spaces > 0 {
+		spacing, remainder := steps/fract.Unit(spaces), steps%fract.Unit(spaces)
+		for _, index := range order {
+			lrune := &layout.runes[index]
+			if lrune.codePoint != ' ' {
+				continue
+			}
+			lrune.spacing = spacing * horzQuant
+			if remainder > 0 {
+				lrune.spacing += horzQuant
+				remainder -= 1
+			}
+		}
+		line.width = self.layoutLineWidth(layout, line)
+		return
+	}
+
+	// distribute extra width between letters, if allowed
+	maxSpacing := self.layoutOptions.justifyLetterSpacing.MulDown(self.state.scale) / horzQuant
+	if maxSpacing == 0 {
+		return
+	}
+	gaps := fract.Unit(len(order) - 1)
+	spacing, remainder := steps/gaps, steps%gaps
+	if spacing >= maxSpacing {
+		spacing, remainder = maxSpacing, 0
+	}
+	for _, index := range order[:len(order)-1] {
+		lrune := &layout.runes[index]
+		lrune.spacing = spacing * horzQuant
+		if remainder > 0 {
+			lrune.spacing += horzQuant
+			remainder -= 1
+		}
+	}
+	line.width = self.layoutLineWidth(layout, line)
+}
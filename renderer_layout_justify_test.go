@@ -0,0 +1,80 @@
+package etxt
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestJustify(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Fract().SetHorzQuantization(QtNone)
+	text := "hello world, how are you doing? fine, thanks for asking.\nsee you soon"
+	limit := renderer.Measure("hello world, how are").Width() + fract.One
+
+	for _, dir := range []Direction{LeftToRight, RightToLeft, BidiLeftToRight, BidiAuto} {
+		renderer.SetDirection(dir)
+		for _, align := range []Align{Justify, JustifyAll} {
+			renderer.SetAlign(align)
+			renderer.layoutString(&renderer.layout, text, true, limit)
+			lines := renderer.layout.lines
+			if len(lines) < 3 {
+				t.Fatalf("expected at least 3 lines, got %d", len(lines))
+			}
+			for i, line := range lines {
+				justified := (align == JustifyAll || line.change.IsWrap)
+				if justified && line.width != limit {
+					t.Fatalf("dir %s, align %s: line #%d width %d, expected %d", dir, align, i, line.width, limit)
+				}
+				if !justified && line.width >= limit {
+					t.Fatalf("dir %s, align %s: line #%d unexpectedly justified", dir, align, i)
+				}
+			}
+		}
+	}
+
+	// letter spacing fallback
+	renderer.SetDirection(LeftToRight)
+	renderer.SetAlign(JustifyAll)
+	renderer.layoutString(&renderer.layout, "word", true, limit)
+	if renderer.layout.lines[0].width == limit {
+		t.Fatal("unexpected letter spacing")
+	}
+	renderer.Layout().SetJustifyLetterSpacing(9999)
+	renderer.layoutString(&renderer.layout, "word", true, limit)
+	if renderer.layout.lines[0].width != limit {
+		t.Fatalf("expected letter spacing to fill the line (%d vs %d)", renderer.layout.lines[0].width, limit)
+	}
+	renderer.Layout().SetJustifyLetterSpacing(1)
+	expected := renderer.Measure("word").Width() + 3*fract.One
+	renderer.layoutString(&renderer.layout, "word", true, limit)
+	if renderer.layout.lines[0].width != expected {
+		t.Fatalf("expected limited letter spacing (%d vs %d)", renderer.layout.lines[0].width, expected)
+	}
+
+	// measuring
+	renderer.SetAlign(Justify)
+	rect := renderer.MeasureWithWrap(text, limit.ToIntCeil())
+	if rect.Width() != fract.FromInt(limit.ToIntCeil()) {
+		t.Fatalf("expected width %d, got %d", fract.FromInt(limit.ToIntCeil()), rect.Width())
+	}
+
+	// quantized glyph positions must not overflow the width limit
+	renderer.Fract().SetHorzQuantization(QtFull)
+	renderer.SetSize(14)
+	limit = fract.FromInt(104)
+	for _, dir := range []Direction{LeftToRight, RightToLeft} {
+		renderer.SetDirection(dir)
+		renderer.layoutString(&renderer.layout, text, true, limit)
+		for i, line := range renderer.layout.lines {
+			if line.change.IsWrap && (line.width > limit || line.width <= limit-QtFull) {
+				t.Fatalf("dir %s, QtFull: line #%d width %d, expected %d or slightly less", dir, i, line.width, limit)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestRenderLayers(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Utils().SetCache8MiB()
+	target := image.NewRGBA(image.Rect(0, 0, 256, 256))
+
+	type drawCall struct {
+		color      color.Color
+		rasterizer mask.Rasterizer
+		origin     fract.Point
+	}
+	var calls []drawCall
+	renderer.Glyph().SetDrawFunc(func(target Target, glyph sfnt.GlyphIndex, origin fract.Point) {
+		calls = append(calls, drawCall{renderer.GetColor(), renderer.Glyph().GetRasterizer(), origin})
+	})
+
+	// reference draw without layers
+	renderer.SetColor(color.White)
+	renderer.Draw(target, "abc", 128, 128)
+	reference := append([]drawCall(nil), calls...)
+	width := renderer.Measure("abc").Width()
+
+	// shadow, outline and fill layers
+	red := color.RGBA{255, 0, 0, 255}
+	var stroke mask.StrokeRasterizer
+	defaultRast := renderer.Glyph().GetRasterizer()
+	renderer.Layout().SetLayers(
+		RenderLayer{Color: red, OffsetX: 2, OffsetY: 3},
+		RenderLayer{Rasterizer: &stroke},
+		RenderLayer{},
+	)
+	calls = calls[:0]
+	renderer.Draw(target, "abc", 128, 128)
+	if len(calls) != 3*len(reference) {
+		t.Fatalf("expected %d glyph draws, got %d", 3*len(reference), len(calls))
+	}
+	for i, call := range calls {
+		layer, ref := i/len(reference), reference[i%len(reference)]
+		expected := drawCall{color.White, defaultRast, ref.origin}
+		switch layer {
+		case 0:
+			expected.color = red
+			expected.origin = ref.origin.AddUnits(fract.FromInt(2), fract.FromInt(3))
+		case 1:
+			expected.rasterizer = &stroke
+		}
+		if call != expected {
+			t.Fatalf("layer #%d, glyph #%d: expected %v, got %v", layer, i%len(reference), expected, call)
+		}
+	}
+
+	// layers don't affect measuring nor the renderer state
+	if renderer.Measure("abc").Width() != width {
+		t.Fatalf("layers affected the measured width")
+	}
+	if renderer.GetColor() != color.White || renderer.Glyph().GetRasterizer() != defaultRast {
+		t.Fatalf("expected renderer state to be restored")
+	}
+
+	// layer colors override highlights, nil colors don't
+	renderer.Layout().SetHighlight(0, 1, color.Black)
+	calls = calls[:0]
+	renderer.Draw(target, "abc", 128, 128)
+	if calls[0].color != red || calls[len(reference)].color != color.Black {
+		t.Fatalf("unexpected highlight colors %v and %v", calls[0].color, calls[len(reference)].color)
+	}
+
+	renderer.Layout().SetLayers()
+	if renderer.Layout().GetLayers() != nil {
+		t.Fatalf("expected layers to be cleared")
+	}
+}
@@ -0,0 +1,150 @@
+package etxt
+
+import (
+	"unicode"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+// Line break opportunities, soft hyphens and hyphenation for the
+// layout process. See renderer_layout.go and linebreak.go.
+
+// Sets the line break opportunities for the runes in [start, end),
+// which shouldn't contain any line breaks. If the renderer has a
+// hyphenator, hyphenation points are also added.
+func (self *Renderer) layoutResolveLineBreaks(layout *textLayout, start, end int) {
+	layout.codePoints = layout.codePoints[:0]
+	for i := start; i < end; i++ {
+		layout.codePoints = append(layout.codePoints, layout.runes[i].codePoint)
+	}
+	layout.breakable = ensureSliceSize(layout.breakable, len(layout.runes))
+	layout.lineBreaker.resolve(layout.codePoints, layout.breakable[start:end])
+
+	// hyphenation points
+	layout.hyphenPoints = ensureSliceSize(layout.hyphenPoints, len(layout.runes)+1)
+	for i := start; i <= end; i++ {
+		layout.hyphenPoints[i] = false
+	}
+	hyphenator := self.layoutOptions.hyphenator
+	if hyphenator == nil {
+		return
+	}
+	wordStart := -1
+	for i := start; i <= end; i++ {
+		if i < end && unicode.IsLetter(layout.runes[i].codePoint) {
+			if wordStart == -1 {
+				wordStart = i
+			}
+			continue
+		}
+		if wordStart == -1 {
+			continue
+		}
+
+		// hyphenate word in [wordStart, i)
+		word := layout.codePoints[wordStart-start : i-start]
+		layout.wordPoints = ensureSliceSize(layout.wordPoints, len(word))
+		points := layout.wordPoints[:len(word)]
+		for j := range points {
+			points[j] = false
+		}
+		hyphenator.Hyphenate(word, points)
+		for j := 1; j < len(points); j++ {
+			if points[j] && !layout.breakable[wordStart+j] {
+				layout.breakable[wordStart+j] = true
+				layout.hyphenPoints[wordStart+j] = true
+			}
+		}
+		wordStart = -1
+	}
+}
+
+// Returns whether a line can be wrapped before the rune at the given
+// index. Precondition: layoutResolveLineBreaks() called on the paragraph.
+func (self *textLayout) canWrapBefore(index int) bool {
+	return self.breakable[index]
+}
+
+// Returns whether a line can be wrapped right after the space at the
+// given index. The end is the end of the paragraph.
+func (self *textLayout) canWrapAfterSpace(index, end int) bool {
+	return index+1 == end || self.breakable[index+1]
+}
+
+// Returns whether a line wrapped before the given index would fit
+// within the width limit, taking into account the hyphen that would
+// be shown if the line ended with a soft hyphen or at a hyphenation
+// point. The x value is the position reached by the line content,
+// as in layoutWrapLine().
+func (self *Renderer) layoutWrapFits(layout *textLayout, index int, x, widthLimit fract.Unit, rtl bool) bool {
+	if layout.runes[index-1].codePoint != '\u00AD' && !layout.hyphenPoints[index] {
+		return true
+	}
+	glyph, ok := self.layoutHyphenGlyph(layout, index-1)
+	if !ok {
+		return true
+	}
+	advance := self.getOpAdvance(glyph)
+	if rtl {
+		advance = -advance
+	}
+	horzQuant := fract.Unit(self.state.horzQuantization)
+	return !layoutWrapExceeds(x+advance, widthLimit, horzQuant)
+}
+
+// Returns the glyph to be shown when wrapping a line after the rune
+// with the given index, which is a soft hyphen or the last letter
+// before a hyphenation point. The style of the rune is applied on
+// the renderer.
+func (self *Renderer) layoutHyphenGlyph(layout *textLayout, index int) (GlyphIndex, bool) {
+	style := &layout.styles[layout.runes[index].style]
+	self.layoutApplyStyle(style)
+	glyph, err := style.font.GlyphIndex(&self.buffer, '-')
+	if err != nil {
+		panic("font.GlyphIndex error: " + err.Error())
+	}
+	return glyph, glyph != 0
+}
+
+// Makes the soft hyphen at the end of the given wrapped line visible,
+// or inserts a hyphen rune if the line was wrapped at a hyphenation
+// point. Must be called before setting the line visual order.
+func (self *Renderer) layoutShowHyphen(layout *textLayout, line *layoutLine) {
+	if !line.change.IsWrap || line.runeEnd == line.runeStart {
+		return
+	}
+
+	if line.change.InsertedHyphen {
+		glyph, ok := self.layoutHyphenGlyph(layout, line.runeEnd-1)
+		if !ok {
+			line.change.InsertedHyphen = false
+			return
+		}
+		hyphen := layout.runes[line.runeEnd-1] // (byteIndex is kept too)
+		hyphen.codePoint = '-'
+		hyphen.glyph = glyph
+		hyphen.advance = self.getOpAdvance(glyph)
+		hyphen.spacing = 0
+		hyphen.skip = false
+		if layout.direction.isVertical() {
+			self.layoutVertRune(layout, &hyphen)
+		}
+		line.hyphenRune = len(layout.runes)
+		layout.runes = append(layout.runes, hyphen)
+		return
+	}
+
+	lrune := &layout.runes[line.runeEnd-1]
+	if lrune.codePoint != '\u00AD' {
+		return
+	}
+	glyph, ok := self.layoutHyphenGlyph(layout, line.runeEnd-1)
+	if ok {
+		lrune.glyph = glyph
+		lrune.advance = self.getOpAdvance(glyph)
+		lrune.skip = false
+		if layout.direction.isVertical() {
+			self.layoutVertRune(layout, lrune)
+		}
+	}
+}
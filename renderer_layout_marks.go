@@ -0,0 +1,135 @@
+package etxt
+
+import (
+	"github.com/tinne26/etxt/font"
+	"github.com/tinne26/etxt/fract"
+)
+
+// Mark attachment for the layout process. See renderer_layout.go.
+//
+// When the font of a combining mark has glyph positioning data (see
+// RendererGlyph.SetPositioning()), the mark is attached to the preceding
+// base glyph or mark in logical order: its advance is removed, it's
+// skipped for kerning, and it's drawn at its anchor position relative
+// to the base origin. Attachments are resolved before line wrapping,
+// but the final offsets from the traversal positions can only be set
+// once kerning and spacing are known. Only horizontal text is supported.
+
+// Attaches marks to their base glyphs and removes their advances.
+// Precondition: layout runes already added.
+func (self *Renderer) layoutAttachMarks(layout *textLayout) {
+	if len(self.positioning) == 0 {
+		return
+	}
+
+	initStyle := self.layoutCurrentStyle()
+	base := -1
+	for i := range layout.runes {
+		lrune := &layout.runes[i]
+		if lrune.skip || lrune.codePoint == '\n' || lrune.codePoint == '\t' {
+			base = -1
+			continue
+		}
+		style := &layout.styles[lrune.style]
+		positioning := self.positioning[style.font]
+		if base == -1 || positioning == nil || !positioning.IsMark(lrune.glyph) {
+			base = i
+			continue
+		}
+		baseStyle := &layout.styles[layout.runes[base].style]
+		if baseStyle.font != style.font || baseStyle.logicalSize != style.logicalSize {
+			base = i
+			continue
+		}
+		self.layoutApplyStyle(style)
+		self.layoutAttachMark(layout, i, base, positioning)
+	}
+	self.layoutApplyStyle(&initStyle)
+}
+
+// Attaches the given mark rune to the previous mark if possible, or
+// to the given base rune otherwise. If the mark can't be attached, the
+// rune is left unchanged. Precondition: the rune style must be applied.
+func (self *Renderer) layoutAttachMark(layout *textLayout, index, base int, positioning *font.GlyphPositioning) {
+	lrune, prev := &layout.runes[index], &layout.runes[index-1]
+	unitsPerEm := int64(self.state.activeFont.UnitsPerEm())
+	scale := func(value int) fract.Unit {
+		return fract.Unit(int64(value) * int64(self.state.scaledSize) / unitsPerEm)
+	}
+
+	if prev.attached {
+		x, y, ok := positioning.MarkOffset(prev.glyph, lrune.glyph)
+		if ok {
+			lrune.attach = prev.attach.AddUnits(scale(x), -scale(y))
+			lrune.base, lrune.attached = base, true
+			lrune.advance = 0
+			return
+		}
+	}
+	x, y, ok := positioning.MarkOffset(layout.runes[base].glyph, lrune.glyph)
+	if ok {
+		lrune.attach = fract.UnitsToPoint(scale(x), -scale(y))
+		lrune.base, lrune.attached = base, true
+		lrune.advance = 0
+	}
+}
+
+// Sets the origin offsets of the attached marks of the line, relative
+// to their traversal positions. Marks whose base is not in the same line
+// are drawn at their traversal positions. Precondition: line order,
+// kerning and spacing already computed.
+func (self *Renderer) layoutPlaceMarksLine(layout *textLayout, line *layoutLine) {
+	if len(self.positioning) == 0 {
+		return
+	}
+
+	// the base comes right before its marks in visual order for
+	// left-to-right runs, and right after them for right-to-left runs
+	order := layout.order[line.orderStart:line.orderEnd]
+	for i, index := range order {
+		lrune := &layout.runes[index]
+		if !lrune.attached {
+			continue
+		}
+		lrune.offset = fract.Point{}
+		if lrune.level&1 == 0 {
+			for j := i - 1; j >= 0; j-- {
+				if order[j] == lrune.base {
+					distance := layoutTraversalDistance(layout, order[j:i+1])
+					lrune.offset = lrune.attach.AddUnits(-distance, 0)
+					break
+				}
+				if !layout.runes[order[j]].attached {
+					break
+				}
+			}
+		} else {
+			for j := i + 1; j < len(order); j++ {
+				if order[j] == lrune.base {
+					distance := layoutTraversalDistance(layout, order[i:j+1])
+					lrune.offset = lrune.attach.AddUnits(distance, 0)
+					break
+				}
+				if !layout.runes[order[j]].attached {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Returns the distance between the traversal positions of the first
+// and last runes in the given visual order slice, without quantization.
+func layoutTraversalDistance(layout *textLayout, order []int) fract.Unit {
+	var distance fract.Unit
+	for i, index := range order {
+		lrune := &layout.runes[index]
+		if i > 0 {
+			distance += lrune.kern
+		}
+		if i < len(order)-1 {
+			distance += lrune.advance + lrune.spacing
+		}
+	}
+	return distance
+}
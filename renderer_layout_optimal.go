@@ -0,0 +1,138 @@
+package etxt
+
+import "github.com/tinne26/etxt/fract"
+
+// Optimal line breaking for the layout process. See renderer_layout.go.
+//
+// The algorithm is a simplified version of the Knuth-Plass total-fit
+// line breaking. Since there's no automatic hyphenation nor glue
+// shrinking, the only feasible breakpoints are the line break
+// opportunities found with UAX #14, and each line is evaluated
+// based on the width it leaves unused. The combination of breakpoints
+// with the least total demerits is found through dynamic programming.
+
+const layoutForcedBadness = 10000 // for lines exceeding the width limit
+const layoutHyphenPenalty = 50    // for lines ending at hyphenation points
+
+// Line break candidate for optimal line wrapping. Each candidate
+// corresponds to a potential line start within a paragraph.
+type layoutBreak struct {
+	demerits float64    // total demerits to reach this break
+	line     layoutLine // line ending at this break
+	from     int        // previous break index
+	reached  bool
+}
+
+// Line wrapping for the runes in [start, end), which shouldn't
+// contain any line breaks, minimizing the total demerits of the
+// paragraph lines.
+func (self *Renderer) layoutWrapParagraphOptimal(layout *textLayout, start, end int, rtl bool, widthLimit fract.Unit) {
+	horzQuant := fract.Unit(self.state.horzQuantization)
+	linePenalty := self.layoutOptions.linePenalty
+	tolerance := fract.Unit(float64(widthLimit) * self.layoutOptions.tolerance)
+
+	// break k corresponds to a line starting at rune start + k,
+	// while the last break is used for the paragraph end
+	numBreaks := end - start + 2
+	layout.breaks = ensureSliceSize(layout.breaks, numBreaks)
+	breaks := layout.breaks[:numBreaks]
+	for k := range breaks {
+		breaks[k] = layoutBreak{}
+	}
+	breaks[0].reached = true
+	final := numBreaks - 1
+
+	relax := func(from, to int, demerits float64, line layoutLine) {
+		demerits += breaks[from].demerits
+		if !breaks[to].reached || demerits < breaks[to].demerits {
+			breaks[to] = layoutBreak{demerits: demerits, line: line, from: from, reached: true}
+		}
+	}
+
+	for k := 0; k < final; k++ {
+		if !breaks[k].reached {
+			continue
+		}
+
+		// explore all feasible lines starting at this break
+		lineStart := start + k
+		var x fract.Unit
+		var prev int = -1
+		var feasible bool
+		var i int
+		for i = lineStart; i < end; i++ {
+			lrune := &layout.runes[i]
+			if i > lineStart && layout.canWrapBefore(i) && layout.runes[i-1].codePoint != ' ' {
+				if self.layoutWrapFits(layout, i, x, widthLimit, rtl) {
+					line := layout.wrappedLine(lineStart, i)
+					badness := layoutBadness(widthLimit-x.Abs(), tolerance)
+					demerits := (linePenalty + badness) * (linePenalty + badness)
+					if line.change.InsertedHyphen {
+						demerits += layoutHyphenPenalty * layoutHyphenPenalty
+					}
+					relax(k, i-start, demerits, line)
+					feasible = true
+				}
+			}
+			if lrune.skip {
+				continue
+			}
+
+			if lrune.codePoint == ' ' && i > lineStart && layout.canWrapAfterSpace(i, end) {
+				line := layout.wrappedLine(lineStart, i+1)
+				badness := layoutBadness(widthLimit-x.Abs(), tolerance)
+				relax(k, i+1-start, (linePenalty+badness)*(linePenalty+badness), line)
+				feasible = true
+			}
+
+			x = self.layoutAdvanceWrapX(layout, x, prev, i, rtl)
+			if layoutWrapExceeds(x, widthLimit, horzQuant) {
+				break
+			}
+			prev = i
+		}
+
+		// natural paragraph end
+		if i >= end {
+			line := layoutLine{runeStart: lineStart, runeEnd: end}
+			relax(k, final, linePenalty*linePenalty, line)
+			continue
+		}
+
+		// forced break when no candidates are feasible (single
+		// words exceeding the limit), same as on greedy wrapping
+		if !feasible {
+			line, next := self.layoutWrapLine(layout, lineStart, end, rtl, widthLimit)
+			demerits := (linePenalty + layoutForcedBadness) * (linePenalty + layoutForcedBadness)
+			if line.change.IsWrap {
+				relax(k, next-start, demerits, line)
+			} else {
+				relax(k, final, demerits, line)
+			}
+		}
+	}
+
+	// backtrack from the paragraph end and append lines in order
+	firstLine := len(layout.lines)
+	for k := final; k != 0; k = breaks[k].from {
+		layout.lines = append(layout.lines, breaks[k].line)
+	}
+	lines := layout.lines[firstLine:]
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+}
+
+// Returns the badness of a line with the given unused width. The
+// badness is 100 when the slack matches the tolerance, and grows
+// cubically with their ratio, like in TeX.
+func layoutBadness(slack, tolerance fract.Unit) float64 {
+	if slack <= 0 {
+		return 0
+	}
+	if tolerance <= 0 {
+		return layoutForcedBadness
+	}
+	ratio := float64(slack) / float64(tolerance)
+	return 100 * ratio * ratio * ratio
+}
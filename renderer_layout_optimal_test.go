@@ -0,0 +1,108 @@
+package etxt
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestOptimalLineBreaking(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Fract().SetHorzQuantization(QtNone)
+	text := "aaa bb cc ddddd"
+	limit := renderer.Measure("aaa bb").Width() + fract.One
+
+	// helper to compute the raggedness of all lines except the last
+	raggedness := func(lines []layoutLine) fract.Unit {
+		var total fract.Unit
+		for _, line := range lines[:len(lines)-1] {
+			slack := (limit - line.width) >> 6
+			total += slack * slack
+		}
+		return total
+	}
+
+	for _, dir := range []Direction{LeftToRight, RightToLeft, BidiAuto} {
+		renderer.SetDirection(dir)
+		renderer.Layout().SetLineBreaking(LineBreakGreedy)
+		renderer.layoutString(&renderer.layout, text, true, limit)
+		greedyLines := append([]layoutLine(nil), renderer.layout.lines...)
+
+		renderer.Layout().SetLineBreaking(LineBreakOptimal)
+		renderer.layoutString(&renderer.layout, text, true, limit)
+		optimalLines := renderer.layout.lines
+		if len(optimalLines) != len(greedyLines) {
+			t.Fatalf("dir %s: expected %d lines, got %d", dir, len(greedyLines), len(optimalLines))
+		}
+		for i, line := range optimalLines {
+			if line.width > limit {
+				t.Fatalf("dir %s: line #%d exceeds the width limit", dir, i)
+			}
+		}
+		if raggedness(optimalLines) >= raggedness(greedyLines) {
+			t.Fatalf("dir %s: expected optimal line breaking to reduce raggedness", dir)
+		}
+		if optimalLines[0].runeEnd != 3 { // "aaa" / "bb cc" / "ddddd"
+			t.Fatalf("dir %s: unexpected first line break at %d", dir, optimalLines[0].runeEnd)
+		}
+	}
+
+	// long words and empty paragraphs
+	renderer.SetDirection(LeftToRight)
+	text = "supercalifragilistic a\n\nb"
+	renderer.layoutString(&renderer.layout, text, true, renderer.Measure("super").Width())
+	optimalLines := append([]layoutLine(nil), renderer.layout.lines...)
+	renderer.Layout().SetLineBreaking(LineBreakGreedy)
+	renderer.layoutString(&renderer.layout, text, true, renderer.Measure("super").Width())
+	if len(optimalLines) != len(renderer.layout.lines) {
+		t.Fatalf("expected %d lines, got %d", len(renderer.layout.lines), len(optimalLines))
+	}
+	for i, line := range optimalLines {
+		if line.runeStart != renderer.layout.lines[i].runeStart || line.runeEnd != renderer.layout.lines[i].runeEnd {
+			t.Fatalf("line #%d mismatch: %+v vs %+v", i, line, renderer.layout.lines[i])
+		}
+	}
+
+	// lines must be the same as on greedy wrapping for the same breaks
+	text = "aaa   bb  cc    ddddd"
+	var compared int
+	for limit := fract.Unit(0); limit < renderer.Measure(text).Width(); limit += fract.One {
+		renderer.Layout().SetLineBreaking(LineBreakGreedy)
+		renderer.layoutString(&renderer.layout, text, true, limit)
+		greedyLines := append([]layoutLine(nil), renderer.layout.lines...)
+		renderer.Layout().SetLineBreaking(LineBreakOptimal)
+		renderer.layoutString(&renderer.layout, text, true, limit)
+		optimalLines = renderer.layout.lines
+		if len(optimalLines) != len(greedyLines) || len(optimalLines) < 2 {
+			continue
+		}
+		sameBreaks := true
+		for i, line := range optimalLines {
+			sameBreaks = sameBreaks && (line.runeStart == greedyLines[i].runeStart)
+		}
+		if !sameBreaks {
+			continue
+		}
+		compared += 1
+		for i, line := range optimalLines {
+			if line != greedyLines[i] {
+				t.Fatalf("limit %d, line #%d mismatch: %+v vs %+v", limit, i, line, greedyLines[i])
+			}
+		}
+	}
+	if compared == 0 {
+		t.Fatal("expected greedy and optimal wrapping to share some breaks")
+	}
+
+	// measuring
+	renderer.Layout().SetLineBreaking(LineBreakOptimal)
+	rect := renderer.MeasureWithWrap("aaa bb cc ddddd", limit.ToIntCeil())
+	if rect.Height() != renderer.Measure("aaa\nbb cc\nddddd").Height() {
+		t.Fatalf("unexpected measure height %d", rect.Height())
+	}
+}
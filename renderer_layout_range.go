@@ -0,0 +1,58 @@
+package etxt
+
+import (
+	"github.com/tinne26/etxt/fract"
+)
+
+// Byte range rects and highlighting for the layout process.
+
+// Returns whether the rune is within the byte range [start, end).
+// Inserted hyphens share the byte index of the rune before them.
+func (self *layoutRune) inByteRange(start, end int) bool {
+	return self.byteIndex >= start && self.byteIndex < end
+}
+
+// Appends the rects covering the glyphs in the byte range [start, end)
+// to the given slice, for a layout drawn at (x, y). Rects span the full
+// line heights, and consecutive glyphs in visual order are merged.
+func (self *Renderer) layoutRangeRects(layout *textLayout, start, end int, x, y fract.Unit, rects []fract.Rect) []fract.Rect {
+	if start >= end {
+		return rects
+	}
+	if layout.direction.isVertical() {
+		return self.layoutVertRangeRects(layout, start, end, x, y, rects)
+	}
+
+	x, y = self.layoutDrawOrigin(layout, x, y)
+	for i := range layout.lines {
+		line := &layout.lines[i]
+		if line.orderStart == line.orderEnd {
+			continue
+		}
+
+		var inRun bool
+		var runStart, runEnd fract.Unit
+		top := y + line.baseline - line.ascent
+		bottom := y + line.baseline + line.descent
+		positions := self.layoutLineGlyphPositions(layout, line, x)
+		for k, index := range layout.order[line.orderStart:line.orderEnd] {
+			lrune := &layout.runes[index]
+			if !lrune.inByteRange(start, end) {
+				if inRun {
+					rects = append(rects, fract.UnitsToRect(runStart, top, runEnd, bottom))
+					inRun = false
+				}
+				continue
+			}
+			if !inRun {
+				runStart = positions[k]
+				inRun = true
+			}
+			runEnd = positions[k] + lrune.advance + lrune.spacing
+		}
+		if inRun {
+			rects = append(rects, fract.UnitsToRect(runStart, top, runEnd, bottom))
+		}
+	}
+	return rects
+}
@@ -0,0 +1,56 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestHighlight(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Utils().SetCache8MiB()
+	target := image.NewRGBA(image.Rect(0, 0, 256, 256))
+
+	var colors []color.Color
+	renderer.Glyph().SetDrawFunc(func(target Target, glyph sfnt.GlyphIndex, origin fract.Point) {
+		colors = append(colors, renderer.GetColor())
+	})
+
+	red := color.RGBA{255, 0, 0, 255}
+	renderer.SetColor(color.White)
+	renderer.Layout().SetHighlight(2, 7, red)
+	for _, dir := range []Direction{LeftToRight, RightToLeft} {
+		renderer.SetDirection(dir)
+		colors = colors[:0]
+		renderer.Draw(target, "abc defg", 128, 128)
+		if len(colors) != 8 {
+			t.Fatalf("dir %s: expected 8 glyphs, got %d", dir, len(colors))
+		}
+		for i, clr := range colors {
+			index := i
+			if dir == RightToLeft {
+				index = len(colors) - 1 - i
+			}
+			expected := color.Color(color.White)
+			if index >= 2 && index < 7 {
+				expected = red
+			}
+			if clr != expected {
+				t.Fatalf("dir %s: glyph #%d: expected color %v, got %v", dir, i, expected, clr)
+			}
+		}
+	}
+	if renderer.GetColor() != color.White {
+		t.Fatalf("expected renderer color to be restored")
+	}
+}
@@ -0,0 +1,112 @@
+package etxt
+
+import (
+	"strings"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+// Tab stops for the layout process. See RendererLayout.SetTabStops().
+//
+// Tab runes have no glyph, only an advance that depends on their
+// position within the line. During line wrapping, tabs are treated
+// as if all stops were left aligned, and once lines are known, the
+// advances are recomputed with the actual stop aligns. Since right,
+// center and decimal aligns can only shorten the advances, wrapped
+// lines never grow past the width limit because of this.
+
+// Returns the position and align of the first tab stop after the given
+// distance from the start of the line.
+func (self *Renderer) layoutNextTabStop(x fract.Unit) (fract.Unit, TabAlign) {
+	if self.layoutOptions.tabStops != nil {
+		for _, stop := range *self.layoutOptions.tabStops {
+			position := fract.FromFloat64Up(stop.Position).MulDown(self.state.scale)
+			if position > x {
+				return position, stop.Align
+			}
+		}
+	}
+
+	interval := self.layoutOptions.tabInterval
+	if self.layoutOptions.tabIntervalInEms {
+		interval = interval.MulDown(self.state.scaledSize)
+	} else {
+		interval = interval.MulDown(self.state.scale)
+	}
+	if interval <= 0 {
+		interval = 1
+	}
+	return (x/interval + 1) * interval, TabLeft
+}
+
+// Returns the tab advance used during line wrapping, at the given
+// distance from the start of the line.
+func (self *Renderer) layoutWrapTabAdvance(x fract.Unit) fract.Unit {
+	stop, _ := self.layoutNextTabStop(x)
+	return stop - x
+}
+
+// Sets the advances of the tabs in the given line. The text after each
+// tab, up to the next tab or the end of the line, is aligned to the tab
+// stop. Positions are computed in logical order from the start of the
+// line, so they are only approximate for lines with mixed directions.
+//
+// Precondition: line kerning already computed.
+func (self *Renderer) layoutTabLine(layout *textLayout, line *layoutLine) {
+	var x, stop, segment, decimal fract.Unit
+	var align TabAlign
+	var hasDecimal bool
+	tab := -1
+	resolve := func() {
+		offset := segment
+		switch align {
+		case TabLeft:
+			offset = 0
+		case TabCenter:
+			offset = segment >> 1
+		case TabDecimal:
+			if hasDecimal {
+				offset = decimal
+			}
+		}
+		advance := stop - x - offset
+		if advance < 0 {
+			advance = 0
+		}
+		layout.runes[tab].advance = advance
+		x += advance + segment
+	}
+
+	for i := line.runeStart; i < line.runeEnd; i++ {
+		lrune := &layout.runes[i]
+		if lrune.skip {
+			continue
+		}
+		if lrune.codePoint == '\t' {
+			if tab != -1 {
+				resolve()
+			}
+			stop, align = self.layoutNextTabStop(x)
+			tab, segment, hasDecimal = i, 0, false
+			continue
+		}
+
+		if tab == -1 {
+			x += lrune.kern + lrune.advance
+			continue
+		}
+		if align == TabDecimal && !hasDecimal && lrune.codePoint == '.' {
+			decimal = segment + lrune.kern
+			hasDecimal = true
+		}
+		segment += lrune.kern + lrune.advance
+	}
+	if tab != -1 {
+		resolve()
+	}
+}
+
+// Returns whether the given text contains any tab characters.
+func layoutHasTabs(text string) bool {
+	return strings.IndexByte(text, '\t') != -1
+}
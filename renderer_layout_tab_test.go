@@ -0,0 +1,113 @@
+package etxt
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestTabStops(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.Utils().SetCache8MiB()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(16)
+	renderer.Fract().SetHorzQuantization(QtNone)
+	renderer.Fract().SetVertQuantization(QtNone)
+
+	// default interval is 4 ems
+	word := renderer.Measure("abc").Width()
+	width := renderer.Measure("\tabc").Width()
+	if width != fract.FromInt(64)+word {
+		t.Fatalf("expected width %v, got %v", fract.FromInt(64)+word, width)
+	}
+	width = renderer.Measure("abc\tabc").Width()
+	if width != fract.FromInt(64)+word {
+		t.Fatalf("expected width %v, got %v", fract.FromInt(64)+word, width)
+	}
+
+	// pixel intervals
+	renderer.Layout().SetTabInterval(10)
+	width = renderer.Measure("\t\tabc").Width()
+	if width != fract.FromInt(20)+word {
+		t.Fatalf("expected width %v, got %v", fract.FromInt(20)+word, width)
+	}
+	if interval, ems := renderer.Layout().GetTabInterval(); interval != 10 || ems {
+		t.Fatalf("unexpected interval %v (ems = %t)", interval, ems)
+	}
+
+	// explicit stops, sorted
+	renderer.Layout().SetTabStops(TabStop{200, TabRight}, TabStop{100, TabCenter})
+	stops := renderer.Layout().GetTabStops()
+	if len(stops) != 2 || stops[0].Position != 100 || stops[1].Position != 200 {
+		t.Fatalf("unexpected stops %v", stops)
+	}
+	width = renderer.Measure("\tabc").Width()
+	if width != fract.FromInt(100)+word-word/2 {
+		t.Fatalf("expected width %v, got %v", fract.FromInt(100)+word-word/2, width)
+	}
+	width = renderer.Measure("\tabc\tabc").Width()
+	if width != fract.FromInt(200) {
+		t.Fatalf("expected width 200, got %v", width)
+	}
+
+	// decimal stops
+	renderer.Layout().SetTabStops(TabStop{100, TabDecimal})
+	integer := renderer.Measure("12.").Width() - renderer.Measure(".").Width()
+	width = renderer.Measure("\t12.5").Width()
+	expected := fract.FromInt(100) + renderer.Measure("12.5").Width() - integer
+	if width != expected {
+		t.Fatalf("expected width %v, got %v", expected, width)
+	}
+
+	// without decimal point, text is right aligned. past
+	// the last stop, the interval is used again
+	width = renderer.Measure("\tabc\tabc").Width()
+	if width != fract.FromInt(110)+word {
+		t.Fatalf("expected width %v, got %v", fract.FromInt(110)+word, width)
+	}
+
+	// feeds treat all stops as left aligned
+	renderer.Layout().SetTabStops(TabStop{100, TabRight})
+	feed := NewFeed(renderer)
+	feed.Advance('a')
+	feed.Advance('\t')
+	if feed.Position.X != fract.FromInt(100) {
+		t.Fatalf("expected feed at x = 100, got %v", feed.Position.X)
+	}
+	feed.Advance('\t')
+	if feed.Position.X != fract.FromInt(110) {
+		t.Fatalf("expected feed at x = 110, got %v", feed.Position.X)
+	}
+	renderer.SetDirection(RightToLeft)
+	feed.LineBreakX = fract.FromInt(300)
+	feed.Position.X = fract.FromInt(290)
+	feed.Advance('\t')
+	if feed.Position.X != fract.FromInt(200) {
+		t.Fatalf("expected feed at x = 200, got %v", feed.Position.X)
+	}
+}
+
+func TestTabWrap(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(16)
+	renderer.Layout().SetTabInterval(50)
+
+	lines := renderer.MeasureLinesWithWrap("abc\tdef\tghi", 110)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if line.Rect.Width() > fract.FromInt(110) {
+			t.Fatalf("line %+v exceeds the width limit", line)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package etxt
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+// Text truncation for the layout process. The approach is simple: the
+// text is laid out with an ellipsis replacing different parts of it,
+// and a binary search is used to find the smallest cut that makes the
+// text fit in the given number of lines. This is not the fastest way
+// to do it, but it takes kerning, bidi and line wrapping into account
+// without any special cases.
+
+// Lays out a plain string with wrapping, replacing part of it with an
+// ellipsis if it doesn't fit in maxLines. See RendererLayout.SetTruncation().
+func (self *Renderer) layoutStringTruncated(layout *textLayout, text string, widthLimit fract.Unit, maxLines int) TruncationDetails {
+	self.layoutString(layout, text, true, widthLimit)
+	if len(layout.lines) <= maxLines {
+		return TruncationDetails{}
+	}
+
+	ellipsis := self.layoutEllipsis()
+	fits := func(cutStart, cutEnd int) bool {
+		self.layoutTruncatedString(layout, text, cutStart, cutEnd, ellipsis, widthLimit)
+		return len(layout.lines) <= maxLines
+	}
+
+	// find the smallest cut that fits with a binary search over the
+	// number of bytes to cut (sort.Search() returns the smallest index
+	// for which the function returns true, and we use i + 1 because an
+	// empty cut is already known not to fit)
+	n := len(text)
+	cutRange := func(cut int) (int, int) {
+		switch self.layoutOptions.truncation {
+		case TruncateEnd:
+			return truncateSnapStart(text, n-cut), n
+		case TruncateStart:
+			return 0, truncateSnapEnd(text, cut)
+		case TruncateMiddle:
+			head := (n - cut) >> 1
+			return truncateSnapStart(text, head), truncateSnapEnd(text, head+cut)
+		default:
+			panic(self.layoutOptions.truncation)
+		}
+	}
+	cut := sort.Search(n, func(i int) bool { return fits(cutRange(i + 1)) })
+	cutStart, cutEnd := cutRange(minInt(cut+1, n))
+
+	// don't leave spaces next to the ellipsis
+	for cutStart > 0 {
+		codePoint, size := utf8.DecodeLastRuneInString(text[:cutStart])
+		if !unicode.IsSpace(codePoint) {
+			break
+		}
+		cutStart -= size
+	}
+	for cutEnd < n {
+		codePoint, size := utf8.DecodeRuneInString(text[cutEnd:])
+		if !unicode.IsSpace(codePoint) {
+			break
+		}
+		cutEnd += size
+	}
+
+	self.layoutTruncatedString(layout, text, cutStart, cutEnd, ellipsis, widthLimit)
+	return TruncationDetails{Truncated: true, CutStart: cutStart, CutEnd: cutEnd}
+}
+
+// Same as layoutString() with wrapping, but replacing text[cutStart:cutEnd]
+// with the given ellipsis. The ellipsis isn't part of the original text,
+// so all its runes are mapped to cutStart as if it had zero length.
+func (self *Renderer) layoutTruncatedString(layout *textLayout, text string, cutStart, cutEnd int, ellipsis string, widthLimit fract.Unit) {
+	layout.reset(self.state.textDirection, true)
+	initStyle := self.layoutCurrentStyle()
+	style := layout.addStyle(initStyle)
+	layout.addRunes(self, text[:cutStart], 0, style)
+	ellipsisStart := len(layout.runes)
+	layout.addRunes(self, ellipsis, cutStart, style)
+	for i := ellipsisStart; i < len(layout.runes); i++ {
+		layout.runes[i].byteIndex = cutStart
+	}
+	layout.addRunes(self, text[cutEnd:], cutEnd, style)
+	self.layoutProcess(layout, widthLimit)
+	self.layoutApplyStyle(&initStyle)
+}
+
+// Returns the font's ellipsis character if available, or "..." otherwise.
+func (self *Renderer) layoutEllipsis() string {
+	index, err := self.state.activeFont.GlyphIndex(&self.buffer, '…')
+	if err != nil {
+		panic("font.GlyphIndex error: " + err.Error())
+	}
+	if index == 0 {
+		return "..."
+	}
+	return "…"
+}
+
+// Moves the given byte index back to the start of a rune that's not
+// a combining mark, so the cut doesn't separate marks from their base.
+func truncateSnapStart(text string, index int) int {
+	for index > 0 && index < len(text) && !utf8.RuneStart(text[index]) {
+		index -= 1
+	}
+	for index > 0 && index < len(text) {
+		codePoint, _ := utf8.DecodeRuneInString(text[index:])
+		if !truncateIsMark(codePoint) {
+			break
+		}
+		_, size := utf8.DecodeLastRuneInString(text[:index])
+		index -= size
+	}
+	return index
+}
+
+// Moves the given byte index forward to the start of a rune that's not
+// a combining mark, so the cut doesn't separate marks from their base.
+func truncateSnapEnd(text string, index int) int {
+	for index < len(text) && !utf8.RuneStart(text[index]) {
+		index += 1
+	}
+	for index < len(text) {
+		codePoint, size := utf8.DecodeRuneInString(text[index:])
+		if !truncateIsMark(codePoint) {
+			break
+		}
+		index += size
+	}
+	return index
+}
+
+func truncateIsMark(codePoint rune) bool {
+	return codePoint == '\u200D' || unicode.Is(unicode.M, codePoint)
+}
@@ -0,0 +1,69 @@
+package etxt
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestTruncation(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Fract().SetHorzQuantization(QtNone)
+	ellipsis := renderer.layoutEllipsis()
+
+	tests := []struct {
+		text       string
+		truncation Truncation
+		maxLines   int
+		limit      string // text whose width is used as the width limit
+		expected   TruncationDetails
+	}{
+		{"hello world", TruncateEnd, 1, "hello world", TruncationDetails{}},
+		{"hello world", TruncateEnd, 1, "hello w" + ellipsis, TruncationDetails{true, 7, 11}},
+		{"hello world", TruncateEnd, 1, "hello " + ellipsis, TruncationDetails{true, 5, 11}},
+		{"hello world", TruncateStart, 1, ellipsis + "world", TruncationDetails{true, 0, 6}},
+		{"hello world", TruncateStart, 1, ellipsis + "orld", TruncationDetails{true, 0, 7}},
+		{"hello world", TruncateMiddle, 1, "hel" + ellipsis + "rld", TruncationDetails{true, 3, 8}},
+		{"one two three four", TruncateEnd, 2, "one two", TruncationDetails{true, 13, 18}},
+		{"one\ntwo\nthree", TruncateEnd, 2, "one two", TruncationDetails{true, 7, 13}},
+		{"héllo wörld", TruncateEnd, 1, "hé" + ellipsis, TruncationDetails{true, 3, 13}},
+	}
+
+	for _, test := range tests {
+		renderer.Layout().SetTruncation(test.truncation)
+		limit := renderer.Measure(test.limit).Width() + fract.One
+		rect, details := renderer.fractMeasureWithTruncation(test.text, limit, test.maxLines)
+		if details != test.expected {
+			t.Fatalf("text %q (%s): expected %v, got %v", test.text, test.truncation, test.expected, details)
+		}
+		if len(renderer.layout.lines) > test.maxLines || rect.Width() > limit {
+			t.Fatalf("text %q (%s): truncated text doesn't fit", test.text, test.truncation)
+		}
+	}
+
+	// ellipsis runes are mapped to the cut start
+	renderer.layoutTruncatedString(&renderer.layout, "hello world", 3, 8, "...", fract.MaxUnit)
+	for _, lrune := range renderer.layout.runes {
+		if lrune.byteIndex > 3 && lrune.byteIndex < 8 {
+			t.Fatalf("rune %q has byte index %d inside the cut", lrune.codePoint, lrune.byteIndex)
+		}
+	}
+
+	// the ellipsis is placed in logical order
+	renderer.SetDirection(RightToLeft)
+	renderer.Layout().SetTruncation(TruncateEnd)
+	limit := renderer.Measure("hello w"+ellipsis).Width() + fract.One
+	_, details := renderer.fractMeasureWithTruncation("hello world", limit, 1)
+	if details != (TruncationDetails{true, 7, 11}) {
+		t.Fatalf("RightToLeft: unexpected truncation %v", details)
+	}
+	first := renderer.layout.runes[renderer.layout.order[0]].codePoint
+	if first != []rune(ellipsis)[0] {
+		t.Fatalf("RightToLeft: expected ellipsis at the left side, got %q", first)
+	}
+}
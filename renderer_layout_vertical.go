@@ -0,0 +1,443 @@
+package etxt
+
+import (
+	"image"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Vertical text for the layout process. See Renderer.SetDirection().
+//
+// Lines become columns that flow from right to left, and glyphs are
+// traversed top to bottom with the same layoutTraverseLine() used for
+// horizontal text, so wrapping, kerning and truncation work unchanged
+// along the columns. Line metrics are reused for the columns: the line
+// baseline becomes the column axis, with the ascent on its right side
+// and the descent on its left side.
+//
+// Each rune gets one of three orientations:
+//   - Vertical alternates: punctuation with a vertical presentation
+//     form (U+FE10 - U+FE19, U+FE30 - U+FE48) in the font uses it.
+//   - Upright: ideographs, kana, hangul, fullwidth forms and similar
+//     are centered on the column and advance by their vertical advance.
+//     Ideographic commas and full stops without vertical forms are
+//     moved to the top right corner of their em box.
+//   - Sideways: everything else is rotated 90 degrees clockwise and
+//     advances by its horizontal advance, with its baseline on the
+//     column axis. Rotation is done with sidewaysRasterizer.
+//
+// Positions along the columns use the horizontal quantization, and
+// positions across them use the vertical quantization.
+
+// Returns the vertical presentation form for the given code point,
+// or the code point itself if there's none.
+func verticalForm(codePoint rune) rune {
+	switch codePoint {
+	case '，':
+		return '︐'
+	case '、':
+		return '︑'
+	case '。':
+		return '︒'
+	case '：':
+		return '︓'
+	case '；':
+		return '︔'
+	case '！':
+		return '︕'
+	case '？':
+		return '︖'
+	case '〖':
+		return '︗'
+	case '〗':
+		return '︘'
+	case '…':
+		return '︙'
+	case '‥':
+		return '︰'
+	case '—':
+		return '︱'
+	case '–':
+		return '︲'
+	case '（':
+		return '︵'
+	case '）':
+		return '︶'
+	case '｛':
+		return '︷'
+	case '｝':
+		return '︸'
+	case '〔':
+		return '︹'
+	case '〕':
+		return '︺'
+	case '【':
+		return '︻'
+	case '】':
+		return '︼'
+	case '《':
+		return '︽'
+	case '》':
+		return '︾'
+	case '〈':
+		return '︿'
+	case '〉':
+		return '﹀'
+	case '「':
+		return '﹁'
+	case '」':
+		return '﹂'
+	case '『':
+		return '﹃'
+	case '』':
+		return '﹄'
+	case '［':
+		return '﹇'
+	case '］':
+		return '﹈'
+	default:
+		return codePoint
+	}
+}
+
+// Returns whether the given code point is drawn upright in vertical
+// text. This is an approximation of the Unicode Vertical_Orientation
+// property (UAX #50), where brackets, dashes and similar characters
+// that are transformed in vertical text are rotated sideways.
+func verticalIsUpright(codePoint rune) bool {
+	switch {
+	case codePoint < 0x1100:
+		return false
+	case codePoint <= 0x11FF: // hangul jamo
+		return true
+	case codePoint < 0x2E80:
+		return false
+	case codePoint <= 0x2FFF: // cjk radicals, kangxi, description chars
+		return true
+	case codePoint <= 0x303F: // cjk symbols and punctuation
+		return !(codePoint >= 0x3008 && codePoint <= 0x3011) &&
+			!(codePoint >= 0x3014 && codePoint <= 0x301F) && codePoint != 0x3030
+	case codePoint <= 0x30FF: // kana
+		return codePoint != 0x30FC
+	case codePoint <= 0x9FFF: // bopomofo, hangul compat, cjk ext a, ideographs...
+		return true
+	case codePoint >= 0xA960 && codePoint <= 0xA97F: // hangul jamo ext a
+		return true
+	case codePoint >= 0xAC00 && codePoint <= 0xD7FF: // hangul syllables
+		return true
+	case codePoint >= 0xF900 && codePoint <= 0xFAFF: // cjk compat ideographs
+		return true
+	case codePoint >= 0xFE10 && codePoint <= 0xFE1F: // vertical forms
+		return true
+	case codePoint >= 0xFE30 && codePoint <= 0xFE4F: // cjk compat forms
+		return true
+	case codePoint >= 0xFF01 && codePoint <= 0xFF60: // fullwidth forms
+		switch codePoint {
+		case 0xFF08, 0xFF09, 0xFF1C, 0xFF1D, 0xFF1E, 0xFF3B, 0xFF3D, 0xFF3F, 0xFF5B, 0xFF5C, 0xFF5D, 0xFF5E, 0xFF5F, 0xFF60:
+			return false
+		default:
+			return true
+		}
+	case codePoint >= 0xFFE0 && codePoint <= 0xFFE6: // fullwidth signs
+		return true
+	case codePoint >= 0x1F000 && codePoint <= 0x1FAFF: // emoji and symbols
+		return true
+	case codePoint >= 0x20000 && codePoint <= 0x3FFFF: // cjk ext b and beyond
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns whether the given code point is punctuation placed on the
+// bottom left corner of its em box in horizontal text and on the top
+// right corner in vertical text.
+func verticalIsCornerPunct(codePoint rune) bool {
+	return codePoint == '、' || codePoint == '。' || codePoint == '，' || codePoint == '．'
+}
+
+// Sets the orientation, advance and origin offset of all the layout
+// runes for vertical text. Precondition: layout runes already added.
+func (self *Renderer) layoutVertRunes(layout *textLayout) {
+	initStyle := self.layoutCurrentStyle()
+	for i := range layout.runes {
+		lrune := &layout.runes[i]
+		if !lrune.skip && lrune.codePoint != '\n' && lrune.codePoint != '\t' {
+			self.layoutVertRune(layout, lrune)
+		}
+	}
+	self.layoutApplyStyle(&initStyle)
+}
+
+// Sets the orientation, advance and origin offset of a single rune for
+// vertical text. The rune's glyph must already be set.
+func (self *Renderer) layoutVertRune(layout *textLayout, lrune *layoutRune) {
+	font := layout.styles[lrune.style].font
+	upright := verticalIsUpright(lrune.codePoint)
+	corner := upright && verticalIsCornerPunct(lrune.codePoint)
+	if form := verticalForm(lrune.codePoint); form != lrune.codePoint {
+		index, err := font.GlyphIndex(&self.buffer, form)
+		if err != nil {
+			panic("font.GlyphIndex error: " + err.Error())
+		}
+		if index != 0 {
+			lrune.glyph = index
+			upright, corner = true, false
+		}
+	}
+
+	// sideways runes use a rotated variant of their style
+	if !upright {
+		sideways := layout.styles[lrune.style]
+		sideways.sideways = true
+		lrune.style = layout.addStyle(sideways)
+		self.layoutApplyStyle(&layout.styles[lrune.style])
+		lrune.advance = self.getOpAdvance(lrune.glyph)
+		lrune.offset = fract.Point{}
+		return
+	}
+
+	// upright runes are centered on the em box of the metrics font
+	metricsStyle := layout.metricsStyle(lrune.style)
+	self.layoutApplyStyle(&metricsStyle)
+	ascent, descent := self.getOpAscent(), self.getOpDescent()
+	self.layoutApplyStyle(&layout.styles[lrune.style])
+	width := self.getOpAdvance(lrune.glyph)
+	lrune.offset.X = ((ascent - descent) >> 1) - (width >> 1)
+	lrune.advance = ascent + descent
+	lrune.offset.Y = ascent
+	if metrics := self.vertMetrics[font]; metrics != nil {
+		scale := func(value int) fract.Unit {
+			return fract.Unit(int64(value) * int64(self.state.scaledSize) / int64(metrics.UnitsPerEm()))
+		}
+		lrune.advance = scale(metrics.Advance(lrune.glyph))
+		bearing, ok := metrics.TopSideBearing(lrune.glyph)
+		bounds := self.glyphLoadBounds(lrune.glyph)
+		if ok && !bounds.Empty() {
+			lrune.offset.Y = scale(bearing) - bounds.Min.Y
+		}
+	}
+	if corner {
+		lrune.offset.X += width >> 1
+		lrune.offset.Y -= lrune.advance >> 1
+	}
+}
+
+// Returns the rasterizer used for sideways glyphs with the given
+// base rasterizer. Subpixel antialiasing doesn't work for rotated
+// glyphs, so LCD rasterizers are replaced by their wrapped ones.
+func (self *Renderer) layoutSidewaysRasterizer(base mask.Rasterizer) mask.Rasterizer {
+	if lcd, isLCD := base.(*mask.LCDRasterizer); isLCD {
+		base = lcd.GetRasterizer()
+	}
+	if self.sideways == nil || self.sideways.base != base {
+		self.sideways = &sidewaysRasterizer{base: base}
+	}
+	return self.sideways
+}
+
+// Returns the x coordinate of the first column axis and the y coordinate
+// where columns start, based on the renderer's align. Columns go from right
+// to left, so the horizontal align places the whole block of columns, while
+// the vertical align is applied to each column individually (see also
+// layoutColumnStart()). The x result is quantized.
+func (self *Renderer) layoutVertDrawOrigin(layout *textLayout, x, y fract.Unit) (fract.Unit, fract.Unit) {
+	switch self.state.align.Horz() {
+	case Left:
+		x += layout.height
+	case HorzCenter:
+		x += layout.height >> 1
+	}
+	return (x - layout.firstAscent()).QuantizeUp(fract.Unit(self.state.vertQuantization)), y
+}
+
+// Returns the y coordinate where the column traversal has to start.
+func (self *Renderer) layoutColumnStart(line *layoutLine, y fract.Unit) fract.Unit {
+	horzQuant := fract.Unit(self.state.horzQuantization)
+	switch self.state.align.Vert() {
+	case VertCenter:
+		return (y - (line.width >> 1)).QuantizeUp(horzQuant)
+	case Bottom:
+		return (y - line.width).QuantizeUp(horzQuant)
+	default:
+		return y.QuantizeUp(horzQuant)
+	}
+}
+
+// Returns the glyph positions along the given column, quantized and in
+// the same order as layout.order[line.orderStart:line.orderEnd].
+func (self *Renderer) layoutColumnGlyphPositions(layout *textLayout, line *layoutLine, y fract.Unit) []fract.Unit {
+	layout.positions = ensureSliceSize(layout.positions, line.orderEnd-line.orderStart)
+	positions := layout.positions[:line.orderEnd-line.orderStart]
+	var k int
+	self.layoutTraverseLine(layout, line, self.layoutColumnStart(line, y), false, func(_ *layoutRune, glyphY fract.Unit) {
+		positions[k] = glyphY
+		k += 1
+	})
+	return positions
+}
+
+// Vertical text equivalent of layoutMeasureLines().
+func (self *Renderer) layoutVertMeasureLines(layout *textLayout, textLen int) []MeasuredLine {
+	x, y := self.layoutVertDrawOrigin(layout, 0, 0)
+	lines := make([]MeasuredLine, 0, len(layout.lines))
+	for i := range layout.lines {
+		line := &layout.lines[i]
+		axis := x - line.baseline
+		top := self.layoutColumnStart(line, y)
+		lines = append(lines, MeasuredLine{
+			Rect:      fract.UnitsToRect(axis-line.descent, top, axis+line.ascent, top+line.width),
+			Baseline:  axis,
+			ByteStart: layout.runeByteIndex(line.runeStart, textLen),
+			ByteEnd:   layout.runeByteIndex(line.runeEnd, textLen),
+			Change:    line.change,
+			LineBreak: !line.change.IsWrap && line.runeEnd < layout.numTextRunes,
+		})
+	}
+	return lines
+}
+
+// Vertical text equivalent of layoutHitTest().
+func (self *Renderer) layoutVertHitTest(layout *textLayout, textLen int, x, y fract.Unit, point fract.Point) TextHit {
+	// find the column
+	x, y = self.layoutVertDrawOrigin(layout, x, y)
+	line := &layout.lines[len(layout.lines)-1]
+	for i := range layout.lines {
+		if point.X > x-layout.lines[i].baseline-layout.lines[i].descent {
+			line = &layout.lines[i]
+			break
+		}
+	}
+	if line.orderStart == line.orderEnd {
+		return layout.emptyLineHit(line, textLen)
+	}
+
+	// find the glyph (the last one starting above the point)
+	positions := self.layoutColumnGlyphPositions(layout, line, y)
+	var k int
+	for k < len(positions)-1 && positions[k+1] <= point.Y {
+		k += 1
+	}
+	index := layout.order[line.orderStart+k]
+	lrune := &layout.runes[index]
+	trailing := (point.Y >= positions[k]+((lrune.advance+lrune.spacing)>>1))
+	return layout.runeHit(line, index, trailing, textLen)
+}
+
+// Vertical text equivalent of layoutCaretRect(). The rect has zero
+// height and spans the column's width.
+func (self *Renderer) layoutVertCaretRect(layout *textLayout, byteIndex int, x, y fract.Unit) fract.Rect {
+	index, line := layout.caretLine(byteIndex)
+	x, y = self.layoutVertDrawOrigin(layout, x, y)
+	caretY := self.layoutColumnStart(line, y)
+	positions := self.layoutColumnGlyphPositions(layout, line, y)
+	for k, runeIndex := range layout.order[line.orderStart:line.orderEnd] {
+		if runeIndex >= line.runeEnd {
+			break // inserted hyphen
+		}
+		if runeIndex >= index {
+			caretY = positions[k]
+			break
+		}
+		lrune := &layout.runes[runeIndex]
+		caretY = positions[k] + lrune.advance + lrune.spacing
+	}
+
+	axis := x - line.baseline
+	return fract.UnitsToRect(axis-line.descent, caretY, axis+line.ascent, caretY)
+}
+
+// Vertical text equivalent of layoutRangeRects().
+func (self *Renderer) layoutVertRangeRects(layout *textLayout, start, end int, x, y fract.Unit, rects []fract.Rect) []fract.Rect {
+	x, y = self.layoutVertDrawOrigin(layout, x, y)
+	for i := range layout.lines {
+		line := &layout.lines[i]
+		if line.orderStart == line.orderEnd {
+			continue
+		}
+
+		var inRun bool
+		var runStart, runEnd fract.Unit
+		left := x - line.baseline - line.descent
+		right := x - line.baseline + line.ascent
+		positions := self.layoutColumnGlyphPositions(layout, line, y)
+		for k, index := range layout.order[line.orderStart:line.orderEnd] {
+			lrune := &layout.runes[index]
+			if !lrune.inByteRange(start, end) {
+				if inRun {
+					rects = append(rects, fract.UnitsToRect(left, runStart, right, runEnd))
+					inRun = false
+				}
+				continue
+			}
+			if !inRun {
+				runStart = positions[k]
+				inRun = true
+			}
+			runEnd = positions[k] + lrune.advance + lrune.spacing
+		}
+		if inRun {
+			rects = append(rects, fract.UnitsToRect(left, runStart, right, runEnd))
+		}
+	}
+	return rects
+}
+
+// ---- sideways rasterizer ----
+
+// Signature bits flipped by sidewaysRasterizer, so rotated masks are
+// cached separately from regular ones.
+const sidewaysSignatureBits = 0x5D00000000000000
+
+// A rasterizer wrapper that rotates glyph outlines 90 degrees clockwise
+// around the glyph origin, so the glyph baseline goes down and the top
+// of the glyph faces right.
+type sidewaysRasterizer struct {
+	base     mask.Rasterizer
+	segments sfnt.Segments
+	onChange func(mask.Rasterizer)
+}
+
+// Satisfies the [mask.Rasterizer] interface.
+func (self *sidewaysRasterizer) Rasterize(outline sfnt.Segments, origin fract.Point) (*image.Alpha, error) {
+	self.segments = append(self.segments[:0], outline...)
+	for i := range self.segments {
+		args := &self.segments[i].Args
+		for j := range args {
+			args[j].X, args[j].Y = -args[j].Y, args[j].X
+		}
+	}
+	return self.base.Rasterize(self.segments, origin)
+}
+
+// Satisfies the [mask.Rasterizer] interface.
+func (self *sidewaysRasterizer) Signature() uint64 {
+	return self.base.Signature() ^ sidewaysSignatureBits
+}
+
+// Satisfies the [mask.SizeAwareRasterizer] interface. The size is
+// forwarded to the base rasterizer if relevant.
+func (self *sidewaysRasterizer) NotifySizeChange(size fract.Unit) {
+	if sizeAware, ok := self.base.(mask.SizeAwareRasterizer); ok {
+		sizeAware.NotifySizeChange(size)
+	}
+}
+
+// Satisfies the [mask.Rasterizer] interface. Changes on the base
+// rasterizer are forwarded.
+func (self *sidewaysRasterizer) SetOnChangeFunc(onChange func(mask.Rasterizer)) {
+	self.onChange = onChange
+	if onChange == nil {
+		self.base.SetOnChangeFunc(nil)
+	} else {
+		self.base.SetOnChangeFunc(self.notifyChange)
+	}
+}
+
+func (self *sidewaysRasterizer) notifyChange(mask.Rasterizer) {
+	if self.onChange != nil {
+		self.onChange(self)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestVerticalDraw(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(16)
+	renderer.SetAlign(Top | Right)
+	renderer.SetDirection(TopToBottom)
+	renderer.Fract().SetHorzQuantization(QtFull)
+	renderer.Fract().SetVertQuantization(QtFull)
+	target := image.NewRGBA(image.Rect(0, 0, 200, 200))
+
+	// latin glyphs must be drawn sideways, one below the other
+	var rasterizers []mask.Rasterizer
+	var origins []fract.Point
+	renderer.Glyph().SetDrawFunc(func(target Target, glyph sfnt.GlyphIndex, origin fract.Point) {
+		rasterizers = append(rasterizers, renderer.Glyph().GetRasterizer())
+		origins = append(origins, origin)
+	})
+	renderer.Draw(target, "ab", 100, 50)
+	renderer.Glyph().SetDrawFunc(nil)
+
+	if len(origins) != 2 {
+		t.Fatalf("expected 2 glyphs, got %d", len(origins))
+	}
+	axis := (fract.FromInt(100) - renderer.Metrics().Ascent()).QuantizeUp(QtFull)
+	advance := renderer.Metrics().Advance(renderer.Glyph().GetRuneIndex('a'))
+	if origins[0] != fract.UnitsToPoint(axis, fract.FromInt(50)) {
+		t.Fatalf("unexpected first glyph origin %v", origins[0])
+	}
+	if origins[1] != fract.UnitsToPoint(axis, (fract.FromInt(50)+advance).QuantizeUp(QtFull)) {
+		t.Fatalf("unexpected second glyph origin %v", origins[1])
+	}
+	for _, rasterizer := range rasterizers {
+		if _, ok := rasterizer.(*sidewaysRasterizer); !ok {
+			t.Fatalf("expected sideways rasterizer, got %T", rasterizer)
+		}
+	}
+	if _, ok := renderer.Glyph().GetRasterizer().(*mask.DefaultRasterizer); !ok {
+		t.Fatalf("expected rasterizer to be restored after drawing")
+	}
+
+	// the drawn text must be within the measured area
+	renderer.Draw(target, "ab", 100, 50)
+	rect := renderer.Measure("ab")
+	bounds := rect.AddUnits(fract.FromInt(100)-rect.Width(), fract.FromInt(50)).ImageRect()
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			if target.RGBAAt(x, y).A != 0 && !image.Pt(x, y).In(bounds) {
+				t.Fatalf("pixel (%d, %d) drawn outside %v", x, y, bounds)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package etxt
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+)
+
+func TestVerticalOrientation(t *testing.T) {
+	tests := []struct {
+		codePoint rune
+		upright   bool
+		form      rune
+	}{
+		{'a', false, 'a'},
+		{'1', false, '1'},
+		{'漢', true, '漢'},
+		{'か', true, 'か'},
+		{'한', true, '한'},
+		{'ー', false, 'ー'},
+		{'「', false, '﹁'},
+		{'、', true, '︑'},
+		{'。', true, '︒'},
+		{'（', false, '︵'},
+		{'Ａ', true, 'Ａ'},
+	}
+	for _, test := range tests {
+		if verticalIsUpright(test.codePoint) != test.upright {
+			t.Fatalf("%q: expected upright = %t", test.codePoint, test.upright)
+		}
+		if verticalForm(test.codePoint) != test.form {
+			t.Fatalf("%q: expected vertical form %q, got %q", test.codePoint, test.form, verticalForm(test.codePoint))
+		}
+	}
+}
+
+func TestSidewaysRasterizer(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(24)
+	segments, err := renderer.glyphLoadSegments(renderer.Glyph().GetRuneIndex('l'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &mask.DefaultRasterizer{}
+	sideways := &sidewaysRasterizer{base: base}
+	if sideways.Signature() == base.Signature() {
+		t.Fatalf("expected different signatures")
+	}
+	upright, err := mask.Rasterize(segments, base, fract.Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := mask.Rasterize(segments, sideways, fract.Point{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rotated mask must be the transposed one, with
+	// the top of the glyph on the right of the origin
+	if rotated.Rect.Dx() != upright.Rect.Dy() || rotated.Rect.Dy() != upright.Rect.Dx() {
+		t.Fatalf("expected transposed mask sizes, got %v and %v", upright.Rect, rotated.Rect)
+	}
+	if rotated.Rect.Max.X != -upright.Rect.Min.Y || rotated.Rect.Min.Y != upright.Rect.Min.X {
+		t.Fatalf("unexpected mask rects %v and %v", upright.Rect, rotated.Rect)
+	}
+
+	// rasterizer changes must be forwarded
+	var notified mask.Rasterizer
+	faux := &mask.FauxRasterizer{}
+	sideways = &sidewaysRasterizer{base: faux}
+	sideways.SetOnChangeFunc(func(rasterizer mask.Rasterizer) { notified = rasterizer })
+	faux.SetSkewFactor(0.2)
+	if notified != sideways {
+		t.Fatalf("expected change notification")
+	}
+}
+
+func TestVerticalMeasure(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(16)
+	renderer.Fract().SetHorzQuantization(QtNone)
+	renderer.Fract().SetVertQuantization(QtNone)
+
+	// latin text is sideways, so columns are as long as the
+	// horizontal text is wide, and as wide as the line height
+	horzRect := renderer.Measure("hello\nworld")
+	horzLine := renderer.Measure("hello")
+	renderer.SetDirection(TopToBottom)
+	vertRect := renderer.Measure("hello\nworld")
+	if vertRect.Width() != horzRect.Height() || vertRect.Height() != horzRect.Width() {
+		t.Fatalf("expected transposed rects, got %v and %v", horzRect, vertRect)
+	}
+
+	// wrapping applies to the column length
+	limit := horzLine.Width().ToIntCeil()
+	lines := renderer.MeasureLinesWithWrap("hello world", limit)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 columns, got %d", len(lines))
+	}
+	if lines[1].Rect.Max.X > lines[0].Rect.Min.X || lines[0].Rect.Height() > fract.FromInt(limit) {
+		t.Fatalf("unexpected columns %v and %v", lines[0].Rect, lines[1].Rect)
+	}
+	if lines[0].ByteStart != 0 || lines[0].ByteEnd != 5 || lines[1].ByteStart != 6 {
+		t.Fatalf("unexpected column contents %+v", lines)
+	}
+}
+
+func TestVerticalHitTestCaretRoundTrip(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(16)
+	renderer.SetDirection(TopToBottom)
+	renderer.Fract().SetHorzQuantization(QtFull)
+	renderer.Fract().SetVertQuantization(QtFull)
+
+	text := "hello world\nhow are you?"
+	limit := renderer.Measure("hello wor").IntHeight()
+	for _, align := range []Align{Top | Right, VertCenter | HorzCenter, Bottom | Left} {
+		renderer.SetAlign(align)
+		for index := 0; index <= len(text); index++ {
+			caret := renderer.CaretRectWithWrap(text, 100, 100, limit, index)
+			if caret.Height() != 0 || caret.Width() <= 0 {
+				t.Fatalf("align %s, index %d: invalid caret %v", align, index, caret)
+			}
+
+			cx, cy := (caret.Min.X + caret.Width()/2).ToIntFloor(), caret.Min.Y.ToIntFloor()
+			point := image.Pt(cx, cy+1)
+			if index == len(text) || text[index] == '\n' {
+				point.Y = cy // end of column
+			}
+			hit := renderer.HitTestWithWrap(text, 100, 100, limit, point)
+			if hit.CaretIndex != index {
+				t.Fatalf("align %s, index %d: caret at %v, but hit test resolved to %+v", align, index, caret, hit)
+			}
+		}
+
+		// range rects must match columns and carets
+		rects := renderer.RangeRectsWithWrap(text, 100, 100, limit, 0, len(text))
+		lines := renderer.MeasureLinesWithWrap(text, limit)
+		if len(rects) != len(lines) {
+			t.Fatalf("align %s: expected %d range rects, got %d", align, len(lines), len(rects))
+		}
+		for i := range rects {
+			column := lines[i].Rect.AddInts(100, 100)
+			if rects[i].Min.X != column.Min.X || rects[i].Max.X != column.Max.X {
+				t.Fatalf("align %s: range rect %v doesn't match column %v", align, rects[i], column)
+			}
+		}
+		caret := renderer.CaretRectWithWrap(text, 100, 100, limit, 1)
+		if caret.Min.X != rects[0].Min.X || caret.Max.X != rects[0].Max.X {
+			t.Fatalf("align %s: caret %v doesn't match range rect %v", align, caret, rects[0])
+		}
+	}
+}
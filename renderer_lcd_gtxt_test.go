@@ -0,0 +1,79 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+)
+
+func TestLCDDraw(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.Utils().SetCache8MiB()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(16)
+	renderer.SetColor(color.RGBA{255, 255, 255, 255})
+
+	// returns the drawn bounds and the number of pixels with
+	// different values on their color channels
+	draw := func() (image.Rectangle, int) {
+		target := image.NewRGBA(image.Rect(0, 0, 128, 64))
+		renderer.Draw(target, "Hello", 64, 32)
+		var bounds image.Rectangle
+		var fringes int
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 128; x++ {
+				rgba := target.RGBAAt(x, y)
+				if rgba.A == 0 {
+					continue
+				}
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+				if rgba.R != rgba.G || rgba.G != rgba.B {
+					fringes += 1
+				}
+				if rgba.R > rgba.A || rgba.G > rgba.A || rgba.B > rgba.A {
+					t.Fatalf("invalid premultiplied color %v at (%d, %d)", rgba, x, y)
+				}
+			}
+		}
+		return bounds, fringes
+	}
+
+	refBounds, refFringes := draw()
+	if refFringes != 0 {
+		t.Fatalf("unexpected color fringes with grayscale antialiasing")
+	}
+	var lcd mask.LCDRasterizer
+	renderer.Glyph().SetRasterizer(&lcd)
+	bounds, fringes := draw()
+	if fringes == 0 {
+		t.Fatalf("expected color fringes with subpixel antialiasing")
+	}
+	if bounds.Dx() < refBounds.Dx() || bounds.Dx() > refBounds.Dx()+2 || bounds.Dy() != refBounds.Dy() {
+		t.Fatalf("expected bounds similar to %v, got %v", refBounds, bounds)
+	}
+
+	// loaded masks can't hold subpixel coverage, so they must be
+	// rasterized with the wrapped rasterizer
+	index := renderer.Glyph().GetRuneIndex('H')
+	loaded := renderer.Glyph().LoadMask(index, fract.Point{})
+	renderer.Glyph().SetRasterizer(lcd.GetRasterizer())
+	expected := renderer.Glyph().LoadMask(index, fract.Point{})
+	if loaded.Rect != expected.Rect || !bytes.Equal(loaded.Pix, expected.Pix) {
+		t.Fatalf("expected LoadMask to use the wrapped rasterizer")
+	}
+
+	// the sideways rasterizer must unwrap the LCD rasterizer
+	if _, isLCD := renderer.layoutSidewaysRasterizer(&lcd).(*sidewaysRasterizer).base.(*mask.LCDRasterizer); isLCD {
+		t.Fatalf("LCD rasterizer used for sideways glyphs")
+	}
+}
@@ -44,6 +44,35 @@ func (self *Renderer) MeasureWithWrap(text string, widthLimit int) fract.Rect {
 	return self.fractMeasureWithWrap(text, fract.FromInt(widthLimit))
 }
 
+// Same as [Renderer.MeasureWithWrap](), but limiting the text to the
+// given number of lines and truncating it like [Renderer.DrawWithTruncation]()
+// if necessary. The returned [TruncationDetails] indicate which part of
+// the text was cut, if any.
+func (self *Renderer) MeasureWithTruncation(text string, widthLimit, maxLines int) (fract.Rect, TruncationDetails) {
+	if widthLimit > fract.MaxInt {
+		panic("widthLimit too big, must be <= fract.MaxInt")
+	}
+	return self.fractMeasureWithTruncation(text, fract.FromInt(widthLimit), maxLines)
+}
+
+// Same as [Renderer.Measure](), but returning the measurements of each
+// line of the text instead of a single rect. See [MeasuredLine] for
+// further details.
+func (self *Renderer) MeasureLines(text string) []MeasuredLine {
+	return self.fractMeasureLines(text, false, 0)
+}
+
+// Same as [Renderer.MeasureWithWrap](), but returning the measurements
+// of each line of the text instead of a single rect. Lines are the same
+// ones that [Renderer.DrawWithWrap]() would draw. See [MeasuredLine] for
+// further details.
+func (self *Renderer) MeasureLinesWithWrap(text string, widthLimit int) []MeasuredLine {
+	if widthLimit > fract.MaxInt {
+		panic("widthLimit too big, must be <= fract.MaxInt")
+	}
+	return self.fractMeasureLines(text, true, fract.FromInt(widthLimit))
+}
+
 // ---- underlying implementations ----
 
 func (self *Renderer) fractMeasure(text string) fract.Rect {
@@ -66,6 +95,10 @@ func (self *Renderer) fractMeasure(text string) fract.Rect {
 	if text == "" {
 		return fract.Rect{}
 	}
+	if self.layoutRequired(text) {
+		self.layoutString(&self.layout, text, false, 0)
+		return self.layoutMeasure(&self.layout)
+	}
 	if self.state.textDirection == LeftToRight {
 		return self.fractMeasureLTR(text)
 	} else {
@@ -89,84 +122,60 @@ func (self *Renderer) fractMeasureWithWrap(text string, widthLimit fract.Unit) f
 	if text == "" {
 		return fract.Rect{}
 	}
-	if self.state.textDirection == LeftToRight {
-		return self.fractMeasureWrapLTR(text, widthLimit)
-	} else {
-		return self.fractMeasureWrapRTL(text, widthLimit)
-	}
+	self.layoutString(&self.layout, text, true, widthLimit)
+	return self.layoutMeasure(&self.layout)
 }
 
-// NOTICE: the four functions below are all the same code, with only different
-//         helperMeasure* functions. One could argue I should be passing a
-//         function directly. Think about it as generics by hand if you want.
-//         The helper functions can be found at renderer_measure_helpers.go.
-
-// Preconditions: non-nil font and sizer, non-empty text.
-func (self *Renderer) fractMeasureLTR(text string) fract.Rect {
-	var iterator ltrStringIterator
-	var lastRune rune
-	var lineBreakNth int = -1
-	var width, height, lineWidth fract.Unit
-	var lineBreaksOnly bool = true
-	vertQuant := fract.Unit(self.state.vertQuantization)
-
-	for { // measure text line by line
-		iterator, lineWidth, _, lastRune = self.helperMeasureLineLTR(iterator, text)
-		if lineWidth > 0 {
-			if lineWidth > width {
-				width = lineWidth
-			}
-			lineBreaksOnly = false
-			lineBreakNth = 0
-		}
-		if lastRune == -1 {
-			break
-		}
-		lineBreakNth = maxInt(1, lineBreakNth+1)
-		height = (height + self.getOpLineAdvance(lineBreakNth)).QuantizeUp(vertQuant)
+func (self *Renderer) fractMeasureWithTruncation(text string, widthLimit fract.Unit, maxLines int) (fract.Rect, TruncationDetails) {
+	// preconditions
+	if self.state.activeFont == nil {
+		panic("can't measure text with nil font (tip: Renderer.SetFont())")
+	}
+	if self.state.fontSizer == nil {
+		panic("can't measure text with a nil sizer (tip: NewRenderer())")
+	}
+	if widthLimit < 0 {
+		panic("can't use a negative widthLimit")
+	}
+	if maxLines < 1 {
+		panic("maxLines must be at least 1")
 	}
 
-	if !lineBreaksOnly {
-		height = (height + self.getOpLineHeight()).QuantizeUp(vertQuant)
+	// main processing
+	if text == "" {
+		return fract.Rect{}, TruncationDetails{}
 	}
-	width = width.QuantizeUp(fract.Unit(self.state.horzQuantization))
-	return fract.Rect{Max: fract.UnitsToPoint(width, height)}
+	details := self.layoutStringTruncated(&self.layout, text, widthLimit, maxLines)
+	return self.layoutMeasure(&self.layout), details
 }
 
-// Preconditions: non-nil font and sizer, non-empty text.
-func (self *Renderer) fractMeasureRTL(text string) fract.Rect {
-	var iterator ltrStringIterator
-	var lastRune rune
-	var lineBreakNth int = -1
-	var width, height, lineWidth fract.Unit
-	var lineBreaksOnly bool = true
-	vertQuant := fract.Unit(self.state.vertQuantization)
-
-	for { // measure text line by line
-		iterator, lineWidth, _, lastRune = self.helperMeasureLineReverseLTR(iterator, text)
-		if lineWidth > 0 {
-			if lineWidth > width {
-				width = lineWidth
-			}
-			lineBreaksOnly = false
-			lineBreakNth = 0
-		}
-		if lastRune == -1 {
-			break
-		}
-		lineBreakNth = maxInt(1, lineBreakNth+1)
-		height = (height + self.getOpLineAdvance(lineBreakNth)).QuantizeUp(vertQuant)
+func (self *Renderer) fractMeasureLines(text string, wrap bool, widthLimit fract.Unit) []MeasuredLine {
+	// preconditions
+	if self.state.activeFont == nil {
+		panic("can't measure text with nil font (tip: Renderer.SetFont())")
+	}
+	if self.state.fontSizer == nil {
+		panic("can't measure text with a nil sizer (tip: NewRenderer())")
+	}
+	if widthLimit < 0 {
+		panic("can't use a negative widthLimit")
 	}
 
-	if !lineBreaksOnly {
-		height = (height + self.getOpLineHeight()).QuantizeUp(vertQuant)
+	// main processing
+	if text == "" {
+		return nil
 	}
-	width = width.QuantizeUp(fract.Unit(self.state.horzQuantization))
-	return fract.Rect{Max: fract.UnitsToPoint(width, height)}
+	self.layoutString(&self.layout, text, wrap, widthLimit)
+	return self.layoutMeasureLines(&self.layout, len(text))
 }
 
+// NOTICE: the two functions below are all the same code, with only different
+//         helperMeasure* functions. One could argue I should be passing a
+//         function directly. Think about it as generics by hand if you want.
+//         The helper functions can be found at renderer_measure_helpers.go.
+
 // Preconditions: non-nil font and sizer, non-empty text.
-func (self *Renderer) fractMeasureWrapLTR(text string, widthLimit fract.Unit) fract.Rect {
+func (self *Renderer) fractMeasureLTR(text string) fract.Rect {
 	var iterator ltrStringIterator
 	var lastRune rune
 	var lineBreakNth int = -1
@@ -175,7 +184,7 @@ func (self *Renderer) fractMeasureWrapLTR(text string, widthLimit fract.Unit) fr
 	vertQuant := fract.Unit(self.state.vertQuantization)
 
 	for { // measure text line by line
-		iterator, lineWidth, _, lastRune = self.helperMeasureWrapLineLTR(iterator, text, widthLimit)
+		iterator, lineWidth, _, lastRune = self.helperMeasureLineLTR(iterator, text)
 		if lineWidth > 0 {
 			if lineWidth > width {
 				width = lineWidth
@@ -197,9 +206,8 @@ func (self *Renderer) fractMeasureWrapLTR(text string, widthLimit fract.Unit) fr
 	return fract.Rect{Max: fract.UnitsToPoint(width, height)}
 }
 
-// failing with qt = 64, align = (Baseline | Left), dir = RightToLeft
 // Preconditions: non-nil font and sizer, non-empty text.
-func (self *Renderer) fractMeasureWrapRTL(text string, widthLimit fract.Unit) fract.Rect {
+func (self *Renderer) fractMeasureRTL(text string) fract.Rect {
 	var iterator ltrStringIterator
 	var lastRune rune
 	var lineBreakNth int = -1
@@ -208,7 +216,7 @@ func (self *Renderer) fractMeasureWrapRTL(text string, widthLimit fract.Unit) fr
 	vertQuant := fract.Unit(self.state.vertQuantization)
 
 	for { // measure text line by line
-		iterator, lineWidth, _, lastRune = self.helperMeasureWrapLineReverseLTR(iterator, text, widthLimit)
+		iterator, lineWidth, _, lastRune = self.helperMeasureLineReverseLTR(iterator, text)
 		if lineWidth > 0 {
 			if lineWidth > width {
 				width = lineWidth
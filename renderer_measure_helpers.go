@@ -103,140 +103,3 @@ func (self *Renderer) helperMeasureLineReverseLTR(iterator ltrStringIterator, te
 		runeCount += 1
 	}
 }
-
-// returns the width unquantized, without accounting for final wrapped spaces.
-func (self *Renderer) helperMeasureWrapLineLTR(iterator ltrStringIterator, text string, widthLimit fract.Unit) (ltrStringIterator, fract.Unit, int, rune) {
-	var x, lastSafeWidth fract.Unit
-	var runeCount, lastSafeCount int
-	var safeIterator ltrStringIterator
-	var prevGlyphIndex sfnt.GlyphIndex
-
-	horzQuant := fract.Unit(self.state.horzQuantization)
-	for {
-		codePoint := iterator.Next(text)
-		if codePoint == -1 || codePoint == '\n' {
-			return iterator, x, runeCount, codePoint
-		}
-
-		// get glyph index
-		currGlyphIndex, skip := self.getGlyphIndex(self.state.activeFont, codePoint)
-		if skip {
-			runeCount += 1
-		} else {
-			// apply kerning unless at line start
-			memoX := x
-			if runeCount > 0 {
-				x += self.getOpKernBetween(prevGlyphIndex, currGlyphIndex)
-				x = x.QuantizeUp(horzQuant)
-			}
-
-			// advance
-			x += self.getOpAdvance(currGlyphIndex)
-
-			// (here we would draw if we wanted to)
-
-			// stop if outside wrapLimit
-			runeCount += 1
-			if codePoint == ' ' {
-				lastSafeCount = runeCount
-				lastSafeWidth = memoX
-				safeIterator = iterator
-			}
-			if x > widthLimit && x.QuantizeUp(horzQuant) > widthLimit { // *
-				// * the correctness of the quantized check is actually debatable, but it
-				//   does make for better consistency between measure and measureWithWrap,
-				//   which seems more relevant in practical scenarios
-				if lastSafeCount == 0 { // special case, show as much of first word as possible
-					if runeCount == 1 {
-						next := iterator.PeekNext(text)
-						if next == -1 || next == '\n' {
-							codePoint = next
-						}
-						if next == '\n' {
-							iterator.Next(text)
-						}
-						return iterator, x, 1, codePoint
-					} else {
-						if codePoint != ' ' {
-							iterator.Unroll(codePoint)
-						}
-						return iterator, memoX, runeCount - 1, codePoint
-					}
-				} else {
-					return safeIterator, lastSafeWidth, lastSafeCount, ' '
-				}
-			}
-		}
-
-		// update loop variables and continue
-		prevGlyphIndex = currGlyphIndex
-	}
-}
-
-// returns the width unquantized, without accounting for final wrapped spaces.
-func (self *Renderer) helperMeasureWrapLineReverseLTR(iterator ltrStringIterator, text string, widthLimit fract.Unit) (ltrStringIterator, fract.Unit, int, rune) {
-	var x, lastSafeWidth fract.Unit // values will be negative while looping
-	var runeCount, lastSafeCount int
-	var safeIterator ltrStringIterator
-	var prevGlyphIndex sfnt.GlyphIndex
-
-	horzQuant := fract.Unit(self.state.horzQuantization)
-	for {
-		codePoint := iterator.Next(text)
-		if codePoint == -1 || codePoint == '\n' {
-			return iterator, -x, runeCount, codePoint
-		}
-
-		// get glyph index
-		currGlyphIndex, skip := self.getGlyphIndex(self.state.activeFont, codePoint)
-		if skip {
-			runeCount += 1
-		} else {
-			// advance
-			memoX := x
-			x -= self.getOpAdvance(currGlyphIndex)
-
-			// apply kerning unless at line start
-			if runeCount > 0 {
-				x -= self.getOpKernBetween(currGlyphIndex, prevGlyphIndex)
-			}
-
-			// we need to quantize here inconditionally due to the previous advance
-			x = x.QuantizeUp(horzQuant)
-
-			// (here we would draw if we wanted to)
-
-			// stop if outside wrapLimit
-			runeCount += 1
-			if codePoint == ' ' {
-				lastSafeCount = runeCount
-				lastSafeWidth = -memoX
-				safeIterator = iterator
-			}
-			if x < -widthLimit && x.QuantizeUp(horzQuant) < -widthLimit {
-				if lastSafeCount == 0 { // special case, show as much of first word as possible
-					if runeCount == 1 {
-						next := iterator.PeekNext(text)
-						if next == -1 || next == '\n' {
-							codePoint = next
-						}
-						if next == '\n' {
-							iterator.Next(text)
-						}
-						return iterator, -x, 1, codePoint
-					} else {
-						if codePoint != ' ' {
-							iterator.Unroll(codePoint)
-						}
-						return iterator, -memoX, runeCount - 1, codePoint
-					}
-				} else {
-					return safeIterator, lastSafeWidth, lastSafeCount, ' '
-				}
-			}
-		}
-
-		// update loop variables and continue
-		prevGlyphIndex = currGlyphIndex
-	}
-}
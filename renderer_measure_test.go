@@ -191,3 +191,71 @@ func testMeasureBasics(t *testing.T, renderer *Renderer, fn func(*Renderer, stri
 		}
 	}
 }
+
+func TestMeasureLines(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Fract().SetHorzQuantization(QtNone)
+
+	text := "hello world\nhi"
+	widthLimit := renderer.Measure("hello wor").IntWidth()
+	type span struct {
+		start, end      int
+		wrap, lineBreak bool
+	}
+	expected := []span{{0, 5, true, false}, {6, 11, false, true}, {12, 14, false, false}}
+	for _, dir := range []Direction{LeftToRight, RightToLeft} {
+		for _, align := range []Align{Left, HorzCenter, Right} {
+			renderer.SetDirection(dir)
+			renderer.SetAlign(align)
+			lines := renderer.MeasureLinesWithWrap(text, widthLimit)
+			if len(lines) != len(expected) {
+				t.Fatalf("dir %s, align %s: expected %d lines, got %d", dir, align, len(expected), len(lines))
+			}
+			for i, line := range lines {
+				if line.ByteStart != expected[i].start || line.ByteEnd != expected[i].end ||
+					line.Change.IsWrap != expected[i].wrap || line.LineBreak != expected[i].lineBreak {
+					t.Fatalf("dir %s, align %s, line #%d: unexpected %+v", dir, align, i, line)
+				}
+				width := renderer.Measure(text[line.ByteStart:line.ByteEnd]).Width()
+				if line.Rect.Width() != width {
+					t.Fatalf("dir %s, align %s, line #%d: expected width %v, got %v", dir, align, i, width, line.Rect.Width())
+				}
+				switch align {
+				case Left:
+					if line.Rect.Min.X != 0 {
+						t.Fatalf("dir %s, align %s, line #%d: unexpected rect %v", dir, align, i, line.Rect)
+					}
+				case Right:
+					if line.Rect.Max.X != 0 {
+						t.Fatalf("dir %s, align %s, line #%d: unexpected rect %v", dir, align, i, line.Rect)
+					}
+				}
+				if i > 0 && line.Baseline <= lines[i-1].Baseline {
+					t.Fatalf("dir %s, align %s, line #%d: baselines not increasing", dir, align, i)
+				}
+			}
+			if !lines[0].Change.ElidedSpace {
+				t.Fatalf("dir %s, align %s: expected elided space", dir, align)
+			}
+		}
+	}
+	// rects must match the drawn glyph positions when quantized
+	renderer.Fract().SetHorzQuantization(QtFull)
+	for _, dir := range []Direction{LeftToRight, RightToLeft} {
+		for _, align := range []Align{Left, HorzCenter, Right} {
+			renderer.SetDirection(dir)
+			renderer.SetAlign(align)
+			for _, line := range renderer.MeasureLinesWithWrap(text, widthLimit) {
+				rects := renderer.RangeRectsWithWrap(text, 0, 0, widthLimit, line.ByteStart, line.ByteEnd)
+				if len(rects) != 1 || rects[0].Min.X != line.Rect.Min.X || rects[0].Max.X != line.Rect.Max.X {
+					t.Fatalf("dir %s, align %s: rect %v doesn't match drawn glyphs %v", dir, align, line.Rect, rects)
+				}
+			}
+		}
+	}
+}
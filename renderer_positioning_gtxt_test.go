@@ -0,0 +1,109 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/font"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Returns font data with only a 'GPOS' table, with a 'kern' feature
+// adjusting the given pair of glyphs and a 'mark' feature attaching the
+// given mark to the given base, with the base anchor at (anchorX, anchorY)
+// and the mark anchor at (0, 0).
+func testPositioningFontData(left, right, base, mark sfnt.GlyphIndex, kern, anchorX, anchorY int) []byte {
+	pairs := testBE16(
+		1, 18, 4, 0, 1, 12, // format, coverage, value formats, pair sets
+		1, int(right), kern, // pair set
+		1, 1, int(left), // coverage
+	)
+	markBase := testBE16(
+		1, 34, 40, 1, 12, 24, // format, coverages, classes, arrays
+		1, 0, 6, 1, 0, 0, // mark array
+		1, 4, 1, anchorX, anchorY, // base array
+		1, 1, int(mark), // mark coverage
+		1, 1, int(base), // base coverage
+	)
+	lookupA := append(testBE16(2, 0, 1, 8), pairs...)
+	lookupB := append(testBE16(4, 0, 1, 8), markBase...)
+	lookupList := testBE16(2, 6, 6+len(lookupA))
+	lookupList = append(append(lookupList, lookupA...), lookupB...)
+	featureList := append(append(testBE16(2), "kern"...), testBE16(14)...)
+	featureList = append(append(featureList, "mark"...), testBE16(20)...)
+	featureList = append(featureList, testBE16(0, 1, 0, 0, 1, 1)...)
+	gpos := testBE16(1, 0, 10, 12, 12+len(featureList), 0)
+	gpos = append(append(gpos, featureList...), lookupList...)
+	return testFontData([]testTable{{"GPOS", gpos}})
+}
+
+func TestGlyphPositioning(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.Utils().SetCache8MiB()
+	renderer.SetFont(testFontA)
+	renderer.SetSize(32)
+	renderer.Fract().SetHorzQuantization(QtNone)
+	glyphA, glyphV := renderer.Glyph().GetRuneIndex('A'), renderer.Glyph().GetRuneIndex('V')
+	space, dot := renderer.Glyph().GetRuneIndex(' '), renderer.Glyph().GetRuneIndex('.')
+	unitsPerEm := int(testFontA.UnitsPerEm())
+	data := testPositioningFontData(glyphA, glyphV, space, dot, -unitsPerEm/8, unitsPerEm/4, unitsPerEm/2)
+	positioning, err := font.ParseGlyphPositioning(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pair kerning
+	width := renderer.Measure("AV").Width()
+	renderer.Glyph().SetPositioning(testFontA, positioning)
+	kernedWidth := renderer.Measure("AV").Width()
+	if kernedWidth != width-32*64/8 {
+		t.Fatalf("expected kerned width %v, got %v (unkerned %v)", width-32*64/8, kernedWidth, width)
+	}
+
+	// mark attachment, which doesn't advance
+	renderer.Glyph().SetPositioning(testFontA, nil)
+	renderer.Fract().SetHorzQuantization(QtFull)
+	spaceWidth := renderer.Measure(" ").Width()
+	if renderer.Measure(" .").Width() <= spaceWidth {
+		t.Fatalf("expected unattached mark to advance")
+	}
+	renderer.Glyph().SetPositioning(testFontA, positioning)
+	if renderer.Measure(" .").Width() != spaceWidth {
+		t.Fatalf("expected attached mark to not advance")
+	}
+
+	target := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	renderer.Draw(target, " .", 10, 40)
+	expected := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	renderer.Draw(expected, ".", 10+8, 40-16)
+	if bytes.Equal(expected.Pix, make([]byte, len(expected.Pix))) {
+		t.Fatalf("expected mark pixels")
+	}
+	if !bytes.Equal(target.Pix, expected.Pix) {
+		t.Fatalf("attached mark not drawn at the base anchor")
+	}
+
+	// right-to-left text
+	renderer.SetDirection(RightToLeft)
+	target = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	renderer.Draw(target, " .", 10, 40)
+	if !bytes.Equal(target.Pix, expected.Pix) {
+		t.Fatalf("attached mark not drawn at the base anchor with right-to-left text")
+	}
+	renderer.SetDirection(LeftToRight)
+
+	// removed positioning data
+	renderer.Glyph().SetPositioning(testFontA, nil)
+	target = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	renderer.Draw(target, " .", 10, 40)
+	if bytes.Equal(target.Pix, expected.Pix) {
+		t.Fatalf("unexpected mark attachment without positioning data")
+	}
+}
@@ -0,0 +1,69 @@
+package etxt
+
+import (
+	"strconv"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Scalable drawing for signed distance fields. When the renderer's
+// rasterizer is a [mask.SDFRasterizer], glyph masks are loaded at the
+// rasterizer's reference size and zero fractional position instead of
+// the current ones, and they are scaled and thresholded while drawing
+// (see drawSDFMask() on ebiten_yes.go and ebiten_no.go). This allows a
+// single cached mask to be used for any size and position.
+
+// Returns the renderer's rasterizer as a [mask.SDFRasterizer], if it's
+// one. Sideways rasterizers for vertical text are looked through, as the
+// field is rasterized with them and rotated like any other mask.
+func (self *Renderer) sdfRasterizer() (*mask.SDFRasterizer, bool) {
+	rasterizer := self.state.rasterizer
+	if sideways, isSideways := rasterizer.(*sidewaysRasterizer); isSideways {
+		rasterizer = sideways.base
+	}
+	sdf, isSDF := rasterizer.(*mask.SDFRasterizer)
+	return sdf, isSDF
+}
+
+// Draws the given glyph with the scalable distance field path.
+func (self *Renderer) sdfGlyphDraw(target Target, index sfnt.GlyphIndex, origin fract.Point, sdf *mask.SDFRasterizer) {
+	refSize := fract.FromFloat64Down(sdf.GetReferenceSize())
+	field := self.sdfLoadMask(index, origin, refSize)
+	scale := self.state.scaledSize.ToFloat64() / refSize.ToFloat64()
+	self.drawSDFMask(target, origin, field, sdf, scale)
+}
+
+// Loads the distance field mask for the given glyph at the given
+// reference size. The cache handler size and fractional position
+// are restored afterwards.
+func (self *Renderer) sdfLoadMask(index sfnt.GlyphIndex, origin fract.Point, refSize fract.Unit) GlyphMask {
+	if self.cacheHandler != nil {
+		self.cacheHandler.NotifySizeChange(refSize)
+		self.cacheHandler.NotifyFractChange(fract.Point{})
+		defer func() {
+			self.cacheHandler.NotifySizeChange(self.state.scaledSize)
+			self.cacheHandler.NotifyFractChange(origin)
+		}()
+		glyphMask, found := self.cacheHandler.GetMask(index)
+		if found {
+			return glyphMask
+		}
+	}
+
+	segments, err := self.state.activeFont.LoadGlyph(&self.buffer, index, fixed.Int26_6(refSize), nil)
+	if err != nil {
+		panic("font.LoadGlyph(index = " + strconv.Itoa(int(index)) + ") error: " + err.Error())
+	}
+	alphaMask, err := mask.Rasterize(segments, self.state.rasterizer, fract.Point{})
+	if err != nil {
+		panic("RasterizeGlyphMask failed: " + err.Error())
+	}
+	glyphMask := convertAlphaImageToGlyphMask(alphaMask)
+	if self.cacheHandler != nil {
+		self.cacheHandler.PassMask(index, glyphMask)
+	}
+	return glyphMask
+}
@@ -0,0 +1,101 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tinne26/etxt/cache"
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+)
+
+func TestSDFDraw(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	glyphCache := cache.NewDefaultCache(1024 * 1024) // (not shared with other tests)
+	renderer.SetCacheHandler(glyphCache.NewHandler())
+	renderer.SetFont(testFontA)
+	renderer.SetAlign(Left | Baseline)
+	renderer.SetColor(color.RGBA{255, 255, 255, 255})
+	var sdf mask.SDFRasterizer
+	renderer.Glyph().SetRasterizer(&sdf)
+
+	// count the drawn pixels
+	countPixels := func(target *image.RGBA) int {
+		var count int
+		for i := 3; i < len(target.Pix); i += 4 {
+			if target.Pix[i] != 0 {
+				count++
+			}
+		}
+		return count
+	}
+
+	// a single cached mask must be used for any size and position
+	var prevCount int
+	for i, size := range []float64{12, 24.5, 48, 96} {
+		renderer.SetSize(size)
+		target := image.NewRGBA(image.Rect(0, 0, 256, 256))
+		renderer.Fract().Draw(target, "O", fract.FromInt(20)+fract.Unit(i*17), fract.FromInt(200))
+		if glyphCache.NumEntries() != 1 {
+			t.Fatalf("size %v: expected 1 cached mask, got %d", size, glyphCache.NumEntries())
+		}
+		count := countPixels(target)
+		if count <= prevCount {
+			t.Fatalf("size %v: expected more pixels than for smaller sizes (%d vs %d)", size, count, prevCount)
+		}
+		prevCount = count
+	}
+
+	// compare with the default rasterizer coverage
+	renderer.SetSize(64)
+	target := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	renderer.Draw(target, "O", 20, 200)
+	sdfCount := countPixels(target)
+	renderer.Glyph().SetRasterizer(&mask.DefaultRasterizer{})
+	target = image.NewRGBA(image.Rect(0, 0, 256, 256))
+	renderer.Draw(target, "O", 20, 200)
+	defaultCount := countPixels(target)
+	if diff := sdfCount - defaultCount; diff < -defaultCount/20 || diff > defaultCount/20 {
+		t.Fatalf("expected similar coverage to the default rasterizer, got %d vs %d", sdfCount, defaultCount)
+	}
+
+	// outlines leave the glyph center empty
+	renderer.Glyph().SetRasterizer(&sdf)
+	sdf.SetOutlineWidth(2)
+	target = image.NewRGBA(image.Rect(0, 0, 256, 256))
+	renderer.Draw(target, "l", 20, 200)
+	minX, maxX := -1, -1
+	for x := 0; x < 256; x++ {
+		if target.RGBAAt(x, 180).A != 0 {
+			if minX == -1 {
+				minX = x
+			}
+			maxX = x
+		}
+	}
+	if maxX-minX < 4 || target.RGBAAt((minX+maxX)/2, 180).A != 0 {
+		t.Fatalf("expected empty glyph center with outlines")
+	}
+
+	// sideways glyphs in vertical text also use the distance field
+	sdf.SetOutlineWidth(0)
+	renderer.SetSize(16)
+	renderer.SetDirection(TopToBottom)
+	target = image.NewRGBA(image.Rect(0, 0, 256, 256))
+	renderer.Draw(target, "i", 128, 20)
+	sdfCount = countPixels(target)
+	renderer.Glyph().SetRasterizer(&mask.DefaultRasterizer{})
+	target = image.NewRGBA(image.Rect(0, 0, 256, 256))
+	renderer.Draw(target, "i", 128, 20)
+	defaultCount = countPixels(target)
+	if diff := sdfCount - defaultCount; diff < -defaultCount/5 || diff > defaultCount/5 {
+		t.Fatalf("TopToBottom: expected similar coverage to the default rasterizer, got %d vs %d", sdfCount, defaultCount)
+	}
+}
@@ -0,0 +1,64 @@
+package etxt
+
+import (
+	"github.com/tinne26/etxt/fract"
+)
+
+// Same as [Renderer.Draw]() or [Renderer.DrawWithWrap](), but for a
+// retained [TextBlock]. The block is laid out with the renderer's
+// configuration if it wasn't already, and drawn with the renderer's
+// current align, color, blend mode and rasterizer.
+func (self *Renderer) DrawTextBlock(target Target, block *TextBlock, x, y int) {
+	self.fractDrawTextBlock(target, block, fract.FromInt(x), fract.FromInt(y))
+}
+
+// Same as [Renderer.Measure]() or [Renderer.MeasureWithWrap](), but for
+// a retained [TextBlock]. If the block is already laid out, measuring is
+// almost free.
+func (self *Renderer) MeasureTextBlock(block *TextBlock) fract.Rect {
+	// preconditions
+	if self.state.activeFont == nil {
+		panic("can't measure text with nil font (tip: Renderer.SetFont())")
+	}
+	if self.state.fontSizer == nil {
+		panic("can't measure text with a nil sizer (tip: NewRenderer())")
+	}
+
+	// main processing
+	if block.text == "" {
+		return fract.Rect{}
+	}
+	self.textBlockRefresh(block)
+	return self.layoutMeasure(&block.layout)
+}
+
+// ---- underlying implementations ----
+
+// x and y are assumed to be unquantized
+func (self *Renderer) fractDrawTextBlock(target Target, block *TextBlock, x, y fract.Unit) {
+	// preconditions
+	if target == nil {
+		panic("can't draw on nil Target")
+	}
+	if self.state.activeFont == nil {
+		panic("can't draw text with nil font (tip: Renderer.SetFont())")
+	}
+	if self.state.fontSizer == nil {
+		panic("can't draw with a nil sizer (tip: NewRenderer())")
+	}
+	if self.state.rasterizer == nil {
+		panic("can't draw with a nil rasterizer (tip: NewRenderer())")
+	}
+
+	// return directly on superfluous invocations
+	if block.text == "" {
+		return
+	}
+	bounds := target.Bounds()
+	if bounds.Empty() {
+		return
+	}
+
+	self.textBlockRefresh(block)
+	self.layoutDraw(target, &block.layout, x, y)
+}
@@ -0,0 +1,104 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"golang.org/x/image/font/sfnt"
+)
+
+type testGlyphRecord struct {
+	glyph  sfnt.GlyphIndex
+	origin fract.Point
+}
+
+func testRecordGlyphs(renderer *Renderer, drawFn func()) []testGlyphRecord {
+	var records []testGlyphRecord
+	renderer.Glyph().SetDrawFunc(func(target Target, glyph sfnt.GlyphIndex, origin fract.Point) {
+		records = append(records, testGlyphRecord{glyph, origin})
+	})
+	drawFn()
+	renderer.Glyph().SetDrawFunc(nil)
+	return records
+}
+
+func TestTwineDrawConsistency(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Utils().SetCache8MiB()
+	target := image.NewRGBA(image.Rect(0, 0, 256, 256))
+
+	texts := []string{"hello world", "\n\nhey\n", "hello world hello world\ngoodbye", "AV.AV  AV"}
+	aligns := []Align{Left | Baseline, Right | Top, Center, HorzCenter | Bottom, Left | LastBaseline}
+	for _, qt := range []fract.Unit{QtFull, Qt4th, QtNone} {
+		for _, align := range aligns {
+			for _, dir := range []Direction{LeftToRight, RightToLeft} {
+				renderer.Fract().SetHorzQuantization(qt)
+				renderer.SetAlign(align)
+				renderer.SetDirection(dir)
+				for _, text := range texts {
+					var twine Twine
+					twine.Add(text, TwineStyle{})
+					r1 := testRecordGlyphs(renderer, func() { renderer.Draw(target, text, 128, 128) })
+					r2 := testRecordGlyphs(renderer, func() { renderer.Twine().Draw(target, twine, 128, 128) })
+					if !testSameGlyphRecords(r1, r2) {
+						t.Fatalf("text %q, align %s, dir %s: expected %v, got %v", text, align, dir, r1, r2)
+					}
+					for _, limit := range []int{40, 80} {
+						r1 = testRecordGlyphs(renderer, func() { renderer.DrawWithWrap(target, text, 128, 128, limit) })
+						r2 = testRecordGlyphs(renderer, func() { renderer.Twine().DrawWithWrap(target, twine, 128, 128, limit) })
+						if !testSameGlyphRecords(r1, r2) {
+							t.Fatalf("text %q (wrap %d), align %s, dir %s: expected %v, got %v", text, limit, align, dir, r1, r2)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTwineDrawBaselines(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	target := image.NewRGBA(image.Rect(0, 0, 256, 256))
+
+	var twine Twine
+	twine.Add("ab", TwineStyle{})
+	twine.Add("AB", TwineStyle{Size: 40})
+	twine.Add("ab", TwineStyle{Size: 10})
+	records := testRecordGlyphs(renderer, func() { renderer.Twine().Draw(target, twine, 0, 128) })
+	if len(records) != 6 {
+		t.Fatalf("expected 6 glyphs, got %d", len(records))
+	}
+	for i := 1; i < len(records); i++ {
+		if records[i].origin.Y != records[0].origin.Y {
+			t.Fatalf("glyph #%d baseline mismatch (%d vs %d)", i, records[i].origin.Y, records[0].origin.Y)
+		}
+		if records[i].origin.X <= records[i-1].origin.X {
+			t.Fatalf("glyph #%d not advancing", i)
+		}
+	}
+}
+
+func testSameGlyphRecords(a, b []testGlyphRecord) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
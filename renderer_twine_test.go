@@ -0,0 +1,91 @@
+package etxt
+
+import (
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestTwineMeasure(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Utils().SetCache8MiB()
+
+	testMeasureBasics(t, renderer, func(r *Renderer, str string) fract.Rect {
+		var twine Twine
+		return r.Twine().Measure(*twine.Add(str, TwineStyle{}))
+	})
+	testMeasureBasics(t, renderer, func(r *Renderer, str string) fract.Rect {
+		var twine Twine
+		return r.Twine().MeasureWithWrap(*twine.Add(str, TwineStyle{}), 9999)
+	})
+
+	// single style twines must match regular measuring
+	texts := []string{"hello world", "\n\nhey\n", "hello world hello world\ngoodbye", " ", "AV.AV"}
+	for _, qt := range []fract.Unit{QtFull, Qt4th, QtNone} {
+		for _, dir := range []Direction{LeftToRight, RightToLeft} {
+			renderer.Fract().SetHorzQuantization(qt)
+			renderer.SetDirection(dir)
+			for _, text := range texts {
+				var twine Twine
+				twine.Add(text, TwineStyle{})
+				r1, r2 := renderer.Measure(text), renderer.Twine().Measure(twine)
+				if r1 != r2 {
+					t.Fatalf("text %q: expected %v, got %v", text, r1, r2)
+				}
+				for _, limit := range []int{0, 40, 80, 9999} {
+					r1 = renderer.MeasureWithWrap(text, limit)
+					r2 = renderer.Twine().MeasureWithWrap(twine, limit)
+					if r1 != r2 {
+						t.Fatalf("text %q (wrap %d): expected %v, got %v", text, limit, r1, r2)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTwineMixedSizes(t *testing.T) {
+	if testFontA == nil || testFontB == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Twine().RegisterFont(1, testFontB)
+	renderer.SetSize(16)
+
+	var twine Twine
+	twine.Add("small ", TwineStyle{})
+	twine.Add("BIG", TwineStyle{Size: 32, FontIndex: 1})
+	twine.Add(" small", TwineStyle{})
+
+	// the tallest run determines the line height
+	renderer.Utils().StoreState()
+	renderer.SetFont(testFontB)
+	renderer.SetSize(32)
+	bigHeight := renderer.Measure("BIG").Height()
+	renderer.Utils().RestoreState()
+	rect := renderer.Twine().Measure(twine)
+	if rect.Height() != bigHeight {
+		t.Fatalf("expected height %d, got %d", bigHeight, rect.Height())
+	}
+	if renderer.GetFont() != testFontA || renderer.GetSize() != 16 {
+		t.Fatal("renderer state modified by twine operation")
+	}
+
+	// wrapping must happen across runs
+	wrapRect := renderer.Twine().MeasureWithWrap(twine, rect.Width().ToIntCeil()-1)
+	if wrapRect.Height() <= rect.Height() || wrapRect.Width() >= rect.Width() {
+		t.Fatalf("expected wrap, got %v (unwrapped %v)", wrapRect, rect)
+	}
+	smallWidth := renderer.Measure("small").Width()
+	glyphs := renderer.Twine().MeasureWithWrap(twine, 1)
+	if glyphs.Width() >= smallWidth || glyphs.Height() <= wrapRect.Height() {
+		t.Fatalf("expected a glyph per line, got %v", glyphs)
+	}
+}
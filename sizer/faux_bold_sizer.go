@@ -0,0 +1,44 @@
+package sizer
+
+import (
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+	. "golang.org/x/image/font/sfnt"
+)
+
+var _ Sizer = (*FauxBoldSizer)(nil)
+
+// A [Sizer] to be used together with a [mask.FauxRasterizer], adding
+// the rasterizer's extra width to the glyph advances so faux-bold
+// glyphs don't overlap. The extra width and bold mode are read on each
+// call, so the sizer stays in sync with the rasterizer without any
+// manual linking.
+//
+// The extra width is only added with [mask.FauxBoldExpand], where glyph
+// outlines grow exactly by the extra width. With [mask.FauxBoldSmear],
+// advances are left unchanged; you may use a [PaddedAdvanceSizer] with
+// a smaller padding instead.
+type FauxBoldSizer struct {
+	defaultSizer
+	rasterizer *mask.FauxRasterizer
+}
+
+// Sets the rasterizer whose extra width will be added to the advances.
+// If nil, the sizer behaves like the default one.
+func (self *FauxBoldSizer) SetRasterizer(rasterizer *mask.FauxRasterizer) {
+	self.rasterizer = rasterizer
+}
+
+// Returns the rasterizer whose extra width is added to the advances.
+func (self *FauxBoldSizer) GetRasterizer() *mask.FauxRasterizer {
+	return self.rasterizer
+}
+
+// Satisfies the [Sizer] interface.
+func (self *FauxBoldSizer) GlyphAdvance(font *Font, buffer *Buffer, size fract.Unit, g GlyphIndex) fract.Unit {
+	advance := self.defaultSizer.GlyphAdvance(font, buffer, size, g)
+	if self.rasterizer == nil || self.rasterizer.GetBoldMode() != mask.FauxBoldExpand {
+		return advance
+	}
+	return advance + fract.FromFloat64(float64(self.rasterizer.GetExtraWidth()))
+}
@@ -0,0 +1,52 @@
+package sizer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/mask"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestFauxBoldSizer(t *testing.T) {
+	data, err := os.ReadFile("../font/test/Go-Regular.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := sfnt.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buffer sfnt.Buffer
+	index, err := font.GlyphIndex(&buffer, 'A')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var defaultSizer DefaultSizer
+	var rasterizer mask.FauxRasterizer
+	var fauxSizer FauxBoldSizer
+	size := fract.FromInt(16)
+	advance := defaultSizer.GlyphAdvance(font, &buffer, size, index)
+	if fauxSizer.GlyphAdvance(font, &buffer, size, index) != advance {
+		t.Fatal("expected default advance without rasterizer")
+	}
+
+	fauxSizer.SetRasterizer(&rasterizer)
+	rasterizer.SetExtraWidth(2)
+	tests := []struct {
+		mode    mask.FauxBoldMode
+		advance fract.Unit
+	}{
+		{mask.FauxBoldSmear, advance},
+		{mask.FauxBoldExpand, advance + fract.FromInt(2)},
+	}
+	for _, test := range tests {
+		rasterizer.SetBoldMode(test.mode)
+		got := fauxSizer.GlyphAdvance(font, &buffer, size, index)
+		if got != test.advance {
+			t.Fatalf("%s: expected advance %v, got %v", test.mode, test.advance, got)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package etxt
+
+import (
+	"reflect"
+
+	"github.com/tinne26/etxt/fract"
+	"github.com/tinne26/etxt/sizer"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Text blocks are retained text layouts that can be drawn many times
+// without repeating the work of mapping runes to glyphs, kerning and
+// wrapping lines. This is useful for text that's drawn every frame
+// but rarely changes, like most UI and HUD text:
+//
+//	block := etxt.NewTextBlockWithWrap("Some long description...", 240)
+//	// ...
+//	renderer.DrawTextBlock(canvas, block, x, y) // each frame
+//
+// Text blocks are laid out lazily the first time they are drawn or
+// measured with a renderer, and laid out again automatically whenever
+// the renderer's font, fallback fonts, size, scale, sizer, quantization,
+// text direction or layout options change. Align, color, blend mode,
+// rasterizer, highlights and decorations can be freely changed between
+// draws, as they don't affect the layout (except for [Justify] aligns).
+// If you modify a sizer's or hyphenator's parameters, the vertical metrics
+// set with [RendererGlyph.SetVertMetrics]() or the positioning data set
+// with [RendererGlyph.SetPositioning](), call [TextBlock.Invalidate]() manually.
+//
+// Text blocks keep their own buffers, so they can't be shared between
+// goroutines, but they can be drawn with different renderers.
+type TextBlock struct {
+	text       string
+	wrap       bool
+	widthLimit fract.Unit
+	layout     textLayout
+	key        textBlockKey
+	valid      bool
+}
+
+// Creates a new [TextBlock] for the given text. See also
+// [NewTextBlockWithWrap]().
+func NewTextBlock(text string) *TextBlock {
+	return &TextBlock{text: text}
+}
+
+// Creates a new [TextBlock] for the given text, to be drawn with line
+// wrapping like [Renderer.DrawWithWrap]().
+func NewTextBlockWithWrap(text string, widthLimit int) *TextBlock {
+	if widthLimit > fract.MaxInt {
+		panic("widthLimit too big, must be <= fract.MaxInt")
+	}
+	if widthLimit < 0 {
+		panic("can't use a negative widthLimit")
+	}
+	return &TextBlock{text: text, wrap: true, widthLimit: fract.FromInt(widthLimit)}
+}
+
+// Sets the text of the block. If the text is different from
+// the current one, the block will be laid out again on the
+// next draw or measure.
+func (self *TextBlock) SetText(text string) {
+	if text != self.text {
+		self.text = text
+		self.valid = false
+	}
+}
+
+// Returns the text of the block.
+func (self *TextBlock) GetText() string {
+	return self.text
+}
+
+// Forces the block to be laid out again on the next draw or measure.
+func (self *TextBlock) Invalidate() {
+	self.valid = false
+}
+
+// ---- underlying implementations ----
+
+// Renderer state that a text block layout depends on. Draw-only
+// properties like the color are not included.
+type textBlockKey struct {
+	state         restorableState // without sizer, see textBlockKeyState()
+	sizer         sizer.Sizer
+	justify       Align // Justify, JustifyAll or 0
+	options       layoutOptions
+	fallbackFonts []*sfnt.Font
+}
+
+// Returns the parts of the renderer state relevant for text block keys
+// that can be compared directly, and the justify align, if any.
+func textBlockKeyState(renderer *Renderer) (restorableState, Align) {
+	var noBlendMode BlendMode // (BlendMode is a struct with Ebitengine)
+	state := renderer.state
+	state.fontColor = nil
+	state.fontSizer = nil
+	state.rasterizer = nil
+	state.blendMode = noBlendMode
+	state.align = 0
+	horzAlign := renderer.state.align.Horz()
+	if horzAlign != Justify && horzAlign != JustifyAll {
+		horzAlign = 0
+	}
+	return state, horzAlign
+}
+
+// Fills the key with the current renderer state.
+func (self *textBlockKey) set(renderer *Renderer) {
+	self.state, self.justify = textBlockKeyState(renderer)
+	self.sizer = renderer.state.fontSizer
+	self.options = renderer.layoutOptions
+	self.fallbackFonts = append(self.fallbackFonts[:0], renderer.fallbackFonts...)
+}
+
+// Returns whether the key matches the current renderer state.
+func (self *textBlockKey) matches(renderer *Renderer) bool {
+	state, justify := textBlockKeyState(renderer)
+	if self.state != state || self.justify != justify {
+		return false
+	}
+	if !textBlockSameValue(self.sizer, renderer.state.fontSizer) {
+		return false
+	}
+	if !self.options.sameLayout(&renderer.layoutOptions) {
+		return false
+	}
+	if len(self.fallbackFonts) != len(renderer.fallbackFonts) {
+		return false
+	}
+	for i, font := range renderer.fallbackFonts {
+		if self.fallbackFonts[i] != font {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns whether the options that affect the layout are the same.
+// Draw-only options like highlights and decorations are not compared.
+func (self *layoutOptions) sameLayout(other *layoutOptions) bool {
+	return self.justifyLetterSpacing == other.justifyLetterSpacing &&
+		self.lineBreaking == other.lineBreaking &&
+		self.linePenalty == other.linePenalty &&
+		self.tolerance == other.tolerance &&
+		self.truncation == other.truncation &&
+		self.tabStops == other.tabStops &&
+		self.tabInterval == other.tabInterval &&
+		self.tabIntervalInEms == other.tabIntervalInEms &&
+		textBlockSameValue(self.hyphenator, other.hyphenator)
+}
+
+// Compares interface values like sizers and hyphenators. Values with
+// non-comparable dynamic types would make == panic, so they are always
+// considered different, and the text block will be laid out again.
+func textBlockSameValue(a, b any) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	valueType := reflect.TypeOf(a)
+	if valueType != reflect.TypeOf(b) || !valueType.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// Lays out the text block again if necessary. Draw-only properties
+// of the layout styles are always refreshed.
+func (self *Renderer) textBlockRefresh(block *TextBlock) {
+	if !block.valid || !block.key.matches(self) {
+		self.layoutString(&block.layout, block.text, block.wrap, block.widthLimit)
+		block.key.set(self)
+		block.valid = true
+	}
+
+	for i := range block.layout.styles {
+		style := &block.layout.styles[i]
+		style.color = self.state.fontColor
+		style.blendMode = self.state.blendMode
+		style.rasterizer = self.state.rasterizer
+		style.decorations = self.layoutOptions.decorations
+	}
+}
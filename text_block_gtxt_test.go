@@ -0,0 +1,47 @@
+//go:build gtxt
+
+package etxt
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tinne26/etxt/fract"
+)
+
+func TestTextBlockDrawConsistency(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	renderer.Utils().SetCache8MiB()
+	target := image.NewRGBA(image.Rect(0, 0, 256, 256))
+
+	text := "hello world hello world\ngoodbye"
+	block := NewTextBlock(text)
+	wrapBlock := NewTextBlockWithWrap(text, 80)
+	for _, qt := range []fract.Unit{QtFull, QtNone} {
+		for _, align := range []Align{Left | Baseline, Right | Top, Center, Justify | Bottom} {
+			for _, dir := range []Direction{LeftToRight, RightToLeft} {
+				renderer.Fract().SetHorzQuantization(qt)
+				renderer.SetAlign(align)
+				renderer.SetDirection(dir)
+				for _, size := range []float64{16, 21} {
+					renderer.SetSize(size)
+					r1 := testRecordGlyphs(renderer, func() { renderer.Draw(target, text, 128, 128) })
+					r2 := testRecordGlyphs(renderer, func() { renderer.DrawTextBlock(target, block, 128, 128) })
+					if !testSameGlyphRecords(r1, r2) {
+						t.Fatalf("align %s, dir %s, size %v: expected %v, got %v", align, dir, size, r1, r2)
+					}
+					r1 = testRecordGlyphs(renderer, func() { renderer.DrawWithWrap(target, text, 128, 128, 80) })
+					r2 = testRecordGlyphs(renderer, func() { renderer.DrawTextBlock(target, wrapBlock, 128, 128) })
+					if !testSameGlyphRecords(r1, r2) {
+						t.Fatalf("align %s, dir %s, size %v (wrap): expected %v, got %v", align, dir, size, r1, r2)
+					}
+				}
+			}
+		}
+	}
+}
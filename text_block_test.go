@@ -0,0 +1,81 @@
+package etxt
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestTextBlockMeasure(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+
+	block := NewTextBlockWithWrap("hello world", 60)
+	for _, size := range []float64{16, 24, 16} {
+		renderer.SetSize(size)
+		expected := renderer.MeasureWithWrap(block.GetText(), 60)
+		if rect := renderer.MeasureTextBlock(block); rect != expected {
+			t.Fatalf("size %v: expected %v, got %v", size, expected, rect)
+		}
+	}
+
+	block.SetText("hello")
+	expected := renderer.MeasureWithWrap("hello", 60)
+	if rect := renderer.MeasureTextBlock(block); rect != expected {
+		t.Fatalf("after SetText(): expected %v, got %v", expected, rect)
+	}
+
+	// draw-only changes don't invalidate the layout
+	renderer.SetAlign(Right | Top)
+	renderer.SetColor(color.RGBA{255, 0, 0, 255})
+	if !block.key.matches(renderer) {
+		t.Fatalf("expected block layout to remain valid")
+	}
+	renderer.SetAlign(Justify)
+	if block.key.matches(renderer) {
+		t.Fatalf("expected block layout to be invalidated by justify aligns")
+	}
+	_ = renderer.MeasureTextBlock(block)
+	renderer.SetAlign(JustifyAll)
+	if block.key.matches(renderer) {
+		t.Fatalf("expected block layout to be invalidated by changing the justify align")
+	}
+}
+
+// hyphenator with a non-comparable dynamic type
+type testSliceHyphenator []int
+
+func (self testSliceHyphenator) Hyphenate(word []rune, points []bool) {}
+
+func TestTextBlockKey(t *testing.T) {
+	if testFontA == nil {
+		t.SkipNow()
+	}
+
+	renderer := NewRenderer()
+	renderer.SetFont(testFontA)
+	block := NewTextBlockWithWrap("hello world", 60)
+
+	renderer.Layout().SetHyphenator(testSliceHyphenator{1})
+	_ = renderer.MeasureTextBlock(block)
+	if block.key.matches(renderer) {
+		t.Fatalf("expected non-comparable hyphenators to invalidate the layout")
+	}
+
+	renderer.Layout().SetHyphenator(nil)
+	_ = renderer.MeasureTextBlock(block)
+	if !block.key.matches(renderer) {
+		t.Fatalf("expected block layout to remain valid")
+	}
+	renderer.Layout().SetHighlight(0, 5, color.RGBA{255, 0, 0, 255})
+	if !block.key.matches(renderer) {
+		t.Fatalf("expected block layout to remain valid after a draw-only option change")
+	}
+	renderer.Layout().SetDecorations(Underline)
+	if !block.key.matches(renderer) {
+		t.Fatalf("expected block layout to remain valid after changing decorations")
+	}
+}
@@ -0,0 +1,75 @@
+package etxt
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/tinne26/etxt/mask"
+)
+
+// Twines are a simple rich text type: a sequence of runs of text
+// where each run can have its own font, size, color, blend mode
+// and rasterizer. Twines can be drawn and measured through the
+// [Renderer.Twine]() gateway:
+//
+//	var twine etxt.Twine
+//	twine.Add("Press ", etxt.TwineStyle{})
+//	twine.Add("[SPACE]", etxt.TwineStyle{FontIndex: BoldFont, Color: yellow})
+//	twine.Add(" to continue.", etxt.TwineStyle{})
+//	renderer.Twine().DrawWithWrap(canvas, twine, x, y, width)
+//
+// Runs are laid out one after another as a single piece of text.
+// Glyphs of different sizes share the same baseline, and line
+// wrapping can happen at any space, even if that means splitting
+// a run across multiple lines.
+//
+// The zero value is an empty twine ready to use.
+type Twine struct {
+	Runs []TwineRun
+}
+
+// A run of text with a specific style. See [Twine].
+type TwineRun struct {
+	Text  string
+	Style TwineStyle
+}
+
+// The style of a [TwineRun]. Zero values are interpreted as "use
+// the renderer's current configuration" for the size, color and
+// rasterizer fields. The font index and blend mode are always used
+// as they are, so the zero value style draws with the font at index
+// 0 (the one set through [Renderer.SetFont]()) and the default
+// blend mode. Decorations are added to the renderer's ones (see
+// [RendererLayout.SetDecorations]()), so they can be enabled for
+// specific runs, but not disabled.
+type TwineStyle struct {
+	FontIndex  FontIndex       // see [RendererTwine.RegisterFont]()
+	Size       float64         // logical size, see [Renderer.SetSize]()
+	Color      color.Color     // text color
+	BlendMode  BlendMode       // blend mode (not inherited from the renderer)
+	Rasterizer mask.Rasterizer // glyph mask rasterizer
+	Decoration Decoration      // added to the renderer's decorations
+}
+
+// Appends a new run with the given text and style to the twine
+// and returns the twine itself so calls can be chained.
+func (self *Twine) Add(text string, style TwineStyle) *Twine {
+	self.Runs = append(self.Runs, TwineRun{Text: text, Style: style})
+	return self
+}
+
+// Removes all the runs from the twine, but keeps the
+// underlying slice allocated for reuse.
+func (self *Twine) Reset() {
+	self.Runs = self.Runs[:0]
+}
+
+// Returns the concatenated text of all the twine runs. Byte indices
+// reported by operations working with twines refer to this string.
+func (self *Twine) String() string {
+	var builder strings.Builder
+	for _, run := range self.Runs {
+		builder.WriteString(run.Text)
+	}
+	return builder.String()
+}